@@ -1,6 +1,7 @@
 package actionrunner
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
 	"github.com/religiosa1/git-webhook-receiver/internal/tmpoutput"
+	"github.com/religiosa1/git-webhook-receiver/internal/whreceiver"
 )
 
 // ActionArgs are arguments passed to run an action/pipeline by an ActionRunner
@@ -18,9 +20,24 @@ type ActionArgs struct {
 	Logger     *slog.Logger
 	ActionDesc ActionDescriptor
 	DeliveryID string
-	Hash       string
-	Event      string
-	Branch     string
+	// RecordID of the stored delivery, empty if deliveries aren't recorded
+	DeliveryRecordID string
+	// Replay is set for the actions started by a redelivery
+	Replay bool
+	Hash   string
+	Event  string
+	Action string
+	Branch string
+	Tag    string
+	// nil, unless it's a pull request event
+	PullRequest *whreceiver.PullRequestInfo
+	// nil, unless it's a release event
+	Release *whreceiver.ReleaseInfo
+	// nil, unless it's a push event
+	Push *whreceiver.PushInfo
+	// JSON payload of the webhook, for the action's `env_from_payload`,
+	// $GIT_PAYLOAD_FILE and `payload_stdin`
+	Payload []byte
 }
 
 type ActionRunner struct {
@@ -28,7 +45,15 @@ type ActionRunner struct {
 	actionsDB    *actionsdb.ActionDB
 	tmpOutputMgr tmpoutput.Manager
 	listenDone   chan struct{}
-	semaphore    chan struct{}
+	stopListen   context.CancelFunc
+	queue        Queue
+
+	mu        sync.Mutex
+	freeSlots int
+	// running pipelines of the concurrency groups, by the group key
+	groups map[string]*groupMember
+	// queued pipelines to be closed without running, with the reason
+	dropped map[string]error
 }
 
 // overflowWriter wraps an io.Writer and records whether ErrOutputTooLarge was ever returned.
@@ -47,51 +72,52 @@ func (w *overflowWriter) Write(p []byte) (int, error) {
 	return n, err
 }
 
+// New creates an ActionRunner, running the actions from the queue as soon as
+// there's a free slot and their concurrency group is free. If actionsDB is provided, the pipeline records are
+// expected to be created by the queue, see [DBQueue].
 func New(
 	ctx context.Context,
-	actionArgsStream <-chan ActionArgs,
+	queue Queue,
 	maxConcurrentActions int,
 	actionsDB *actionsdb.ActionDB,
 	tmpOutputMgr tmpoutput.Manager,
 ) *ActionRunner {
+	listenCtx, stopListen := context.WithCancel(ctx)
 	r := ActionRunner{
 		wg:           &sync.WaitGroup{},
 		actionsDB:    actionsDB,
 		tmpOutputMgr: tmpOutputMgr,
 		listenDone:   make(chan struct{}),
-		semaphore:    make(chan struct{}, maxConcurrentActions),
+		stopListen:   stopListen,
+		queue:        queue,
+		freeSlots:    maxConcurrentActions,
+		groups:       make(map[string]*groupMember),
+		dropped:      make(map[string]error),
 	}
-	go r.listen(ctx, actionArgsStream)
+	go r.listen(ctx, listenCtx, queue)
 	return &r
 }
 
-// Wait waits for the channel to close and all of actions to finish
+// Close stops picking the actions from the queue, the actions left in it
+// aren't run.
+func (r *ActionRunner) Close() {
+	r.stopListen()
+}
+
+// Wait waits for the runner to be closed and all of actions to finish
 func (r *ActionRunner) Wait() {
 	<-r.listenDone // to make sure all wg.Go in listen are issued
 	r.wg.Wait()
 }
 
-func (r *ActionRunner) listen(ctx context.Context, actionArgsStream <-chan ActionArgs) {
-	defer func() {
-		close(r.listenDone)
-		close(r.semaphore)
-	}()
+func (r *ActionRunner) listen(ctx context.Context, listenCtx context.Context, queue Queue) {
+	defer close(r.listenDone)
 	for {
-		select {
-		case <-ctx.Done():
+		args, ok := queue.Pop(listenCtx, r.choose)
+		if !ok {
 			return
-		case args, ok := <-actionArgsStream:
-			if !ok {
-				return
-			}
-			r.semaphore <- struct{}{}
-			r.wg.Go(func() {
-				defer func() {
-					<-r.semaphore
-				}()
-				r.executeAction(ctx, args)
-			})
 		}
+		r.start(ctx, args)
 	}
 }
 
@@ -113,6 +139,74 @@ func pipelineError(err error) error {
 //------------------------------------------------------------------------------
 // Private parts
 
+// newPushRecord converts the push context for the pipeline record.
+func newPushRecord(push *whreceiver.PushInfo) *actionsdb.PushRecord {
+	if push == nil {
+		return nil
+	}
+	return &actionsdb.PushRecord{
+		Pusher:        push.Pusher,
+		Before:        push.Before,
+		CommitMessage: push.CommitMessage,
+		CommitCount:   push.CommitCount,
+		CompareURL:    push.CompareURL,
+		Created:       push.Created,
+		Deleted:       push.Deleted,
+		Forced:        push.Forced,
+	}
+}
+
+// start runs the action, taken from the queue by choose, or closes its record
+// if it was dropped.
+func (r *ActionRunner) start(ctx context.Context, args ActionArgs) {
+	pipeID := args.ActionDesc.PipeID
+	r.mu.Lock()
+	if reason, ok := r.dropped[pipeID]; ok {
+		delete(r.dropped, pipeID)
+		r.mu.Unlock()
+		args.Logger.Info("Dropping action", slog.Any("reason", reason))
+		r.closeRecord(args, reason)
+		return
+	}
+	r.freeSlots--
+	key, hasGroup := groupKey(args)
+	var member *groupMember
+	actionCtx := ctx
+	if hasGroup {
+		memberCtx, cancel := context.WithCancelCause(ctx)
+		member = &groupMember{ctx: memberCtx, cancel: cancel}
+		r.groups[key] = member
+		actionCtx = memberCtx
+	}
+	r.mu.Unlock()
+	r.wg.Go(func() {
+		defer r.finish(key, member)
+		r.executeAction(actionCtx, args)
+	})
+}
+
+// finish frees the slot and the concurrency group of the finished action.
+func (r *ActionRunner) finish(key string, member *groupMember) {
+	r.mu.Lock()
+	r.freeSlots++
+	if member != nil {
+		member.cancel(nil)
+		delete(r.groups, key)
+	}
+	r.mu.Unlock()
+	r.queue.Wake()
+}
+
+// closeRecord closes the record of the action that wasn't run.
+func (r *ActionRunner) closeRecord(args ActionArgs, actionErr error) {
+	if r.actionsDB == nil {
+		return
+	}
+	if err := r.actionsDB.CloseRecord(args.ActionDesc.PipeID, actionErr, nil); err != nil {
+		args.Logger.Error("Error closing action's db record", slog.Any("error", err))
+	}
+}
+
 func (r *ActionRunner) executeAction(
 	ctx context.Context,
 	args ActionArgs,
@@ -137,12 +231,8 @@ func (r *ActionRunner) executeAction(
 			}
 		}()
 	}
+	// the record is created, when the action is queued
 	if r.actionsDB != nil {
-		err := r.actionsDB.CreateRecord(actionDesc.PipeID, actionDesc.Project, args.DeliveryID, args.Hash, actionDesc.Config)
-		if err != nil {
-			logger.Error("Error creating pipeline record in the db", slog.Any("error", errors.Join(err, actionErr)))
-			return
-		}
 		defer func() {
 			var outputForDB []byte
 			// rawOutput == nil means we failed to create a tmp file in the first place
@@ -204,15 +294,37 @@ func (r *ActionRunner) executeAction(
 		// action runs as another user, hand ownership over so it can write --
 		// the 0700 mode still keeps the (possibly credential-bearing) contents
 		// private to that single user.
-		if err := chownActionDir(tmpDir, sysProcAttr); err != nil {
+		if err := chownActionPath(tmpDir, sysProcAttr); err != nil {
 			logger.Error("Error setting ownership of action's temporary directory", slog.Any("error", err))
 			actionErr = pipelineError(fmt.Errorf("error setting ownership of action's temporary directory: %w", err))
 			return
 		}
 	}
 
+	// payloadFile is the runner-managed copy of the webhook payload, exposed to
+	// the action as $GIT_PAYLOAD_FILE and removed the same way as tmpDir.
+	var payloadFile string
+	if len(args.Payload) > 0 {
+		payloadFile, err = writePayloadFile(args.Payload, sysProcAttr)
+		if err != nil {
+			logger.Error("Error creating the payload file for action", slog.Any("error", err))
+			actionErr = pipelineError(fmt.Errorf("error creating the payload file for action: %w", err))
+			return
+		}
+		defer func() {
+			if err := os.Remove(payloadFile); err != nil {
+				logger.Error("Error removing action's payload file", slog.String("file", payloadFile), slog.Any("error", err))
+			}
+		}()
+	}
+
+	var stdin io.Reader
+	if actionDesc.Config.PayloadStdin {
+		stdin = bytes.NewReader(args.Payload)
+	}
+
 	outputWriter := &overflowWriter{Writer: rawOutput}
-	env, err := createEnv(args, tmpDir)
+	env, err := createEnv(args, tmpDir, payloadFile)
 	if err != nil {
 		logger.Error("Error building the action environment", slog.Any("error", err))
 		actionErr = pipelineError(fmt.Errorf("error building action environment: %w", err))
@@ -220,13 +332,15 @@ func (r *ActionRunner) executeAction(
 	}
 	if len(actionDesc.Config.Run) > 0 {
 		logger.Debug("Running the command", slog.Any("command", actionDesc.Config.Run))
-		actionErr = executeActionRun(actionCtx, actionDesc.Config, env, sysProcAttr, outputWriter)
+		actionErr = executeActionRun(actionCtx, actionDesc.Config, env, sysProcAttr, stdin, outputWriter)
 	} else {
 		logger.Debug("Running the script", slog.String("script", actionDesc.Config.Script))
-		actionErr = executeActionScript(actionCtx, actionDesc.Config, env, sysProcAttr, outputWriter)
+		actionErr = executeActionScript(actionCtx, actionDesc.Config, env, sysProcAttr, stdin, outputWriter)
 	}
 	if outputWriter.overflowed {
 		actionErr = fmt.Errorf("action output exceeded the maximum allowed size: %w", tmpoutput.ErrOutputTooLarge)
+	} else if cause := context.Cause(ctx); actionErr != nil && errors.Is(cause, ErrConcurrencyCancelled) {
+		actionErr = fmt.Errorf("%w: %w", cause, actionErr)
 	}
 
 	if actionErr != nil {
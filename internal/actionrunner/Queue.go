@@ -0,0 +1,341 @@
+package actionrunner
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/whreceiver"
+)
+
+// ErrQueueFull is returned, if the action can't be queued, as the queue is
+// at its max depth.
+var ErrQueueFull = errors.New("action runner is at full queue capacity")
+
+// Queue holds the actions, waiting for a free slot of the ActionRunner or for
+// their concurrency group.
+type Queue interface {
+	// Push queues the action; ErrQueueFull is returned if the queue is at its
+	// max depth.
+	Push(args ActionArgs) error
+	// Pop waits for an action picked by choose and removes it from the queue;
+	// ok is false, if ctx is done first. See [Chooser].
+	Pop(ctx context.Context, choose Chooser) (args ActionArgs, ok bool)
+	// Wake makes the waiting Pop call choose again, as the state it depends on
+	// has changed.
+	Wake()
+}
+
+// Chooser picks the action to take from the queued ones, passed oldest first,
+// returning its index, or -1 to leave them all in the queue.
+type Chooser func(queued []ActionArgs) int
+
+// Oldest is the Chooser taking the actions in the order they were queued.
+func Oldest(queued []ActionArgs) int {
+	return 0
+}
+
+//------------------------------------------------------------------------------
+// In-memory queue
+
+// MemoryQueue is the queue used, when the actions DB is disabled. Actions
+// still in the queue are lost on shutdown.
+type MemoryQueue struct {
+	mu       sync.Mutex
+	items    []ActionArgs
+	maxDepth int
+	wake     chan struct{}
+}
+
+func NewMemoryQueue(maxDepth int) *MemoryQueue {
+	return &MemoryQueue{maxDepth: maxDepth, wake: make(chan struct{}, 1)}
+}
+
+func (q *MemoryQueue) Push(args ActionArgs) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) >= q.maxDepth {
+		return ErrQueueFull
+	}
+	q.items = append(q.items, args)
+	q.Wake()
+	return nil
+}
+
+func (q *MemoryQueue) Pop(ctx context.Context, choose Chooser) (ActionArgs, bool) {
+	for ctx.Err() == nil {
+		if args, ok := q.take(choose); ok {
+			return args, true
+		}
+		select {
+		case <-q.wake:
+		case <-ctx.Done():
+		}
+	}
+	return ActionArgs{}, false
+}
+
+func (q *MemoryQueue) take(choose Chooser) (ActionArgs, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) == 0 {
+		return ActionArgs{}, false
+	}
+	idx := choose(q.items)
+	if idx < 0 {
+		return ActionArgs{}, false
+	}
+	args := q.items[idx]
+	q.items = slices.Delete(q.items, idx, idx+1)
+	return args, true
+}
+
+func (q *MemoryQueue) Wake() {
+	select {
+	case q.wake <- struct{}{}:
+	default: // already woken up
+	}
+}
+
+//------------------------------------------------------------------------------
+// Durable queue
+
+// dbQueuePollInterval is how often DBQueue checks for the pipelines, queued
+// by other processes (e.g. the redeliver CLI command) or left after an error.
+const dbQueuePollInterval = 5 * time.Second
+
+// DBQueue stores the actions as queued pipeline records in the actions DB, so
+// they survive restarts.
+//
+// The action config is taken from the current config, when the action is
+// picked from the queue. It's looked up by the config stored with the record,
+// as the actions may've been reordered or changed before a restart.
+type DBQueue struct {
+	db       *actionsdb.ActionDB
+	projects map[string]config.Project
+	// marshalled action configs of the projects, to compare with the stored ones
+	actionConfigs map[string][][]byte
+	// queued actions already restored from their records, by pipe ID
+	restored map[string]ActionArgs
+	maxDepth int
+	logger   *slog.Logger
+	wake     chan struct{}
+}
+
+func NewDBQueue(db *actionsdb.ActionDB, cfg config.Config, logger *slog.Logger) *DBQueue {
+	actionConfigs := make(map[string][][]byte, len(cfg.Projects))
+	for name, project := range cfg.Projects {
+		configs := make([][]byte, len(project.Actions))
+		for i, action := range project.Actions {
+			// an action failing to marshal never matches a stored config
+			configs[i], _ = json.Marshal(action)
+		}
+		actionConfigs[name] = configs
+	}
+	return &DBQueue{
+		db:            db,
+		projects:      cfg.Projects,
+		actionConfigs: actionConfigs,
+		restored:      make(map[string]ActionArgs),
+		maxDepth:      cfg.MaxQueuedActions,
+		logger:        logger,
+		wake:          make(chan struct{}, 1),
+	}
+}
+
+// queuedArgs are the action arguments, stored with the queued record
+type queuedArgs struct {
+	DeliveryID       string                      `json:"deliveryId"`
+	DeliveryRecordID string                      `json:"deliveryRecordId,omitempty"`
+	Replay           bool                        `json:"replay,omitempty"`
+	Hash             string                      `json:"hash,omitempty"`
+	Event            string                      `json:"event"`
+	Action           string                      `json:"action,omitempty"`
+	Branch           string                      `json:"branch,omitempty"`
+	Tag              string                      `json:"tag,omitempty"`
+	PullRequest      *whreceiver.PullRequestInfo `json:"pullRequest,omitempty"`
+	Release          *whreceiver.ReleaseInfo     `json:"release,omitempty"`
+	Push             *whreceiver.PushInfo        `json:"push,omitempty"`
+}
+
+func (q *DBQueue) Push(args ActionArgs) error {
+	desc := args.ActionDesc
+	entryArgs, err := json.Marshal(queuedArgs{
+		DeliveryID:       args.DeliveryID,
+		DeliveryRecordID: args.DeliveryRecordID,
+		Replay:           args.Replay,
+		Hash:             args.Hash,
+		Event:            args.Event,
+		Action:           args.Action,
+		Branch:           args.Branch,
+		Tag:              args.Tag,
+		PullRequest:      args.PullRequest,
+		Release:          args.Release,
+		Push:             args.Push,
+	})
+	if err != nil {
+		return fmt.Errorf("error serializing the action arguments: %w", err)
+	}
+	err = q.db.EnqueueRecord(
+		desc.PipeID, desc.Project, args.DeliveryID, args.DeliveryRecordID, args.Hash, args.Replay, newPushRecord(args.Push), desc.Config,
+		actionsdb.QueueEntry{ActionIdx: desc.Index, Args: entryArgs, Payload: args.Payload},
+		q.maxDepth,
+	)
+	if errors.Is(err, actionsdb.ErrQueueFull) {
+		return ErrQueueFull
+	}
+	if err != nil {
+		return fmt.Errorf("error creating the queued pipeline record: %w", err)
+	}
+	q.Wake()
+	return nil
+}
+
+func (q *DBQueue) Pop(ctx context.Context, choose Chooser) (ActionArgs, bool) {
+	for ctx.Err() == nil {
+		args, err := q.take(choose)
+		if err == nil {
+			return args, true
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			q.logger.Error("Error picking the pipeline from the queue", slog.Any("error", err))
+		}
+		select {
+		case <-q.wake:
+		case <-time.After(dbQueuePollInterval):
+		case <-ctx.Done():
+		}
+	}
+	return ActionArgs{}, false
+}
+
+func (q *DBQueue) Wake() {
+	select {
+	case q.wake <- struct{}{}:
+	default: // already woken up
+	}
+}
+
+// take starts the queued pipeline picked by choose. sql.ErrNoRows is returned
+// if nothing was picked.
+func (q *DBQueue) take(choose Chooser) (ActionArgs, error) {
+	records, err := q.db.ListQueuedRecords()
+	if err != nil {
+		return ActionArgs{}, err
+	}
+	queued := make([]ActionArgs, 0, len(records))
+	restored := make(map[string]ActionArgs, len(records))
+	for _, record := range records {
+		args, ok := q.restored[record.PipeID]
+		if !ok {
+			args, err = q.restoreArgs(record)
+			if err != nil {
+				q.logger.Error("Unable to run the queued pipeline", slog.String("pipe_id", record.PipeID), slog.Any("error", err))
+				q.closeRecord(record.PipeID, pipelineError(err))
+				continue
+			}
+		}
+		restored[record.PipeID] = args
+		queued = append(queued, args)
+	}
+	// dropping the ones, which are no longer in the queue
+	q.restored = restored
+	if len(queued) == 0 {
+		return ActionArgs{}, sql.ErrNoRows
+	}
+	idx := choose(queued)
+	if idx < 0 {
+		return ActionArgs{}, sql.ErrNoRows
+	}
+	args := queued[idx]
+	args.Payload, err = q.db.StartQueuedRecord(args.ActionDesc.PipeID)
+	if err != nil {
+		return ActionArgs{}, fmt.Errorf("error starting the queued pipeline %s: %w", args.ActionDesc.PipeID, err)
+	}
+	return args, nil
+}
+
+// closeRecord removes the pipeline, which can't be run, from the queue and
+// closes its record with the error.
+func (q *DBQueue) closeRecord(pipeID string, pipeErr error) {
+	if _, err := q.db.StartQueuedRecord(pipeID); err != nil {
+		q.logger.Error("Error removing the pipeline from the queue", slog.String("pipe_id", pipeID), slog.Any("error", err))
+		return
+	}
+	if err := q.db.CloseRecord(pipeID, pipeErr, nil); err != nil {
+		q.logger.Error("Error closing queued pipeline's db record", slog.Any("error", err))
+	}
+}
+
+// restoreArgs recreates the action arguments of the queued record, with the
+// action config of the current config.
+func (q *DBQueue) restoreArgs(record actionsdb.QueuedRecord) (ActionArgs, error) {
+	project, ok := q.projects[record.Project]
+	if !ok {
+		return ActionArgs{}, fmt.Errorf("project %q is no longer in the config", record.Project)
+	}
+	actionIdx, ok := findQueuedAction(q.actionConfigs[record.Project], record)
+	if !ok {
+		return ActionArgs{}, fmt.Errorf("action %d of project %q was changed or is no longer in the config", record.ActionIdx, record.Project)
+	}
+	var stored queuedArgs
+	if err := json.Unmarshal(record.Args, &stored); err != nil {
+		return ActionArgs{}, fmt.Errorf("error deserializing the action arguments: %w", err)
+	}
+	desc := ActionDescriptor{
+		ActionIdentifier: ActionIdentifier{
+			Index:   actionIdx,
+			Project: record.Project,
+			PipeID:  record.PipeID,
+		},
+		GitProvider: project.GitProvider,
+		Repo:        project.Repo,
+		Config:      project.Actions[actionIdx],
+	}
+	return ActionArgs{
+		Logger: q.logger.With(
+			slog.String("project", record.Project),
+			slog.String("deliveryId", stored.DeliveryID),
+			slog.Any("action", desc.ActionIdentifier),
+		),
+		ActionDesc:       desc,
+		DeliveryID:       stored.DeliveryID,
+		DeliveryRecordID: stored.DeliveryRecordID,
+		Replay:           stored.Replay,
+		Hash:             stored.Hash,
+		Event:            stored.Event,
+		Action:           stored.Action,
+		Branch:           stored.Branch,
+		Tag:              stored.Tag,
+		PullRequest:      stored.PullRequest,
+		Release:          stored.Release,
+		Push:             stored.Push,
+	}, nil
+}
+
+// findQueuedAction returns the index of the project's action with the same
+// marshalled config, as the one stored with the queued record, preferring the
+// stored index.
+func findQueuedAction(configs [][]byte, record actionsdb.QueuedRecord) (int, bool) {
+	if len(record.Config) == 0 {
+		return 0, false
+	}
+	if record.ActionIdx >= 0 && record.ActionIdx < len(configs) && bytes.Equal(configs[record.ActionIdx], record.Config) {
+		return record.ActionIdx, true
+	}
+	for idx, conf := range configs {
+		if bytes.Equal(conf, record.Config) {
+			return idx, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,198 @@
+package actionrunner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/tmpoutput"
+	"github.com/religiosa1/git-webhook-receiver/internal/whreceiver"
+)
+
+func makeQueueConfig(actions ...config.Action) config.Config {
+	return config.Config{
+		MaxQueuedActions: 10,
+		Projects: map[string]config.Project{
+			"proj": {GitProvider: "github", Repo: "user/repo", Actions: actions},
+		},
+	}
+}
+
+func popWithTimeout(queue Queue, timeout time.Duration) (ActionArgs, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return queue.Pop(ctx, Oldest)
+}
+
+func TestMemoryQueue(t *testing.T) {
+	queue := NewMemoryQueue(2)
+	for _, pipeID := range []string{"pipe-1", "pipe-2"} {
+		if err := queue.Push(makeExecArgs(pipeID, config.Action{})); err != nil {
+			t.Fatalf("Push returned error: %v", err)
+		}
+	}
+	if err := queue.Push(makeExecArgs("pipe-3", config.Action{})); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("Push to a full queue returned %v; want ErrQueueFull", err)
+	}
+	for _, want := range []string{"pipe-1", "pipe-2"} {
+		args, ok := popWithTimeout(queue, time.Second)
+		if !ok || args.ActionDesc.PipeID != want {
+			t.Errorf("Pop = %q, %t; want %q", args.ActionDesc.PipeID, ok, want)
+		}
+	}
+	if _, ok := popWithTimeout(queue, 10*time.Millisecond); ok {
+		t.Error("Pop from an empty queue returned an action")
+	}
+}
+
+func TestDBQueue(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	action := config.Action{Script: "echo queued", Timeout: time.Minute}
+
+	t.Run("restores the queued actions after reopening the db", func(t *testing.T) {
+		dbFile := filepath.Join(t.TempDir(), "actions.sqlite3")
+		db, err := actionsdb.New(dbFile, 0)
+		if err != nil {
+			t.Fatalf("failed to create test actions db: %v", err)
+		}
+		cfg := makeQueueConfig(config.Action{}, action)
+		args := makeExecArgs("pipe-restored", action)
+		args.ActionDesc.Index = 1
+		args.DeliveryRecordID = "record-1"
+		args.Replay = true
+		args.Push = &whreceiver.PushInfo{Pusher: "dev", CommitCount: 2}
+		args.Payload = []byte(`{"ref":"refs/heads/master"}`)
+		if err := NewDBQueue(db, cfg, logger).Push(args); err != nil {
+			t.Fatalf("Push returned error: %v", err)
+		}
+		_ = db.Close()
+
+		db = newTestActionsDBFile(t, dbFile)
+		got, ok := popWithTimeout(NewDBQueue(db, cfg, logger), time.Second)
+		if !ok {
+			t.Fatal("Pop returned no action")
+		}
+		if got.ActionDesc.ActionIdentifier != args.ActionDesc.ActionIdentifier {
+			t.Errorf("action = %+v; want %+v", got.ActionDesc.ActionIdentifier, args.ActionDesc.ActionIdentifier)
+		}
+		if got.ActionDesc.Config.Script != action.Script || got.ActionDesc.Repo != "user/repo" {
+			t.Errorf("action config isn't taken from the config: %+v", got.ActionDesc)
+		}
+		if got.DeliveryID != args.DeliveryID || got.DeliveryRecordID != "record-1" || !got.Replay ||
+			got.Hash != args.Hash || got.Event != args.Event || got.Branch != args.Branch {
+			t.Errorf("webhook info isn't restored: %+v", got)
+		}
+		if got.Push == nil || *got.Push != *args.Push {
+			t.Errorf("push = %+v; want %+v", got.Push, args.Push)
+		}
+		if string(got.Payload) != string(args.Payload) {
+			t.Errorf("payload = %q; want %q", got.Payload, args.Payload)
+		}
+		if got.Logger == nil {
+			t.Error("logger isn't set")
+		}
+	})
+
+	t.Run("reports the full queue", func(t *testing.T) {
+		db := newTestActionsDB(t)
+		cfg := makeQueueConfig(action)
+		cfg.MaxQueuedActions = 1
+		queue := NewDBQueue(db, cfg, logger)
+		if err := queue.Push(makeExecArgs("pipe-1", action)); err != nil {
+			t.Fatalf("Push returned error: %v", err)
+		}
+		if err := queue.Push(makeExecArgs("pipe-2", action)); !errors.Is(err, ErrQueueFull) {
+			t.Fatalf("Push to a full queue returned %v; want ErrQueueFull", err)
+		}
+	})
+
+	t.Run("closes records of the actions removed from the config", func(t *testing.T) {
+		db := newTestActionsDB(t)
+		if err := NewDBQueue(db, makeQueueConfig(action), logger).Push(makeExecArgs("pipe-removed", action)); err != nil {
+			t.Fatalf("Push returned error: %v", err)
+		}
+		queue := NewDBQueue(db, config.Config{MaxQueuedActions: 10}, logger)
+		if _, ok := popWithTimeout(queue, 50*time.Millisecond); ok {
+			t.Fatal("Pop returned an action of a removed project")
+		}
+		assertRecordClosedWithError(t, db, "pipe-removed", "no longer in the config")
+	})
+
+	t.Run("finds the queued action after the actions were reordered", func(t *testing.T) {
+		db := newTestActionsDB(t)
+		other := config.Action{Script: "echo other", Timeout: time.Minute}
+		args := makeExecArgs("pipe-reordered", action)
+		if err := NewDBQueue(db, makeQueueConfig(action, other), logger).Push(args); err != nil {
+			t.Fatalf("Push returned error: %v", err)
+		}
+		queue := NewDBQueue(db, makeQueueConfig(other, config.Action{Script: "echo new"}, action), logger)
+		got, ok := popWithTimeout(queue, time.Second)
+		if !ok {
+			t.Fatal("Pop returned no action")
+		}
+		if got.ActionDesc.Index != 2 || got.ActionDesc.Config.Script != action.Script {
+			t.Errorf("want the action moved to index 2, got %d %q", got.ActionDesc.Index, got.ActionDesc.Config.Script)
+		}
+	})
+
+	t.Run("closes records of the actions changed in the config", func(t *testing.T) {
+		db := newTestActionsDB(t)
+		if err := NewDBQueue(db, makeQueueConfig(action), logger).Push(makeExecArgs("pipe-changed", action)); err != nil {
+			t.Fatalf("Push returned error: %v", err)
+		}
+		changed := action
+		changed.Script = "echo changed"
+		queue := NewDBQueue(db, makeQueueConfig(changed), logger)
+		if _, ok := popWithTimeout(queue, 50*time.Millisecond); ok {
+			t.Fatal("Pop returned a changed action")
+		}
+		assertRecordClosedWithError(t, db, "pipe-changed", "was changed")
+	})
+}
+
+// Actions pushed over the concurrency limit must wait in the queue and run,
+// once the previous ones are finished.
+func TestActionRunnerRunsQueuedActions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	db := newTestActionsDB(t)
+	action := config.Action{Script: "echo queued-output", Timeout: time.Minute}
+	queue := NewDBQueue(db, makeQueueConfig(action), logger)
+	r := New(context.Background(), queue, 1, db, tmpoutput.NewInMemoryTmpOutput(0))
+
+	pipeIDs := []string{"pipe-1", "pipe-2", "pipe-3"}
+	for _, pipeID := range pipeIDs {
+		if err := queue.Push(makeExecArgs(pipeID, action)); err != nil {
+			t.Fatalf("Push returned error: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, pipeID := range pipeIDs {
+		for {
+			rec, err := db.GetPipelineRecord(pipeID)
+			if err != nil {
+				t.Fatalf("record %q was not persisted: %v", pipeID, err)
+			}
+			if rec.Status() == actionsdb.PipeStatusOk {
+				break
+			}
+			if rec.Status() == actionsdb.PipeStatusError || time.Now().After(deadline) {
+				t.Fatalf("record %q status = %q, error %v; want ok", pipeID, rec.Status(), rec.Error)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		out, err := db.GetPipelineOutput(pipeID)
+		if err != nil || !strings.Contains(string(out), "queued-output") {
+			t.Errorf("output for %q = %q, %v; want it to contain %q", pipeID, out, err, "queued-output")
+		}
+	}
+	r.Close()
+	r.Wait()
+}
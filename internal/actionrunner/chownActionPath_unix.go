@@ -0,0 +1,21 @@
+//go:build unix
+
+package actionrunner
+
+import (
+	"os"
+	"syscall"
+)
+
+// chownActionPath hands ownership of a runner-created directory or file to the
+// user the action runs as, so a setuid'd action can access an otherwise 0700
+// (0600 for files), receiver-owned path. It's a no-op when no `user` override is configured
+// (Credential is nil): the path already belongs to the receiver's own
+// user, which is who the action runs as.
+func chownActionPath(path string, sysProcAttr *syscall.SysProcAttr) error {
+	if sysProcAttr == nil || sysProcAttr.Credential == nil {
+		return nil
+	}
+	cred := sysProcAttr.Credential
+	return os.Chown(path, int(cred.Uid), int(cred.Gid))
+}
@@ -8,8 +8,8 @@ import (
 	"testing"
 )
 
-// chownActionDir must not touch the directory when no user override is set --
-// the dir already belongs to the receiver's user. Actually chowning to a
+// chownActionPath must not touch the path when no user override is set --
+// it already belongs to the receiver's user. Actually chowning to a
 // different user requires root, so only the no-op paths are exercised here.
 func TestChownActionDirNoUserIsNoop(t *testing.T) {
 	dir := t.TempDir()
@@ -23,8 +23,8 @@ func TestChownActionDirNoUserIsNoop(t *testing.T) {
 		"nil Credential":  {Setpgid: true},
 	} {
 		t.Run(name, func(t *testing.T) {
-			if err := chownActionDir(dir, attr); err != nil {
-				t.Fatalf("chownActionDir returned error: %v", err)
+			if err := chownActionPath(dir, attr); err != nil {
+				t.Fatalf("chownActionPath returned error: %v", err)
 			}
 			after, err := os.Stat(dir)
 			if err != nil {
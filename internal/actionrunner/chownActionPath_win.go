@@ -4,9 +4,9 @@ package actionrunner
 
 import "syscall"
 
-// chownActionDir is a no-op on non-unix platforms: the `user` field is
-// unsupported there (getSysProcAttr rejects it), so a runner-created directory
+// chownActionPath is a no-op on non-unix platforms: the `user` field is
+// unsupported there (getSysProcAttr rejects it), so a runner-created path
 // always belongs to the receiver's user, which is who the action runs as.
-func chownActionDir(_ string, _ *syscall.SysProcAttr) error {
+func chownActionPath(_ string, _ *syscall.SysProcAttr) error {
 	return nil
 }
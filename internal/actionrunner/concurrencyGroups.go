@@ -0,0 +1,91 @@
+package actionrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+)
+
+// ErrConcurrencyCancelled is the cause of the pipelines, cancelled by a newer
+// pipeline of their concurrency group with the cancel-in-progress policy.
+var ErrConcurrencyCancelled = errors.New("cancelled by a newer pipeline")
+
+// ErrConcurrencySkipped is the error of the pipelines, dropped with the skip
+// policy, as their concurrency group was busy.
+var ErrConcurrencySkipped = errors.New("skipped")
+
+// groupMember is the running pipeline of a concurrency group. Its ctx is
+// cancelled, if a newer pipeline cancels it.
+type groupMember struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+}
+
+// groupKey returns the concurrency group of the action; ok is false, if the
+// action doesn't have one.
+func groupKey(args ActionArgs) (key string, ok bool) {
+	concurrency := args.ActionDesc.Config.Concurrency
+	if concurrency.Group == "" {
+		return "", false
+	}
+	return concurrency.GroupKey(config.ConcurrencyVars{
+		Project: args.ActionDesc.Project,
+		Branch:  args.Branch,
+		Tag:     args.Tag,
+		Event:   args.Event,
+	}), true
+}
+
+// choose is the [Chooser] of the runner. It picks the oldest action, which can
+// be run right away, or which is to be dropped by its concurrency group policy.
+// Actions waiting for a free slot or for their group are left in the queue, so
+// they don't take a slot, and they're kept for the restart by [DBQueue].
+//
+// Only one pipeline of a group runs at a time, and the pipelines of a group
+// run in the order they were queued.
+func (r *ActionRunner) choose(queued []ActionArgs) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// indices of the pipelines of the group, left in the queue
+	waiting := make(map[string][]int)
+	for i, args := range queued {
+		pipeID := args.ActionDesc.PipeID
+		if _, ok := r.dropped[pipeID]; ok {
+			return i
+		}
+		key, ok := groupKey(args)
+		if !ok {
+			if r.freeSlots > 0 {
+				return i
+			}
+			continue
+		}
+		running, busy := r.groups[key]
+		busy = busy || len(waiting[key]) > 0
+		switch args.ActionDesc.Config.Concurrency.Policy {
+		case config.ConcurrencyPolicySkip:
+			if busy {
+				r.dropped[pipeID] = fmt.Errorf("%w: concurrency group %q is busy", ErrConcurrencySkipped, key)
+				return i
+			}
+		case config.ConcurrencyPolicyCancelInProgress:
+			cause := fmt.Errorf("%w %s in concurrency group %q", ErrConcurrencyCancelled, pipeID, key)
+			if running != nil {
+				running.cancel(cause)
+			}
+			if older := waiting[key]; len(older) > 0 {
+				for _, idx := range older {
+					r.dropped[queued[idx].ActionDesc.PipeID] = cause
+				}
+				return older[0]
+			}
+		}
+		if !busy && r.freeSlots > 0 {
+			return i
+		}
+		waiting[key] = append(waiting[key], i)
+	}
+	return -1
+}
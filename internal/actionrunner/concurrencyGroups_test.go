@@ -0,0 +1,298 @@
+package actionrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/tmpoutput"
+)
+
+func TestActionRunnerChoose(t *testing.T) {
+	group := func(policy string) config.Action {
+		return config.Action{Concurrency: config.Concurrency{Group: "deploy-${branch}", Policy: policy}}
+	}
+	newRunner := func(freeSlots int, running ...string) *ActionRunner {
+		r := &ActionRunner{freeSlots: freeSlots, groups: make(map[string]*groupMember), dropped: make(map[string]error)}
+		for _, key := range running {
+			ctx, cancel := context.WithCancelCause(context.Background())
+			r.groups[key] = &groupMember{ctx: ctx, cancel: cancel}
+		}
+		return r
+	}
+
+	t.Run("pipelines of a busy group are left in the queue", func(t *testing.T) {
+		r := newRunner(1, "deploy-master")
+		queued := []ActionArgs{
+			makeExecArgs("pipe-1", group(config.ConcurrencyPolicyQueue)),
+			makeExecArgs("pipe-2", group(config.ConcurrencyPolicyQueue)),
+			makeExecArgs("pipe-3", config.Action{}),
+		}
+		if got := r.choose(queued); got != 2 {
+			t.Errorf("want the pipeline without a group to be taken, got %d", got)
+		}
+		if got := r.choose(queued[:2]); got != -1 {
+			t.Errorf("want the group pipelines left in the queue, got %d", got)
+		}
+	})
+
+	t.Run("pipelines are left in the queue without a free slot", func(t *testing.T) {
+		r := newRunner(0)
+		queued := []ActionArgs{makeExecArgs("pipe-1", config.Action{}), makeExecArgs("pipe-2", group(config.ConcurrencyPolicyQueue))}
+		if got := r.choose(queued); got != -1 {
+			t.Errorf("want nothing taken, got %d", got)
+		}
+	})
+
+	t.Run("pipelines of a group keep their order", func(t *testing.T) {
+		r := newRunner(0)
+		queued := []ActionArgs{
+			makeExecArgs("pipe-1", group(config.ConcurrencyPolicyQueue)),
+			makeExecArgs("pipe-2", group(config.ConcurrencyPolicyQueue)),
+		}
+		if got := r.choose(queued); got != -1 {
+			t.Errorf("want nothing taken without a free slot, got %d", got)
+		}
+		r.freeSlots = 2
+		if got := r.choose(queued); got != 0 {
+			t.Errorf("want the oldest pipeline of the group taken, got %d", got)
+		}
+	})
+
+	t.Run("skip policy drops the pipeline of a busy group", func(t *testing.T) {
+		r := newRunner(1, "deploy-master")
+		queued := []ActionArgs{makeExecArgs("pipe-1", group(config.ConcurrencyPolicySkip))}
+		if got := r.choose(queued); got != 0 {
+			t.Fatalf("want the pipeline taken to be dropped, got %d", got)
+		}
+		if err := r.dropped["pipe-1"]; !errors.Is(err, ErrConcurrencySkipped) {
+			t.Errorf("want ErrConcurrencySkipped, got %v", err)
+		}
+	})
+
+	t.Run("cancel-in-progress cancels the running and the waiting pipelines", func(t *testing.T) {
+		r := newRunner(1, "deploy-master")
+		running := r.groups["deploy-master"]
+		queued := []ActionArgs{
+			makeExecArgs("pipe-1", group(config.ConcurrencyPolicyQueue)),
+			makeExecArgs("pipe-2", group(config.ConcurrencyPolicyCancelInProgress)),
+		}
+		if got := r.choose(queued); got != 0 {
+			t.Fatalf("want the waiting pipeline taken to be dropped, got %d", got)
+		}
+		for _, err := range []error{context.Cause(running.ctx), r.dropped["pipe-1"]} {
+			if !errors.Is(err, ErrConcurrencyCancelled) {
+				t.Errorf("want ErrConcurrencyCancelled, got %v", err)
+			}
+		}
+		delete(r.dropped, "pipe-1")
+		if got := r.choose(queued[1:]); got != -1 {
+			t.Errorf("want the newest pipeline to wait for the cancelled one, got %d", got)
+		}
+		if _, ok := r.dropped["pipe-2"]; ok {
+			t.Error("the newest pipeline shouldn't be dropped")
+		}
+	})
+}
+
+// waitForFile waits for the action to create the file, to be sure it's running.
+func waitForFile(t *testing.T, file string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(file); err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("file %q wasn't created by the action", file)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func waitForRecord(t *testing.T, db *actionsdb.ActionDB, pipeID string) actionsdb.PipeLineRecord {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		rec, err := db.GetPipelineRecord(pipeID)
+		if err != nil {
+			t.Fatalf("record %q was not persisted: %v", pipeID, err)
+		}
+		if rec.EndedAt != nil {
+			return rec
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("record %q wasn't closed", pipeID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestActionRunnerConcurrencyGroups(t *testing.T) {
+	runPipelines := func(t *testing.T, db *actionsdb.ActionDB, actions ...config.Action) (*MemoryQueue, []string) {
+		t.Helper()
+		queue := NewMemoryQueue(len(actions))
+		r := New(context.Background(), queue, len(actions), db, tmpoutput.NewInMemoryTmpOutput(0))
+		t.Cleanup(func() {
+			r.Close()
+			r.Wait()
+		})
+		var pipeIDs []string
+		for i, action := range actions {
+			args := makeExecArgs(fmt.Sprintf("pipe-%d", i+1), action)
+			createExecRecord(t, db, args)
+			pipeIDs = append(pipeIDs, args.ActionDesc.PipeID)
+		}
+		return queue, pipeIDs
+	}
+	push := func(t *testing.T, queue *MemoryQueue, pipeID string, action config.Action) {
+		t.Helper()
+		if err := queue.Push(makeExecArgs(pipeID, action)); err != nil {
+			t.Fatalf("Push returned error: %v", err)
+		}
+	}
+
+	t.Run("queue policy runs the pipelines of the group one by one", func(t *testing.T) {
+		db := newTestActionsDB(t)
+		logFile := filepath.Join(t.TempDir(), "log")
+		action := config.Action{
+			Script:      "echo start >> " + logFile + "; sleep 0.2; echo end >> " + logFile,
+			Timeout:     time.Minute,
+			Concurrency: config.Concurrency{Group: "deploy-${branch}", Policy: config.ConcurrencyPolicyQueue},
+		}
+		queue, pipeIDs := runPipelines(t, db, action, action)
+		for _, pipeID := range pipeIDs {
+			push(t, queue, pipeID, action)
+		}
+		for _, pipeID := range pipeIDs {
+			if rec := waitForRecord(t, db, pipeID); rec.Error != nil {
+				t.Errorf("pipeline %q failed: %v", pipeID, rec.Error)
+			}
+		}
+		log, err := os.ReadFile(logFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "start\nend\nstart\nend\n"; string(log) != want {
+			t.Errorf("want the pipelines to run one by one %q, got %q", want, log)
+		}
+	})
+
+	t.Run("cancel-in-progress policy cancels the running pipeline", func(t *testing.T) {
+		db := newTestActionsDB(t)
+		startedFile := filepath.Join(t.TempDir(), "started")
+		first := config.Action{
+			Script:      "touch " + startedFile + "; sleep 5",
+			Timeout:     time.Minute,
+			Concurrency: config.Concurrency{Group: "deploy", Policy: config.ConcurrencyPolicyCancelInProgress},
+		}
+		second := config.Action{
+			Script:      "echo second",
+			Timeout:     time.Minute,
+			Concurrency: config.Concurrency{Group: "deploy", Policy: config.ConcurrencyPolicyCancelInProgress},
+		}
+		queue, pipeIDs := runPipelines(t, db, first, second)
+		push(t, queue, pipeIDs[0], first)
+		waitForFile(t, startedFile)
+		push(t, queue, pipeIDs[1], second)
+
+		rec := waitForRecord(t, db, pipeIDs[0])
+		if rec.Error == nil || !strings.Contains(rec.Error.Error(), ErrConcurrencyCancelled.Error()) {
+			t.Errorf("want the first pipeline to be cancelled, got error %v", rec.Error)
+		}
+		if rec := waitForRecord(t, db, pipeIDs[1]); rec.Error != nil {
+			t.Errorf("want the second pipeline to succeed, got %v", rec.Error)
+		}
+	})
+
+	t.Run("group with more pending pipelines than slots doesn't block others", func(t *testing.T) {
+		db := newTestActionsDB(t)
+		logFile := filepath.Join(t.TempDir(), "log")
+		deploy := config.Action{
+			Script:      "echo start >> " + logFile + "; sleep 0.3; echo end >> " + logFile,
+			Timeout:     time.Minute,
+			Concurrency: config.Concurrency{Group: "deploy"},
+		}
+		other := config.Action{Script: "echo other", Timeout: time.Minute}
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		queue := NewDBQueue(db, makeQueueConfig(deploy, other), logger)
+		r := New(context.Background(), queue, 2, db, tmpoutput.NewInMemoryTmpOutput(0))
+		t.Cleanup(func() {
+			r.Close()
+			r.Wait()
+		})
+
+		deployIDs := []string{"pipe-1", "pipe-2", "pipe-3", "pipe-4"}
+		for _, pipeID := range deployIDs {
+			if err := queue.Push(makeExecArgs(pipeID, deploy)); err != nil {
+				t.Fatalf("Push returned error: %v", err)
+			}
+		}
+		otherArgs := makeExecArgs("pipe-other", other)
+		otherArgs.ActionDesc.Index = 1
+		if err := queue.Push(otherArgs); err != nil {
+			t.Fatalf("Push returned error: %v", err)
+		}
+
+		if rec := waitForRecord(t, db, "pipe-other"); rec.Error != nil {
+			t.Errorf("pipeline without a group failed: %v", rec.Error)
+		}
+		for _, pipeID := range deployIDs[1:] {
+			rec, err := db.GetPipelineRecord(pipeID)
+			if err != nil {
+				t.Fatalf("record %q was not persisted: %v", pipeID, err)
+			}
+			if got := rec.Status(); got != actionsdb.PipeStatusQueued {
+				t.Errorf("want the waiting pipeline %q to stay queued, got %q", pipeID, got)
+			}
+		}
+		for _, pipeID := range deployIDs {
+			if rec := waitForRecord(t, db, pipeID); rec.Error != nil {
+				t.Errorf("pipeline %q failed: %v", pipeID, rec.Error)
+			}
+		}
+		log, err := os.ReadFile(logFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := strings.Repeat("start\nend\n", len(deployIDs)); string(log) != want {
+			t.Errorf("want the pipelines to run one by one %q, got %q", want, log)
+		}
+	})
+
+	t.Run("skip policy drops the new pipeline of a busy group", func(t *testing.T) {
+		db := newTestActionsDB(t)
+		startedFile := filepath.Join(t.TempDir(), "started")
+		first := config.Action{
+			Script:      "touch " + startedFile + "; sleep 0.5",
+			Timeout:     time.Minute,
+			Concurrency: config.Concurrency{Group: "migrations", Policy: config.ConcurrencyPolicySkip},
+		}
+		second := config.Action{
+			Script:      "echo second",
+			Timeout:     time.Minute,
+			Concurrency: config.Concurrency{Group: "migrations", Policy: config.ConcurrencyPolicySkip},
+		}
+		queue, pipeIDs := runPipelines(t, db, first, second)
+		push(t, queue, pipeIDs[0], first)
+		waitForFile(t, startedFile)
+		push(t, queue, pipeIDs[1], second)
+
+		rec := waitForRecord(t, db, pipeIDs[1])
+		if rec.Error == nil || !strings.Contains(rec.Error.Error(), ErrConcurrencySkipped.Error()) {
+			t.Errorf("want the second pipeline to be skipped, got error %v", rec.Error)
+		}
+		if rec := waitForRecord(t, db, pipeIDs[0]); rec.Error != nil {
+			t.Errorf("want the first pipeline to succeed, got %v", rec.Error)
+		}
+	})
+}
@@ -2,9 +2,14 @@ package actionrunner
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/jsonpointer"
+
 	"mvdan.cc/sh/v3/expand"
 	"mvdan.cc/sh/v3/syntax"
 )
@@ -20,11 +25,16 @@ var passthroughEnv = []string{"PATH", "HOME", "USER"}
 // tmpDir, when non-empty, is the runner-managed temporary directory exposed to
 // the action as $TMPDIR (see WithTempDir); empty means the action didn't request
 // one. CWD mirrors the action's `cwd` config, keeping a single source of truth.
+// payloadFile, when non-empty, is the runner-managed copy of the webhook
+// payload, exposed as $GIT_PAYLOAD_FILE.
 //
-// The action's config `environment` entries are interpolated and appended last,
-// so they may override any built-in or passed-through variable (for a duplicate
-// key os/exec uses the last value in the slice).
-func createEnv(args ActionArgs, tmpDir string) ([]string, error) {
+// The action's `env_from_payload` variables are resolved against the payload
+// and appended after the built-ins, so `environment` entries can reference
+// them. The action's config `environment` entries are interpolated and
+// appended last, so they may override any built-in or passed-through variable
+// (for a duplicate key os/exec uses the last value in the slice).
+func createEnv(args ActionArgs, tmpDir string, payloadFile string) ([]string, error) {
+	ref, refType := getRef(args)
 	env := []string{
 		fmt.Sprintf("PROJECT_NAME=%s", args.ActionDesc.Project),
 		fmt.Sprintf("ACTION_IDX=%d", args.ActionDesc.Index),
@@ -35,18 +45,62 @@ func createEnv(args ActionArgs, tmpDir string) ([]string, error) {
 		fmt.Sprintf("GIT_PROVIDER=%s", args.ActionDesc.GitProvider),
 		fmt.Sprintf("GIT_REPO=%s", args.ActionDesc.Repo),
 		fmt.Sprintf("GIT_BRANCH=%s", args.Branch),
+		fmt.Sprintf("GIT_TAG=%s", args.Tag),
+		fmt.Sprintf("GIT_REF=%s", ref),
+		fmt.Sprintf("GIT_REF_TYPE=%s", refType),
 		fmt.Sprintf("GIT_EVENT=%s", args.Event),
 		fmt.Sprintf("CWD=%s", args.ActionDesc.Config.Cwd),
 	}
+	if pr := args.PullRequest; pr != nil {
+		env = append(env,
+			fmt.Sprintf("GIT_PR_NUMBER=%d", pr.Number),
+			fmt.Sprintf("GIT_PR_ACTION=%s", args.Action),
+			fmt.Sprintf("GIT_PR_TITLE=%s", pr.Title),
+			fmt.Sprintf("GIT_PR_AUTHOR=%s", pr.Author),
+			fmt.Sprintf("GIT_PR_HEAD_BRANCH=%s", pr.HeadBranch),
+			fmt.Sprintf("GIT_PR_BASE_BRANCH=%s", pr.BaseBranch),
+			fmt.Sprintf("GIT_PR_HEAD_SHA=%s", pr.HeadHash),
+		)
+	}
+	if release := args.Release; release != nil {
+		env = append(env,
+			fmt.Sprintf("GIT_RELEASE_TAG=%s", release.Tag),
+			fmt.Sprintf("GIT_RELEASE_NAME=%s", release.Name),
+			fmt.Sprintf("GIT_RELEASE_ACTION=%s", args.Action),
+			fmt.Sprintf("GIT_RELEASE_PRERELEASE=%t", release.Prerelease),
+			fmt.Sprintf("GIT_RELEASE_DRAFT=%t", release.Draft),
+		)
+	}
+	if push := args.Push; push != nil {
+		env = append(env,
+			fmt.Sprintf("GIT_BEFORE=%s", push.Before),
+			fmt.Sprintf("GIT_PUSHER=%s", push.Pusher),
+			fmt.Sprintf("GIT_COMMIT_MESSAGE=%s", push.CommitMessage),
+			fmt.Sprintf("GIT_COMMIT_COUNT=%d", push.CommitCount),
+			fmt.Sprintf("GIT_COMPARE_URL=%s", push.CompareURL),
+			fmt.Sprintf("GIT_REF_CREATED=%t", push.Created),
+			fmt.Sprintf("GIT_REF_DELETED=%t", push.Deleted),
+			fmt.Sprintf("GIT_FORCED=%t", push.Forced),
+		)
+	}
 	if tmpDir != "" {
 		env = append(env, fmt.Sprintf("TMPDIR=%s", tmpDir))
 	}
+	if payloadFile != "" {
+		env = append(env, fmt.Sprintf("GIT_PAYLOAD_FILE=%s", payloadFile))
+	}
 	for _, key := range passthroughEnv {
 		if val, ok := os.LookupEnv(key); ok {
 			env = append(env, fmt.Sprintf("%s=%s", key, val))
 		}
 	}
 
+	payloadEnv, err := resolvePayloadEnv(args.ActionDesc.Config.EnvFromPayload, args.Payload)
+	if err != nil {
+		return nil, err
+	}
+	env = append(env, payloadEnv...)
+
 	userEnv, err := expandEnvEntries(args.ActionDesc.Config.Environment, env)
 	if err != nil {
 		return nil, err
@@ -54,6 +108,50 @@ func createEnv(args ActionArgs, tmpDir string) ([]string, error) {
 	return append(env, userEnv...), nil
 }
 
+// resolvePayloadEnv resolves the `env_from_payload` variables against the JSON
+// payload, in the "KEY=VALUE" form sorted by key. Missing values are replaced
+// with their defaults, or result in an error for the required variables.
+func resolvePayloadEnv(payloadEnv config.PayloadEnv, payload []byte) ([]string, error) {
+	if len(payloadEnv) == 0 {
+		return nil, nil
+	}
+	doc, err := jsonpointer.Unmarshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the webhook payload for 'env_from_payload': %w", err)
+	}
+	env := make([]string, 0, len(payloadEnv))
+	for _, key := range slices.Sorted(maps.Keys(payloadEnv)) {
+		v := payloadEnv[key]
+		// pointers are validated on config load
+		pointer, err := jsonpointer.Parse(v.Path)
+		if err != nil {
+			return nil, fmt.Errorf("'env_from_payload' variable %s: %w", key, err)
+		}
+		value, ok := pointer.GetString(doc)
+		if !ok {
+			if v.Required {
+				return nil, fmt.Errorf("'env_from_payload' variable %s is required, but %q is missing in the payload", key, v.Path)
+			}
+			value = v.Default
+		}
+		env = append(env, key+"="+value)
+	}
+	return env, nil
+}
+
+// getRef restores the full ref name and its type ("branch" or "tag") out of
+// the branch or tag name. Both are empty, if the event isn't for a ref.
+func getRef(args ActionArgs) (ref string, refType string) {
+	switch {
+	case args.Tag != "":
+		return "refs/tags/" + args.Tag, "tag"
+	case args.Branch != "":
+		return "refs/heads/" + args.Branch, "branch"
+	default:
+		return "", ""
+	}
+}
+
 // expandEnvEntries interpolates the "KEY=VALUE" config entries, resolving
 // ${VAR}, ${VAR:-default}, ${VAR:?error} and the like against the receiver's
 // process environment layered under `base` (so both os.Environ() variables and
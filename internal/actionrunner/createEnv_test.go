@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/whreceiver"
 )
 
 func makeArgs(environment []string) ActionArgs {
@@ -39,7 +40,7 @@ func envValue(env []string, key string) (string, bool) {
 }
 
 func TestCreateEnvBuiltins(t *testing.T) {
-	env, err := createEnv(makeArgs(nil), "")
+	env, err := createEnv(makeArgs(nil), "", "")
 	if err != nil {
 		t.Fatalf("createEnv returned error: %v", err)
 	}
@@ -49,6 +50,9 @@ func TestCreateEnvBuiltins(t *testing.T) {
 		"GIT_PROVIDER": "github",
 		"GIT_REPO":     "user/repo",
 		"GIT_BRANCH":   "master",
+		"GIT_TAG":      "",
+		"GIT_REF":      "refs/heads/master",
+		"GIT_REF_TYPE": "branch",
 		"GIT_EVENT":    "push",
 		"DELIVERY_ID":  "delivery-1",
 	}
@@ -59,11 +63,133 @@ func TestCreateEnvBuiltins(t *testing.T) {
 	}
 }
 
+func TestCreateEnvTag(t *testing.T) {
+	args := makeArgs(nil)
+	args.Branch = ""
+	args.Tag = "v1.2.0"
+	env, err := createEnv(args, "", "")
+	if err != nil {
+		t.Fatalf("createEnv returned error: %v", err)
+	}
+	want := map[string]string{
+		"GIT_BRANCH":   "",
+		"GIT_TAG":      "v1.2.0",
+		"GIT_REF":      "refs/tags/v1.2.0",
+		"GIT_REF_TYPE": "tag",
+	}
+	for k, v := range want {
+		if got, ok := envValue(env, k); !ok || got != v {
+			t.Errorf("env[%q] = %q, %v; want %q", k, got, ok, v)
+		}
+	}
+}
+
+func TestCreateEnvPullRequest(t *testing.T) {
+	args := makeArgs(nil)
+	if _, ok := envValue(mustCreateEnv(t, args), "GIT_PR_NUMBER"); ok {
+		t.Error("expected GIT_PR_NUMBER to be absent for non pull request events")
+	}
+
+	args.Event = "pull_request"
+	args.Action = "opened"
+	args.PullRequest = &whreceiver.PullRequestInfo{
+		Number:     42,
+		Title:      "Add feature",
+		Author:     "octocat",
+		HeadBranch: "feature",
+		BaseBranch: "master",
+		HeadHash:   "def456",
+	}
+	env := mustCreateEnv(t, args)
+	want := map[string]string{
+		"GIT_PR_NUMBER":      "42",
+		"GIT_PR_ACTION":      "opened",
+		"GIT_PR_TITLE":       "Add feature",
+		"GIT_PR_AUTHOR":      "octocat",
+		"GIT_PR_HEAD_BRANCH": "feature",
+		"GIT_PR_BASE_BRANCH": "master",
+		"GIT_PR_HEAD_SHA":    "def456",
+	}
+	for k, v := range want {
+		if got, ok := envValue(env, k); !ok || got != v {
+			t.Errorf("env[%q] = %q, %v; want %q", k, got, ok, v)
+		}
+	}
+}
+
+func TestCreateEnvRelease(t *testing.T) {
+	args := makeArgs(nil)
+	args.Event = "release"
+	args.Action = "published"
+	args.Branch = ""
+	args.Tag = "v1.0.0-rc.1"
+	args.Release = &whreceiver.ReleaseInfo{
+		Tag:        "v1.0.0-rc.1",
+		Name:       "First RC",
+		Prerelease: true,
+	}
+	env := mustCreateEnv(t, args)
+	want := map[string]string{
+		"GIT_RELEASE_TAG":        "v1.0.0-rc.1",
+		"GIT_RELEASE_NAME":       "First RC",
+		"GIT_RELEASE_ACTION":     "published",
+		"GIT_RELEASE_PRERELEASE": "true",
+		"GIT_RELEASE_DRAFT":      "false",
+		"GIT_REF":                "refs/tags/v1.0.0-rc.1",
+	}
+	for k, v := range want {
+		if got, ok := envValue(env, k); !ok || got != v {
+			t.Errorf("env[%q] = %q, %v; want %q", k, got, ok, v)
+		}
+	}
+}
+
+func TestCreateEnvPush(t *testing.T) {
+	args := makeArgs(nil)
+	if _, ok := envValue(mustCreateEnv(t, args), "GIT_BEFORE"); ok {
+		t.Error("expected GIT_BEFORE to be absent for non push events")
+	}
+
+	args.Push = &whreceiver.PushInfo{
+		Pusher:        "octocat",
+		Before:        "abc000",
+		CommitMessage: "Fix the build\n\nProper description",
+		CommitCount:   3,
+		CompareURL:    "https://github.com/octocat/repo/compare/abc000...abc123",
+		Forced:        true,
+	}
+	env := mustCreateEnv(t, args)
+	want := map[string]string{
+		"GIT_BEFORE":         "abc000",
+		"GIT_PUSHER":         "octocat",
+		"GIT_COMMIT_MESSAGE": "Fix the build\n\nProper description",
+		"GIT_COMMIT_COUNT":   "3",
+		"GIT_COMPARE_URL":    "https://github.com/octocat/repo/compare/abc000...abc123",
+		"GIT_REF_CREATED":    "false",
+		"GIT_REF_DELETED":    "false",
+		"GIT_FORCED":         "true",
+	}
+	for k, v := range want {
+		if got, ok := envValue(env, k); !ok || got != v {
+			t.Errorf("env[%q] = %q, %v; want %q", k, got, ok, v)
+		}
+	}
+}
+
+func mustCreateEnv(t *testing.T, args ActionArgs) []string {
+	t.Helper()
+	env, err := createEnv(args, "", "")
+	if err != nil {
+		t.Fatalf("createEnv returned error: %v", err)
+	}
+	return env
+}
+
 func TestCreateEnvCwdAndTmpDir(t *testing.T) {
 	args := makeArgs([]string{"CLONE_TARGET=${TMPDIR}", "DEST=${CWD}"})
 	args.ActionDesc.Config.Cwd = "/var/www/app"
 
-	env, err := createEnv(args, "/tmp/git-webhook-receiver-xyz")
+	env, err := createEnv(args, "/tmp/git-webhook-receiver-xyz", "")
 	if err != nil {
 		t.Fatalf("createEnv returned error: %v", err)
 	}
@@ -80,7 +206,7 @@ func TestCreateEnvCwdAndTmpDir(t *testing.T) {
 	}
 
 	// No temp dir requested -> TMPDIR must be absent, not empty.
-	env, err = createEnv(makeArgs(nil), "")
+	env, err = createEnv(makeArgs(nil), "", "")
 	if err != nil {
 		t.Fatalf("createEnv returned error: %v", err)
 	}
@@ -89,6 +215,23 @@ func TestCreateEnvCwdAndTmpDir(t *testing.T) {
 	}
 }
 
+func TestCreateEnvPayloadFile(t *testing.T) {
+	env := mustCreateEnv(t, makeArgs(nil))
+	if _, ok := envValue(env, "GIT_PAYLOAD_FILE"); ok {
+		t.Error("GIT_PAYLOAD_FILE present when there's no payload file")
+	}
+
+	env, err := createEnv(makeArgs([]string{"EVENT_PATH=${GIT_PAYLOAD_FILE}"}), "", "/tmp/git-webhook-receiver-payload-1.json")
+	if err != nil {
+		t.Fatalf("createEnv returned error: %v", err)
+	}
+	for _, k := range []string{"GIT_PAYLOAD_FILE", "EVENT_PATH"} {
+		if got, _ := envValue(env, k); got != "/tmp/git-webhook-receiver-payload-1.json" {
+			t.Errorf("env[%q] = %q; want %q", k, got, "/tmp/git-webhook-receiver-payload-1.json")
+		}
+	}
+}
+
 func TestCreateEnvInterpolation(t *testing.T) {
 	t.Setenv("MY_TOKEN", "s3cr3t")
 
@@ -97,7 +240,7 @@ func TestCreateEnvInterpolation(t *testing.T) {
 		"WITH_DEFAULT=${MISSING:-fallback}",
 		"REPLACE=${MY_TOKEN:+present}",
 		"REF_BUILTIN=${GIT_COMMIT}",
-	}), "")
+	}), "", "")
 	if err != nil {
 		t.Fatalf("createEnv returned error: %v", err)
 	}
@@ -116,7 +259,7 @@ func TestCreateEnvInterpolation(t *testing.T) {
 }
 
 func TestCreateEnvOverridesBuiltin(t *testing.T) {
-	env, err := createEnv(makeArgs([]string{"GIT_COMMIT=overridden"}), "")
+	env, err := createEnv(makeArgs([]string{"GIT_COMMIT=overridden"}), "", "")
 	if err != nil {
 		t.Fatalf("createEnv returned error: %v", err)
 	}
@@ -140,7 +283,7 @@ func TestCreateEnvCascadingReferences(t *testing.T) {
 		"CHILD=${ROOT}/child", // project references root
 		"LEAF=${CHILD}/leaf",  // action references project
 		"ROOT=overridden",     // action overrides root
-	}), "")
+	}), "", "")
 	if err != nil {
 		t.Fatalf("createEnv returned error: %v", err)
 	}
@@ -157,7 +300,7 @@ func TestCreateEnvCascadingReferences(t *testing.T) {
 }
 
 func TestCreateEnvUnsetRequiredErrors(t *testing.T) {
-	_, err := createEnv(makeArgs([]string{"X=${DEFINITELY_MISSING_VAR:?is required}"}), "")
+	_, err := createEnv(makeArgs([]string{"X=${DEFINITELY_MISSING_VAR:?is required}"}), "", "")
 	if err == nil {
 		t.Fatal("expected error for unset required variable, got nil")
 	}
@@ -167,8 +310,76 @@ func TestCreateEnvUnsetRequiredErrors(t *testing.T) {
 }
 
 func TestCreateEnvNoCommandSubstitution(t *testing.T) {
-	_, err := createEnv(makeArgs([]string{"X=$(echo pwned)"}), "")
+	_, err := createEnv(makeArgs([]string{"X=$(echo pwned)"}), "", "")
 	if err == nil {
 		t.Fatal("expected error for command substitution, got nil")
 	}
 }
+
+func TestCreateEnvFromPayload(t *testing.T) {
+	payload := []byte(`{"head_commit":{"author":{"email":"dev@example.com"}},"repository":{"id":32167,"private":false}}`)
+	makePayloadArgs := func(payloadEnv config.PayloadEnv, environment []string) ActionArgs {
+		args := makeArgs(environment)
+		args.ActionDesc.Config.EnvFromPayload = payloadEnv
+		args.Payload = payload
+		return args
+	}
+
+	t.Run("resolves pointers, falling back to defaults", func(t *testing.T) {
+		env, err := createEnv(makePayloadArgs(config.PayloadEnv{
+			"AUTHOR_EMAIL": {Path: "/head_commit/author/email", Required: true},
+			"REPO_ID":      {Path: "/repository/id"},
+			"PRIVATE":      {Path: "/repository/private"},
+			"CLONE_URL":    {Path: "/repository/clone_url", Default: "git@example.com:repo.git"},
+			"MISSING":      {Path: "/missing"},
+		}, nil), "", "")
+		if err != nil {
+			t.Fatalf("createEnv returned error: %v", err)
+		}
+		want := map[string]string{
+			"AUTHOR_EMAIL": "dev@example.com",
+			"REPO_ID":      "32167",
+			"PRIVATE":      "false",
+			"CLONE_URL":    "git@example.com:repo.git",
+			"MISSING":      "",
+		}
+		for k, v := range want {
+			if got, ok := envValue(env, k); !ok || got != v {
+				t.Errorf("env[%q] = %q, %v; want %q", k, got, ok, v)
+			}
+		}
+	})
+
+	t.Run("is visible to environment interpolation", func(t *testing.T) {
+		env, err := createEnv(makePayloadArgs(
+			config.PayloadEnv{"AUTHOR_EMAIL": {Path: "/head_commit/author/email"}},
+			[]string{"NOTIFY=mailto:${AUTHOR_EMAIL}"},
+		), "", "")
+		if err != nil {
+			t.Fatalf("createEnv returned error: %v", err)
+		}
+		if got, _ := envValue(env, "NOTIFY"); got != "mailto:dev@example.com" {
+			t.Errorf("env[NOTIFY] = %q; want %q", got, "mailto:dev@example.com")
+		}
+	})
+
+	t.Run("errors on missing required values", func(t *testing.T) {
+		_, err := createEnv(makePayloadArgs(config.PayloadEnv{
+			"CLONE_URL": {Path: "/repository/clone_url", Required: true},
+		}, nil), "", "")
+		if err == nil {
+			t.Fatal("expected error for missing required value, got nil")
+		}
+		if !strings.Contains(err.Error(), "CLONE_URL") || !strings.Contains(err.Error(), "/repository/clone_url") {
+			t.Errorf("error %q doesn't mention the variable and its path", err)
+		}
+	})
+
+	t.Run("errors on malformed payload", func(t *testing.T) {
+		args := makePayloadArgs(config.PayloadEnv{"REPO_ID": {Path: "/repository/id"}}, nil)
+		args.Payload = []byte("not json")
+		if _, err := createEnv(args, "", ""); err == nil {
+			t.Fatal("expected error for malformed payload, got nil")
+		}
+	})
+}
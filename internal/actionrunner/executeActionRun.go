@@ -13,6 +13,7 @@ func executeActionRun(
 	action config.Action,
 	env []string,
 	sysProcAttr *syscall.SysProcAttr,
+	stdin io.Reader,
 	output io.Writer,
 ) error {
 	cmd := newCmd(ctx, action.Run[0], action.Run[1:], sysProcAttr, action.GracefulShutdown)
@@ -20,6 +21,7 @@ func executeActionRun(
 		cmd.Dir = action.Cwd
 	}
 	cmd.Env = env
+	cmd.Stdin = stdin
 	cmd.Stdout = output
 	cmd.Stderr = output
 	return cmd.Run()
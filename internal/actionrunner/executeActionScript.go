@@ -20,6 +20,7 @@ func executeActionScript(
 	action config.Action,
 	env []string,
 	sysProcAttr *syscall.SysProcAttr,
+	stdin io.Reader,
 	output io.Writer,
 ) error {
 	script, err := syntax.NewParser().Parse(strings.NewReader(action.Script), "")
@@ -30,7 +31,7 @@ func executeActionScript(
 	runner, err := interp.New(
 		interp.Env(expand.ListEnviron(env...)),
 		interp.ExecHandlers(execHandler(sysProcAttr, action.GracefulShutdown)),
-		interp.StdIO(nil, output, output),
+		interp.StdIO(stdin, output, output),
 		interp.Dir(action.Cwd),
 		interp.Params("-e", "-o", "pipefail"),
 	)
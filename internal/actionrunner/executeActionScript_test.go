@@ -13,7 +13,7 @@ func TestExecuteActionScriptErrExitAborts(t *testing.T) {
 	var out bytes.Buffer
 	action := config.Action{Script: "false\necho after-failure"}
 
-	err := executeActionScript(context.Background(), action, nil, nil, &out)
+	err := executeActionScript(context.Background(), action, nil, nil, nil, &out)
 
 	if err == nil {
 		t.Error("expected a non-nil error from the failed command, got nil")
@@ -27,7 +27,7 @@ func TestExecuteActionScriptSetPlusEOverrides(t *testing.T) {
 	var out bytes.Buffer
 	action := config.Action{Script: "set +e\nfalse\necho after-failure"}
 
-	err := executeActionScript(context.Background(), action, nil, nil, &out)
+	err := executeActionScript(context.Background(), action, nil, nil, nil, &out)
 	if err != nil {
 		t.Errorf("expected nil error after `set +e`, got: %v", err)
 	}
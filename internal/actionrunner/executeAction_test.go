@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -26,7 +27,12 @@ func (f failingTmpOutput) Reader(context.Context, string) (io.Reader, bool) { re
 
 func newTestActionsDB(t *testing.T) *actionsdb.ActionDB {
 	t.Helper()
-	db, err := actionsdb.New(filepath.Join(t.TempDir(), "actions.sqlite3"), 0)
+	return newTestActionsDBFile(t, filepath.Join(t.TempDir(), "actions.sqlite3"))
+}
+
+func newTestActionsDBFile(t *testing.T, dbFile string) *actionsdb.ActionDB {
+	t.Helper()
+	db, err := actionsdb.New(dbFile, 0)
 	if err != nil {
 		t.Fatalf("failed to create test actions db: %v", err)
 	}
@@ -50,6 +56,17 @@ func makeExecArgs(pipeID string, cfg config.Action) ActionArgs {
 	}
 }
 
+// createExecRecord creates the pipeline record of the action, as it's done by
+// the queue before the action is run.
+func createExecRecord(t *testing.T, db *actionsdb.ActionDB, args ActionArgs) {
+	t.Helper()
+	desc := args.ActionDesc
+	err := db.CreateRecord(desc.PipeID, desc.Project, args.DeliveryID, "", args.Hash, false, nil, desc.Config)
+	if err != nil {
+		t.Fatalf("failed to create the pipeline record: %v", err)
+	}
+}
+
 // assertRecordClosedWithError checks the pipeline record exists, has been closed
 // (EndedAt set) and carries a non-nil error whose message contains msgSubstr.
 func assertRecordClosedWithError(t *testing.T, db *actionsdb.ActionDB, pipeID, msgSubstr string) {
@@ -108,6 +125,7 @@ func TestExecuteActionSuccessClosesRecordWithOutput(t *testing.T) {
 		Timeout: time.Minute,
 	})
 
+	createExecRecord(t, db, args)
 	r.executeAction(context.Background(), args)
 
 	rec, err := db.GetPipelineRecord(pipeID)
@@ -130,6 +148,52 @@ func TestExecuteActionSuccessClosesRecordWithOutput(t *testing.T) {
 	}
 }
 
+// The payload must be available to the action in $GIT_PAYLOAD_FILE and, with
+// `payload_stdin`, on its stdin. The file must be removed after the run.
+func TestExecuteActionPayloadFileAndStdin(t *testing.T) {
+	db := newTestActionsDB(t)
+	r := &ActionRunner{actionsDB: db, tmpOutputMgr: tmpoutput.NewInMemoryTmpOutput(0)}
+
+	pipeID := "pipe-payload"
+	args := makeExecArgs(pipeID, config.Action{
+		Script: `read -r fromFile < "$GIT_PAYLOAD_FILE"
+read -r fromStdin
+echo "file=$fromFile"
+echo "stdin=$fromStdin"
+echo "path=$GIT_PAYLOAD_FILE"`,
+		PayloadStdin: true,
+		Timeout:      time.Minute,
+	})
+	args.Payload = []byte("{\"ref\":\"refs/heads/master\"}\n")
+
+	createExecRecord(t, db, args)
+	r.executeAction(context.Background(), args)
+
+	rec, err := db.GetPipelineRecord(pipeID)
+	if err != nil {
+		t.Fatalf("record %q was not persisted: %v", pipeID, err)
+	}
+	if rec.Error != nil {
+		t.Fatalf("record %q closed with error %q; want none", pipeID, rec.Error)
+	}
+	out, err := db.GetPipelineOutput(pipeID)
+	if err != nil {
+		t.Fatalf("failed to read output for %q: %v", pipeID, err)
+	}
+	for _, want := range []string{`file={"ref":"refs/heads/master"}`, `stdin={"ref":"refs/heads/master"}`} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output = %q; want it to contain %q", out, want)
+		}
+	}
+	_, path, found := strings.Cut(string(out), "path=")
+	if !found || strings.TrimSpace(path) == "" {
+		t.Fatalf("output = %q; want it to contain the payload file path", out)
+	}
+	if _, err := os.Stat(strings.TrimSpace(path)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("payload file %q wasn't removed after the run: %v", path, err)
+	}
+}
+
 // A createEnv failure (here: forbidden command substitution) must still close
 // the pipeline record, tagged with the environment-build error.
 func TestExecuteActionEnvErrorClosesRecord(t *testing.T) {
@@ -142,12 +206,33 @@ func TestExecuteActionEnvErrorClosesRecord(t *testing.T) {
 		Environment: config.EnvList{"X=$(echo pwned)"},
 	})
 
+	createExecRecord(t, db, args)
 	r.executeAction(context.Background(), args)
 
 	assertRecordClosedWithError(t, db, pipeID, "action environment")
 	assertOutputEmpty(t, db, pipeID)
 }
 
+// A required env_from_payload value missing in the payload must close the
+// pipeline record with the environment-build error.
+func TestExecuteActionRequiredPayloadEnvClosesRecord(t *testing.T) {
+	db := newTestActionsDB(t)
+	r := &ActionRunner{actionsDB: db, tmpOutputMgr: tmpoutput.NewInMemoryTmpOutput(0)}
+
+	pipeID := "pipe-payload-env-error"
+	args := makeExecArgs(pipeID, config.Action{
+		Run:            []string{"true"},
+		EnvFromPayload: config.PayloadEnv{"X": {Path: "/missing", Required: true}},
+	})
+	args.Payload = []byte(`{}`)
+
+	createExecRecord(t, db, args)
+	r.executeAction(context.Background(), args)
+
+	assertRecordClosedWithError(t, db, pipeID, "is required")
+	assertOutputEmpty(t, db, pipeID)
+}
+
 // A getSysProcAttr failure (here: an unknown run-as user) must still close the
 // pipeline record, tagged with the process-attributes error.
 func TestExecuteActionSysProcAttrErrorClosesRecord(t *testing.T) {
@@ -161,15 +246,16 @@ func TestExecuteActionSysProcAttrErrorClosesRecord(t *testing.T) {
 		User: "nonexistent_user_for_test_zzz",
 	})
 
+	createExecRecord(t, db, args)
 	r.executeAction(context.Background(), args)
 
 	assertRecordClosedWithError(t, db, pipeID, "process attributes")
 	assertOutputEmpty(t, db, pipeID)
 }
 
-// Failing to create the temporary output file must still produce a closed
-// pipeline record with empty output (the run never starts).
-func TestExecuteActionTmpFileErrorCreatesEmptyRecord(t *testing.T) {
+// Failing to create the temporary output file must still close the pipeline
+// record with empty output (the run never starts).
+func TestExecuteActionTmpFileErrorClosesRecord(t *testing.T) {
 	db := newTestActionsDB(t)
 	r := &ActionRunner{
 		actionsDB:    db,
@@ -179,6 +265,7 @@ func TestExecuteActionTmpFileErrorCreatesEmptyRecord(t *testing.T) {
 	pipeID := "pipe-tmpfile-error"
 	args := makeExecArgs(pipeID, config.Action{Run: []string{"true"}})
 
+	createExecRecord(t, db, args)
 	r.executeAction(context.Background(), args)
 
 	assertRecordClosedWithError(t, db, pipeID, "temporary file")
@@ -0,0 +1,33 @@
+package actionrunner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// writePayloadFile writes the webhook payload into a runner-managed file,
+// exposed to the action as $GIT_PAYLOAD_FILE. The file is created 0600 and
+// handed over to the action's user, as the payload may contain private data
+// (commit messages and author emails of private repos and such). It's up to
+// the caller to remove the file once the action is finished.
+func writePayloadFile(payload []byte, sysProcAttr *syscall.SysProcAttr) (string, error) {
+	f, err := os.CreateTemp("", "git-webhook-receiver-payload-*.json")
+	if err != nil {
+		return "", err
+	}
+	name := f.Name()
+	_, err = f.Write(payload)
+	err = errors.Join(err, f.Close())
+	if err == nil {
+		err = chownActionPath(name, sysProcAttr)
+	}
+	if err != nil {
+		if rmErr := os.Remove(name); rmErr != nil {
+			err = errors.Join(err, fmt.Errorf("error removing the payload file: %w", rmErr))
+		}
+		return "", err
+	}
+	return name, nil
+}
@@ -0,0 +1,34 @@
+package actionrunner
+
+import (
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestWritePayloadFile(t *testing.T) {
+	payload := []byte(`{"ref":"refs/heads/master"}`)
+	name, err := writePayloadFile(payload, nil)
+	if err != nil {
+		t.Fatalf("writePayloadFile returned error: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Remove(name) })
+
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("failed to read the payload file: %v", err)
+	}
+	if string(got) != string(payload) {
+		t.Errorf("payload file contents = %q; want %q", got, payload)
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("payload file mode = %o; want 600", mode)
+	}
+}
@@ -21,15 +21,28 @@ var (
 
 // output is also stored in a row, but it's only fetched via a separate query
 type pipelineRecordDTO struct {
-	ID         int64           `db:"id"`
-	PipeID     string          `db:"pipe_id"`
-	Project    string          `db:"project"`
-	DeliveryID string          `db:"delivery_id"`
-	Hash       sql.NullString  `db:"hash"`
-	Config     json.RawMessage `db:"config"`
-	Error      sql.NullString  `db:"error"`
-	CreatedAt  int64           `db:"created_at"`
-	EndedAt    sql.NullInt64   `db:"ended_at"`
+	ID         int64  `db:"id"`
+	PipeID     string `db:"pipe_id"`
+	Project    string `db:"project"`
+	DeliveryID string `db:"delivery_id"`
+	// NULL for pipelines created before deliveries were recorded
+	DeliveryRecordID sql.NullString  `db:"delivery_record_id"`
+	Replay           bool            `db:"replay"`
+	Hash             sql.NullString  `db:"hash"`
+	Config           json.RawMessage `db:"config"`
+	Error            sql.NullString  `db:"error"`
+	CreatedAt        int64           `db:"created_at"`
+	StartedAt        sql.NullInt64   `db:"started_at"`
+	EndedAt          sql.NullInt64   `db:"ended_at"`
+	// push info, commit_count is NULL for non-push events
+	Pusher        sql.NullString `db:"pusher"`
+	BeforeHash    sql.NullString `db:"before_hash"`
+	CommitMessage sql.NullString `db:"commit_message"`
+	CommitCount   sql.NullInt64  `db:"commit_count"`
+	CompareURL    sql.NullString `db:"compare_url"`
+	RefCreated    sql.NullBool   `db:"ref_created"`
+	RefDeleted    sql.NullBool   `db:"ref_deleted"`
+	Forced        sql.NullBool   `db:"forced"`
 }
 
 func (r pipelineRecordDTO) ToModel() PipeLineRecord {
@@ -37,43 +50,100 @@ func (r pipelineRecordDTO) ToModel() PipeLineRecord {
 	if r.Error.Valid {
 		pipeErr = errors.New(r.Error.String)
 	}
-	var endedAt *time.Time
+	var startedAt, endedAt *time.Time
+	if r.StartedAt.Valid {
+		t := time.UnixMilli(r.StartedAt.Int64).UTC()
+		startedAt = &t
+	}
 	if r.EndedAt.Valid {
 		t := time.UnixMilli(r.EndedAt.Int64).UTC()
 		endedAt = &t
 	}
+	var push *PushRecord
+	if r.CommitCount.Valid {
+		push = &PushRecord{
+			Pusher:        r.Pusher.String,
+			Before:        r.BeforeHash.String,
+			CommitMessage: r.CommitMessage.String,
+			CommitCount:   int(r.CommitCount.Int64),
+			CompareURL:    r.CompareURL.String,
+			Created:       r.RefCreated.Bool,
+			Deleted:       r.RefDeleted.Bool,
+			Forced:        r.Forced.Bool,
+		}
+	}
 	return PipeLineRecord{
 		ID:         r.ID,
 		PipeID:     r.PipeID,
 		Project:    r.Project,
 		DeliveryID: r.DeliveryID,
-		Hash:       r.Hash.String,
-		Config:     r.Config,
-		Error:      pipeErr,
-		CreatedAt:  time.UnixMilli(r.CreatedAt).UTC(),
-		EndedAt:    endedAt,
+		// empty if it's not recorded
+		DeliveryRecordID: r.DeliveryRecordID.String,
+		Replay:           r.Replay,
+		Hash:             r.Hash.String,
+		Config:           r.Config,
+		Error:            pipeErr,
+		CreatedAt:        time.UnixMilli(r.CreatedAt).UTC(),
+		StartedAt:        startedAt,
+		EndedAt:          endedAt,
+		Push:             push,
 	}
 }
 
 // PipeLineRecord is the domain representation of a pipeline run. Error is nil
 // when the pipeline didn't error out; a non-nil Error (even with an empty
-// message) means the pipeline failed. EndedAt is nil while the pipeline is
-// still running.
+// message) means the pipeline failed. StartedAt is nil while the pipeline is
+// queued, and EndedAt is nil while it's queued or still running. Push is nil
+// unless the pipeline was triggered by a push event.
 type PipeLineRecord struct {
 	ID         int64
 	PipeID     string
 	Project    string
 	DeliveryID string
-	Hash       string
-	Config     json.RawMessage
-	Error      error
-	CreatedAt  time.Time
-	EndedAt    *time.Time
+	// RecordID of the delivery which started the pipeline, see [DeliveryRecord]
+	DeliveryRecordID string
+	// Replay is set, if the pipeline was started by a redelivery of the stored
+	// delivery
+	Replay    bool
+	Hash      string
+	Config    json.RawMessage
+	Error     error
+	CreatedAt time.Time
+	StartedAt *time.Time
+	EndedAt   *time.Time
+	Push      *PushRecord
+}
+
+// PushRecord is the push context of the pipeline, triggered by a push event.
+type PushRecord struct {
+	Pusher        string
+	Before        string
+	CommitMessage string
+	CommitCount   int
+	CompareURL    string
+	Created       bool
+	Deleted       bool
+	Forced        bool
+}
+
+// Status of the pipeline, one of ok, error, pending or queued.
+func (r PipeLineRecord) Status() PipeStatus {
+	switch {
+	case r.EndedAt == nil && r.StartedAt == nil:
+		return PipeStatusQueued
+	case r.EndedAt == nil:
+		return PipeStatusPending
+	case r.Error != nil:
+		return PipeStatusError
+	default:
+		return PipeStatusOk
+	}
 }
 
 type PipeLineConfigSummary struct {
-	Branch string `json:"branch"`
-	On     string `json:"on"`
+	Branch config.PatternList `json:"branch"`
+	Tag    string             `json:"tag"`
+	On     config.PatternList `json:"on"`
 }
 
 func (r PipeLineRecord) ParseConfigSummary() (PipeLineConfigSummary, error) {
@@ -91,6 +161,24 @@ type ActionDB struct {
 //go:embed Init.sql
 var schema string
 
+//go:embed AddPushInfo.sql
+var addPushInfoMigration string
+
+//go:embed AddPushRefFlags.sql
+var addPushRefFlagsMigration string
+
+//go:embed AddSeenDeliveries.sql
+var addSeenDeliveriesMigration string
+
+//go:embed AddDeliveries.sql
+var addDeliveriesMigration string
+
+//go:embed AddReplayFlag.sql
+var addReplayFlagMigration string
+
+//go:embed AddPipelineQueue.sql
+var addPipelineQueueMigration string
+
 func New(dbFileName string, maxActions int) (*ActionDB, error) {
 	if dbFileName == "" {
 		return nil, nil
@@ -100,7 +188,15 @@ func New(dbFileName string, maxActions int) (*ActionDB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error opening the actions db: %w", err)
 	}
-	err = sqlhelpers.NewMigrator(db).Migrate([]string{schema})
+	err = sqlhelpers.NewMigrator(db).Migrate([]string{
+		schema,
+		addPushInfoMigration,
+		addPushRefFlagsMigration,
+		addSeenDeliveriesMigration,
+		addDeliveriesMigration,
+		addReplayFlagMigration,
+		addPipelineQueueMigration,
+	})
 	if err != nil {
 		closeErr := db.Close()
 		return nil, errors.Join(fmt.Errorf("error applying actions db migrations: %w", err), closeErr)
@@ -118,10 +214,11 @@ func (d *ActionDB) Close() (err error) {
 
 // SweepStaleRecords moves all pending pipeline records to errored state; to
 // be called during a service startup, to cleanup records that were left stale
-// after a non-graceful shutdown
+// after a non-graceful shutdown. Queued records are left intact, as they are
+// picked up after the restart.
 func (d *ActionDB) SweepStaleRecords() (int64, error) {
 	const stalePipelineError = "pipeline was killed abruptly during a server crash"
-	query := `UPDATE pipelines SET error = ?, ended_at = ? WHERE ended_at IS NULL AND error IS NULL`
+	query := `UPDATE pipelines SET error = ?, ended_at = ? WHERE ended_at IS NULL AND error IS NULL AND started_at IS NOT NULL`
 
 	result, err := d.db.Exec(query, stalePipelineError, time.Now().UTC().UnixMilli())
 	if err != nil {
@@ -134,7 +231,29 @@ func (d *ActionDB) SweepStaleRecords() (int64, error) {
 	return rowsAffected, nil
 }
 
-func (d *ActionDB) CreateRecord(pipeID, project, deliveryID, hash string, conf config.Action) error {
+// CreateRecord creates a pending pipeline record, bypassing the queue;
+// deliveryRecordID links it to the recorded delivery and can be empty, replay
+// marks redelivered ones; push can be nil for non-push events.
+func (d *ActionDB) CreateRecord(
+	pipeID, project, deliveryID, deliveryRecordID, hash string,
+	replay bool,
+	push *PushRecord,
+	conf config.Action,
+) error {
+	return d.createRecord(pipeID, project, deliveryID, deliveryRecordID, hash, replay, push, conf, nil, 0)
+}
+
+// createRecord inserts the pipeline record. If entry is non-nil, the record is
+// created as queued along with its queue entry, failing with ErrQueueFull if
+// there are already maxQueued records in the queue.
+func (d *ActionDB) createRecord(
+	pipeID, project, deliveryID, deliveryRecordID, hash string,
+	replay bool,
+	push *PushRecord,
+	conf config.Action,
+	entry *QueueEntry,
+	maxQueued int,
+) error {
 	configJSON, err := json.Marshal(conf)
 	if err != nil {
 		return err
@@ -144,22 +263,79 @@ func (d *ActionDB) CreateRecord(pipeID, project, deliveryID, hash string, conf c
 		return err
 	}
 
-	var hashValue sql.NullString
+	var hashValue, deliveryRecordIDValue sql.NullString
 	if hash != "" {
 		hashValue = sql.NullString{Valid: true, String: hash}
 	}
-	query := `INSERT INTO pipelines (pipe_id, project, delivery_id, hash, config) VALUES (?, ?, ?, ?, ?)`
-	_, err = tx.Exec(query, pipeID, project, deliveryID, hashValue, configJSON)
+	if deliveryRecordID != "" {
+		deliveryRecordIDValue = sql.NullString{Valid: true, String: deliveryRecordID}
+	}
+	var pusher, before, commitMessage, compareURL sql.NullString
+	var commitCount sql.NullInt64
+	var refCreated, refDeleted, forced sql.NullBool
+	if push != nil {
+		pusher = sql.NullString{Valid: true, String: push.Pusher}
+		before = sql.NullString{Valid: true, String: push.Before}
+		commitMessage = sql.NullString{Valid: true, String: push.CommitMessage}
+		commitCount = sql.NullInt64{Valid: true, Int64: int64(push.CommitCount)}
+		compareURL = sql.NullString{Valid: true, String: push.CompareURL}
+		refCreated = sql.NullBool{Valid: true, Bool: push.Created}
+		refDeleted = sql.NullBool{Valid: true, Bool: push.Deleted}
+		forced = sql.NullBool{Valid: true, Bool: push.Forced}
+	}
+	var startedAt sql.NullInt64
+	if entry == nil {
+		startedAt = sql.NullInt64{Valid: true, Int64: time.Now().UTC().UnixMilli()}
+	}
+	args := []any{
+		pipeID, project, deliveryID, deliveryRecordIDValue, replay, hashValue, configJSON,
+		pusher, before, commitMessage, commitCount, compareURL,
+		refCreated, refDeleted, forced, startedAt,
+	}
+	query := `
+INSERT INTO pipelines (
+	pipe_id, project, delivery_id, delivery_record_id, replay, hash, config,
+	pusher, before_hash, commit_message, commit_count, compare_url,
+	ref_created, ref_deleted, forced, started_at
+) SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?`
+	if entry != nil {
+		// checked in the same statement, so concurrent inserts can't exceed the limit
+		query += "\nWHERE (SELECT count(*) FROM pipeline_queue) < ?"
+		args = append(args, maxQueued)
+	}
+	result, err := tx.Exec(query, args...)
 	if err != nil {
 		_ = tx.Rollback()
 		return err
 	}
 
-	// auto-removal of records above max actions config value
+	if entry != nil {
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		if rowsAffected == 0 {
+			_ = tx.Rollback()
+			return ErrQueueFull
+		}
+		_, err = tx.Exec(
+			`INSERT INTO pipeline_queue (pipe_id, action_idx, args, payload) VALUES (?, ?, ?, ?)`,
+			pipeID, entry.ActionIdx, entry.Args, entry.Payload,
+		)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	// auto-removal of records above max actions config value, queued records
+	// are kept, so they aren't lost before they had a chance to run
 	if d.maxActions > 0 {
 		autoRemoveQuery := `
 DELETE FROM pipelines WHERE pipe_id IN (
-		SELECT pipe_id FROM pipelines ORDER BY created_at DESC LIMIT -1 OFFSET ?
+		SELECT pipe_id FROM pipelines WHERE started_at IS NOT NULL OR ended_at IS NOT NULL
+		ORDER BY created_at DESC LIMIT -1 OFFSET ?
 )`
 		_, err = tx.Exec(autoRemoveQuery, d.maxActions)
 		if err != nil {
@@ -196,7 +372,8 @@ func (d *ActionDB) CloseRecord(pipeID string, actionErr error, output []byte) er
 	return err
 }
 
-const recordColumns = "id, pipe_id, project, delivery_id, hash, config, error, created_at, ended_at"
+const recordColumns = "id, pipe_id, project, delivery_id, delivery_record_id, replay, hash, config, error, created_at, started_at, ended_at, " +
+	"pusher, before_hash, commit_message, commit_count, compare_url, ref_created, ref_deleted, forced"
 
 func (d *ActionDB) GetPipelineRecord(pipeID string) (PipeLineRecord, error) {
 	var record pipelineRecordDTO
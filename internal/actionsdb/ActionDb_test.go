@@ -23,8 +23,8 @@ const (
 )
 
 var action = config.Action{
-	On:     "push",
-	Branch: "main",
+	On:     config.PatternList{"push"},
+	Branch: config.PatternList{"main"},
 	Cwd:    "/var/www",
 	User:   "www-data",
 	Script: "whoami",
@@ -43,7 +43,7 @@ func TestActionDb(t *testing.T) {
 			t.Fatalf("Unable to create a db: %s", err)
 		}
 
-		err = db.CreateRecord(pipeID, projectName, deliveryID, hash, action)
+		err = db.CreateRecord(pipeID, projectName, deliveryID, "", hash, false, nil, action)
 		if err != nil {
 			t.Fatalf("Unable to create a pipeline record: %s", err)
 		}
@@ -64,6 +64,35 @@ func TestActionDb(t *testing.T) {
 		compareAction(t, action, record)
 	})
 
+	t.Run("stores push info", func(t *testing.T) {
+		db, err := actionsdb.New(":memory:", defaultMaxActionsStored)
+		if err != nil {
+			t.Fatalf("Unable to create a db: %s", err)
+		}
+
+		push := &actionsdb.PushRecord{
+			Pusher:        "octocat",
+			Before:        "1234",
+			CommitMessage: "Fix the build",
+			CommitCount:   2,
+			CompareURL:    "https://example.com/compare/1234...6789",
+			Created:       true,
+			Forced:        true,
+		}
+		err = db.CreateRecord(pipeID, projectName, deliveryID, "", hash, false, push, action)
+		if err != nil {
+			t.Fatalf("Unable to create a pipeline record: %s", err)
+		}
+
+		record, err := db.GetPipelineRecord(pipeID)
+		if err != nil {
+			t.Fatalf("Unable to retrieve the created record: %s", err)
+		}
+		if !reflect.DeepEqual(record.Push, push) {
+			t.Errorf("Push info mismatch, want %+v, got %+v", push, record.Push)
+		}
+	})
+
 	t.Run("Close successful action", func(t *testing.T) {
 		actionOutput := "test output"
 		db, err := actionsdb.New(":memory:", defaultMaxActionsStored)
@@ -71,7 +100,7 @@ func TestActionDb(t *testing.T) {
 			t.Fatalf("Unable to create a db: %s", err)
 		}
 
-		err = db.CreateRecord(pipeID, projectName, deliveryID, hash, action)
+		err = db.CreateRecord(pipeID, projectName, deliveryID, "", hash, false, nil, action)
 		if err != nil {
 			t.Fatalf("Unable to create a pipeline record: %s", err)
 		}
@@ -106,7 +135,7 @@ func TestActionDb(t *testing.T) {
 			t.Fatalf("Unable to create a db: %s", err)
 		}
 
-		err = db.CreateRecord(pipeID, projectName, deliveryID, hash, action)
+		err = db.CreateRecord(pipeID, projectName, deliveryID, "", hash, false, nil, action)
 		if err != nil {
 			t.Fatalf("Unable to create a pipeline record: %s", err)
 		}
@@ -139,7 +168,7 @@ func TestActionDb(t *testing.T) {
 			t.Fatalf("Unable to create a db: %s", err)
 		}
 
-		err = db.CreateRecord(pipeID, projectName, deliveryID, hash, action)
+		err = db.CreateRecord(pipeID, projectName, deliveryID, "", hash, false, nil, action)
 		if err != nil {
 			t.Fatalf("Unable to create a pipeline record: %s", err)
 		}
@@ -169,7 +198,7 @@ func TestActionDb(t *testing.T) {
 			t.Fatalf("Unable to create a db: %s", err)
 		}
 
-		err = db.CreateRecord(pipeID, projectName, deliveryID, hash, action)
+		err = db.CreateRecord(pipeID, projectName, deliveryID, "", hash, false, nil, action)
 		if err != nil {
 			t.Fatalf("Unable to create a record: %s", err)
 		}
@@ -205,7 +234,7 @@ func TestAutoRemoval(t *testing.T) {
 	createRecord := func(t *testing.T, db *actionsdb.ActionDB) string {
 		t.Helper()
 		pipeID := ulid.Make().String()
-		err := db.CreateRecord(pipeID, projectName, deliveryID, hash, action)
+		err := db.CreateRecord(pipeID, projectName, deliveryID, "", hash, false, nil, action)
 		if err != nil {
 			t.Fatalf("Unable to create a pipeline record: %s", err)
 		}
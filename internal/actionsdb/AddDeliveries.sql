@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS deliveries (
+  id              INTEGER PRIMARY KEY NOT NULL,
+  record_id       TEXT NOT NULL,
+  project         TEXT NOT NULL,
+  delivery_id     TEXT NOT NULL,
+  event           TEXT NOT NULL,
+  headers         BLOB NOT NULL,
+  payload         BLOB,
+  payload_size    INTEGER NOT NULL,
+  verification    TEXT NOT NULL,
+  matched_actions BLOB NOT NULL,
+  status_code     INTEGER NOT NULL,
+  created_at      INTEGER DEFAULT (unixepoch('subsec') * 1000) NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS ix_unique_deliveries_recordId on deliveries(record_id);
+CREATE INDEX IF NOT EXISTS ix_deliveries_created  ON deliveries (             created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS ix_deliveries_project  ON deliveries (project,     created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS ix_deliveries_delivery ON deliveries (delivery_id, created_at DESC, id DESC);
+
+ALTER TABLE pipelines ADD COLUMN delivery_record_id TEXT;
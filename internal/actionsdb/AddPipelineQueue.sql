@@ -0,0 +1,9 @@
+ALTER TABLE pipelines ADD COLUMN started_at INTEGER;
+UPDATE pipelines SET started_at = created_at;
+
+CREATE TABLE IF NOT EXISTS pipeline_queue (
+  pipe_id    TEXT PRIMARY KEY NOT NULL REFERENCES pipelines (pipe_id) ON DELETE CASCADE,
+  action_idx INTEGER NOT NULL,
+  args       BLOB NOT NULL,
+  payload    BLOB
+);
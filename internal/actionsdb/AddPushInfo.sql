@@ -0,0 +1,5 @@
+ALTER TABLE pipelines ADD COLUMN pusher         TEXT;
+ALTER TABLE pipelines ADD COLUMN before_hash    TEXT;
+ALTER TABLE pipelines ADD COLUMN commit_message TEXT;
+ALTER TABLE pipelines ADD COLUMN commit_count   INTEGER;
+ALTER TABLE pipelines ADD COLUMN compare_url    TEXT;
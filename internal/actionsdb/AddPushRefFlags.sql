@@ -0,0 +1,3 @@
+ALTER TABLE pipelines ADD COLUMN ref_created INTEGER;
+ALTER TABLE pipelines ADD COLUMN ref_deleted INTEGER;
+ALTER TABLE pipelines ADD COLUMN forced      INTEGER;
@@ -0,0 +1 @@
+ALTER TABLE pipelines ADD COLUMN replay INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS seen_deliveries (
+  project     TEXT NOT NULL,
+  delivery_id TEXT NOT NULL,
+  received_at INTEGER DEFAULT (unixepoch('subsec') * 1000) NOT NULL,
+  PRIMARY KEY (project, delivery_id)
+);
+CREATE INDEX IF NOT EXISTS ix_seen_deliveries_received ON seen_deliveries (project, received_at DESC);
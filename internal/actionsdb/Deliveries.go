@@ -0,0 +1,157 @@
+package actionsdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Possible verification outcomes of a delivery
+const (
+	// project has no secret or authorization configured
+	DeliveryVerificationNone = "none"
+	// all of the configured checks passed
+	DeliveryVerificationPassed = "passed"
+	// authorization header didn't match
+	DeliveryVerificationAuthorizationFailed = "authorization_failed"
+	// payload signature didn't match
+	DeliveryVerificationSignatureFailed = "signature_failed"
+	// request was rejected before the verification, e.g. malformed payload
+	DeliveryVerificationUnchecked = "unchecked"
+)
+
+// DeliveryRecord is a webhook request received by a project, with the outcome
+// of its processing. Headers are expected to be masked and Payload capped by
+// the caller; PayloadSize is the size of the original payload, so payload was
+// truncated if it's bigger than len(Payload).
+type DeliveryRecord struct {
+	ID             int64
+	RecordID       string
+	Project        string
+	DeliveryID     string
+	Event          string
+	Headers        http.Header
+	Payload        []byte
+	PayloadSize    int
+	Verification   string
+	MatchedActions []DeliveryAction
+	StatusCode     int
+	CreatedAt      time.Time
+}
+
+// DeliveryAction is an action matched by the delivery. PipeID is empty if the
+// action was skipped.
+type DeliveryAction struct {
+	Index   int    `json:"actionIdx"`
+	PipeID  string `json:"pipeId,omitempty"`
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// IsPayloadTruncated reports if the stored payload was capped.
+func (r DeliveryRecord) IsPayloadTruncated() bool {
+	return r.PayloadSize > len(r.Payload)
+}
+
+// payload is only fetched for a single delivery, list queries leave it empty
+type deliveryRecordDTO struct {
+	ID             int64           `db:"id"`
+	RecordID       string          `db:"record_id"`
+	Project        string          `db:"project"`
+	DeliveryID     string          `db:"delivery_id"`
+	Event          string          `db:"event"`
+	Headers        json.RawMessage `db:"headers"`
+	Payload        []byte          `db:"payload"`
+	PayloadSize    int             `db:"payload_size"`
+	Verification   string          `db:"verification"`
+	MatchedActions json.RawMessage `db:"matched_actions"`
+	StatusCode     int             `db:"status_code"`
+	CreatedAt      int64           `db:"created_at"`
+}
+
+func (r deliveryRecordDTO) ToModel() (DeliveryRecord, error) {
+	record := DeliveryRecord{
+		ID:           r.ID,
+		RecordID:     r.RecordID,
+		Project:      r.Project,
+		DeliveryID:   r.DeliveryID,
+		Event:        r.Event,
+		Payload:      r.Payload,
+		PayloadSize:  r.PayloadSize,
+		Verification: r.Verification,
+		StatusCode:   r.StatusCode,
+		CreatedAt:    time.UnixMilli(r.CreatedAt).UTC(),
+	}
+	if err := json.Unmarshal(r.Headers, &record.Headers); err != nil {
+		return record, fmt.Errorf("malformed delivery headers: %w", err)
+	}
+	if err := json.Unmarshal(r.MatchedActions, &record.MatchedActions); err != nil {
+		return record, fmt.Errorf("malformed delivery matched actions: %w", err)
+	}
+	return record, nil
+}
+
+const deliveryListColumns = "id, record_id, project, delivery_id, event, headers, payload_size, " +
+	"verification, matched_actions, status_code, created_at"
+
+// CreateDeliveryRecord stores the delivery. Only maxStored most recent
+// deliveries are kept.
+func (d *ActionDB) CreateDeliveryRecord(record DeliveryRecord, maxStored int) error {
+	headers, err := json.Marshal(record.Headers)
+	if err != nil {
+		return err
+	}
+	matchedActions := record.MatchedActions
+	if matchedActions == nil {
+		matchedActions = []DeliveryAction{}
+	}
+	matchedActionsJSON, err := json.Marshal(matchedActions)
+	if err != nil {
+		return err
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `
+INSERT INTO deliveries (
+	record_id, project, delivery_id, event, headers, payload, payload_size,
+	verification, matched_actions, status_code
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err = tx.Exec(
+		query, record.RecordID, record.Project, record.DeliveryID, record.Event, headers,
+		record.Payload, record.PayloadSize, record.Verification, matchedActionsJSON, record.StatusCode,
+	)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if maxStored > 0 {
+		autoRemoveQuery := `
+DELETE FROM deliveries WHERE record_id IN (
+		SELECT record_id FROM deliveries ORDER BY created_at DESC, id DESC LIMIT -1 OFFSET ?
+)`
+		_, err = tx.Exec(autoRemoveQuery, maxStored)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetDeliveryRecord returns the delivery with its payload.
+func (d *ActionDB) GetDeliveryRecord(recordID string) (DeliveryRecord, error) {
+	var record deliveryRecordDTO
+	err := d.db.Get(
+		&record,
+		"SELECT "+deliveryListColumns+", payload FROM deliveries WHERE record_id=?;",
+		recordID,
+	)
+	if err != nil {
+		return DeliveryRecord{}, err
+	}
+	return record.ToModel()
+}
@@ -0,0 +1,159 @@
+package actionsdb_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+)
+
+func newDeliveryRecord(project string) actionsdb.DeliveryRecord {
+	return actionsdb.DeliveryRecord{
+		RecordID:     ulid.Make().String(),
+		Project:      project,
+		DeliveryID:   deliveryID,
+		Event:        "push",
+		Headers:      http.Header{"X-Gitea-Event": {"push"}},
+		Payload:      []byte(`{"ref":`),
+		PayloadSize:  42,
+		Verification: actionsdb.DeliveryVerificationPassed,
+		MatchedActions: []actionsdb.DeliveryAction{
+			{Index: 0, PipeID: pipeID},
+			{Index: 1, Skipped: "[skip ci]"},
+		},
+		StatusCode: http.StatusCreated,
+	}
+}
+
+func TestDeliveryRecords(t *testing.T) {
+	newDB := func(t *testing.T) *actionsdb.ActionDB {
+		t.Helper()
+		db, err := actionsdb.New(":memory:", defaultMaxActionsStored)
+		if err != nil {
+			t.Fatalf("Unable to create a db: %s", err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+		return db
+	}
+
+	t.Run("creates and retrieves a record", func(t *testing.T) {
+		db := newDB(t)
+		want := newDeliveryRecord(projectName)
+		if err := db.CreateDeliveryRecord(want, 10); err != nil {
+			t.Fatalf("Unable to create a delivery record: %s", err)
+		}
+		got, err := db.GetDeliveryRecord(want.RecordID)
+		if err != nil {
+			t.Fatalf("Unable to retrieve the delivery record: %s", err)
+		}
+		if got.CreatedAt.IsZero() {
+			t.Error("expected created at to be set")
+		}
+		want.ID = got.ID
+		want.CreatedAt = got.CreatedAt
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("Unexpected delivery record, want %+v, got %+v", want, got)
+		}
+		if !got.IsPayloadTruncated() {
+			t.Error("expected payload to be reported as truncated")
+		}
+	})
+
+	t.Run("lists records newest first without the payload", func(t *testing.T) {
+		db := newDB(t)
+		ids := make([]string, 5)
+		for i := range ids {
+			record := newDeliveryRecord(projectName)
+			ids[len(ids)-1-i] = record.RecordID
+			if err := db.CreateDeliveryRecord(record, 10); err != nil {
+				t.Fatalf("Unable to create a delivery record: %s", err)
+			}
+		}
+
+		got := make([]string, 0, len(ids))
+		var cursor string
+		for {
+			page, err := db.ListDeliveryRecords(actionsdb.ListDeliveryRecordsQuery{Limit: 2, Cursor: cursor})
+			if err != nil {
+				t.Fatalf("Unable to list delivery records: %s", err)
+			}
+			if page.TotalCount != len(ids) {
+				t.Errorf("Unexpected total count, want %d, got %d", len(ids), page.TotalCount)
+			}
+			for _, item := range page.Items {
+				if item.Payload != nil {
+					t.Errorf("expected no payload in the list, got %q", item.Payload)
+				}
+				got = append(got, item.RecordID)
+			}
+			if page.Cursor == nil {
+				break
+			}
+			cursor = *page.Cursor
+		}
+		if !reflect.DeepEqual(ids, got) {
+			t.Errorf("Unexpected records order, want %v, got %v", ids, got)
+		}
+	})
+
+	t.Run("filters records by project", func(t *testing.T) {
+		db := newDB(t)
+		for _, project := range []string{projectName, "other", projectName} {
+			if err := db.CreateDeliveryRecord(newDeliveryRecord(project), 10); err != nil {
+				t.Fatalf("Unable to create a delivery record: %s", err)
+			}
+		}
+		page, err := db.ListDeliveryRecords(actionsdb.ListDeliveryRecordsQuery{Project: "other"})
+		if err != nil {
+			t.Fatalf("Unable to list delivery records: %s", err)
+		}
+		if page.TotalCount != 1 || len(page.Items) != 1 || page.Items[0].Project != "other" {
+			t.Errorf("Unexpected filtered page: %+v", page)
+		}
+	})
+
+	t.Run("removes old records", func(t *testing.T) {
+		const maxStored = 3
+		db := newDB(t)
+		var last actionsdb.DeliveryRecord
+		for range maxStored * 2 {
+			last = newDeliveryRecord(projectName)
+			if err := db.CreateDeliveryRecord(last, maxStored); err != nil {
+				t.Fatalf("Unable to create a delivery record: %s", err)
+			}
+		}
+		count, err := db.CountDeliveryRecords(actionsdb.ListDeliveryRecordsQuery{})
+		if err != nil {
+			t.Fatalf("Unable to count delivery records: %s", err)
+		}
+		if count != maxStored {
+			t.Errorf("Unexpected amount of records after auto-removal; want %d, got %d", maxStored, count)
+		}
+		if _, err := db.GetDeliveryRecord(last.RecordID); err != nil {
+			t.Errorf("Unable to retrieve the last delivery record: %s", err)
+		}
+	})
+
+	t.Run("links replayed pipelines to their delivery", func(t *testing.T) {
+		db := newDB(t)
+		delivery := newDeliveryRecord(projectName)
+		if err := db.CreateDeliveryRecord(delivery, 10); err != nil {
+			t.Fatalf("Unable to create a delivery record: %s", err)
+		}
+		if err := db.CreateRecord(pipeID, projectName, deliveryID, delivery.RecordID, hash, true, nil, action); err != nil {
+			t.Fatalf("Unable to create a pipeline record: %s", err)
+		}
+		record, err := db.GetPipelineRecord(pipeID)
+		if err != nil {
+			t.Fatalf("Unable to retrieve the pipeline record: %s", err)
+		}
+		if record.DeliveryRecordID != delivery.RecordID {
+			t.Errorf("Unexpected delivery record id, want %q, got %q", delivery.RecordID, record.DeliveryRecordID)
+		}
+		if !record.Replay {
+			t.Error("Expected the pipeline to be marked as a replay")
+		}
+	})
+}
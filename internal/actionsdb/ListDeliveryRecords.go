@@ -0,0 +1,120 @@
+package actionsdb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/models"
+	"github.com/religiosa1/git-webhook-receiver/internal/sqlhelpers"
+)
+
+type ListDeliveryRecordsQuery struct {
+	Offset     int
+	Limit      int
+	Project    string
+	DeliveryID string
+	Event      string
+	Cursor     string
+}
+
+// ListDeliveryRecords lists the deliveries, newest first, without their
+// payloads.
+func (d *ActionDB) ListDeliveryRecords(search ListDeliveryRecordsQuery) (models.PagedDB[DeliveryRecord], error) {
+	if search.Limit <= 0 {
+		search.Limit = defaultPageSize
+	}
+	var result models.PagedDB[DeliveryRecord]
+
+	if search.Cursor != "" && search.Offset != 0 {
+		return result, ErrCursorAndOffset
+	}
+
+	cursor, err := newCursorFromStr(search.Cursor)
+	if err != nil {
+		return result, err
+	}
+
+	var qb strings.Builder
+	args := make([]any, 0)
+
+	qb.WriteString("SELECT " + deliveryListColumns + " FROM deliveries\n")
+
+	fj := createListDeliveryWhereQuery(search)
+	if cursor != nil {
+		fj.AddParamFilter("(created_at, id) < (?, ?)\n", cursor.CreatedAt, cursor.ID)
+	}
+
+	if fj.HasFilters() {
+		qb.WriteString("WHERE\n")
+		qb.WriteString(fj.String())
+		args = append(args, fj.Args()...)
+	}
+
+	qb.WriteString("ORDER BY created_at DESC, id DESC\n")
+	qb.WriteString("LIMIT ?\n")
+	args = append(args, search.Limit+1) // +1 to understand if we still have next page or not
+
+	if search.Offset != 0 {
+		qb.WriteString("OFFSET ?\n")
+		args = append(args, search.Offset)
+	}
+
+	var rows []deliveryRecordDTO
+	err = d.db.Select(&rows, qb.String(), args...)
+	if err != nil {
+		return result, err
+	}
+	result.TotalCount, err = d.CountDeliveryRecords(search)
+	if err != nil {
+		return result, fmt.Errorf("error while getting the total count of delivery records: %w", err)
+	}
+
+	if len(rows) > search.Limit {
+		lastReturnedRow := rows[search.Limit-1]
+		c := paginationCursor{
+			CreatedAt: lastReturnedRow.CreatedAt,
+			ID:        lastReturnedRow.ID,
+		}.String()
+		result.Cursor = &c
+		rows = rows[0:search.Limit]
+	}
+	result.Items = make([]DeliveryRecord, len(rows))
+	for i, item := range rows {
+		result.Items[i], err = item.ToModel()
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// CountDeliveryRecords counts the amount of delivery records matching provided
+// search query, disregarding pagination params (offset or cursor)
+func (d *ActionDB) CountDeliveryRecords(search ListDeliveryRecordsQuery) (int, error) {
+	args := make([]any, 0)
+	var qb strings.Builder
+	qb.WriteString(`SELECT count(*) FROM deliveries`)
+	fb := createListDeliveryWhereQuery(search)
+	if fb.HasFilters() {
+		qb.WriteString("\nWHERE\n")
+		qb.WriteString(fb.String())
+		args = append(args, fb.Args()...)
+	}
+	row := d.db.QueryRow(qb.String(), args...)
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func createListDeliveryWhereQuery(search ListDeliveryRecordsQuery) *sqlhelpers.Builder {
+	fb := sqlhelpers.New()
+
+	fb.AddEqFilter("delivery_id", search.DeliveryID)
+	fb.AddEqFilter("project", search.Project)
+	fb.AddEqFilter("event", search.Event)
+
+	return fb
+}
@@ -38,12 +38,7 @@ func (d *ActionDB) ListPipelineRecords(search ListPipelineRecordsQuery) (models.
 	var qb strings.Builder
 	args := make([]any, 0)
 
-	qb.WriteString(`
-SELECT
-	id, pipe_id, project, delivery_id, hash, config, error, created_at, ended_at
-FROM
-	pipelines
-`)
+	qb.WriteString("SELECT " + recordColumns + " FROM pipelines\n")
 
 	fj := createListPipelineWhereQuery(search)
 	if cursor != nil {
@@ -125,7 +120,9 @@ func createListPipelineWhereQuery(search ListPipelineRecordsQuery) *sqlhelpers.B
 	case PipeStatusError:
 		fb.AddFilter("(ended_at IS NOT NULL AND error IS NOT NULL)\n")
 	case PipeStatusPending:
-		fb.AddFilter("ended_at IS NULL\n")
+		fb.AddFilter("(ended_at IS NULL AND started_at IS NOT NULL)\n")
+	case PipeStatusQueued:
+		fb.AddFilter("(ended_at IS NULL AND started_at IS NULL)\n")
 	}
 
 	return fb
@@ -9,6 +9,7 @@ const (
 	PipeStatusOk
 	PipeStatusError
 	PipeStatusPending
+	PipeStatusQueued
 )
 
 func ParsePipelineStatus(status string) (PipeStatus, error) {
@@ -19,6 +20,8 @@ func ParsePipelineStatus(status string) (PipeStatus, error) {
 		return PipeStatusError, nil
 	case "pending":
 		return PipeStatusPending, nil
+	case "queued":
+		return PipeStatusQueued, nil
 	case "", "any":
 		return PipeStatusAny, nil
 	default:
@@ -34,6 +37,8 @@ func (s PipeStatus) String() string {
 		return "error"
 	case PipeStatusPending:
 		return "pending"
+	case PipeStatusQueued:
+		return "queued"
 	default:
 		return ""
 	}
@@ -0,0 +1,107 @@
+package actionsdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+)
+
+// ErrQueueFull is returned, when the pipeline can't be queued, as there are
+// already max allowed records in the queue.
+var ErrQueueFull = errors.New("pipeline queue is full")
+
+// QueueEntry is the data of a queued pipeline, required to run it, once it's
+// picked from the queue.
+type QueueEntry struct {
+	// index of the action in the project's config
+	ActionIdx int
+	// serialized action arguments, opaque to the DB
+	Args []byte
+	// JSON payload of the webhook
+	Payload []byte
+}
+
+// QueuedRecord is the queue entry of the pipeline record.
+type QueuedRecord struct {
+	QueueEntry
+	PipeID  string
+	Project string
+	// action config, stored with the record when it was queued
+	Config json.RawMessage
+}
+
+// EnqueueRecord creates a queued pipeline record, see [ActionDB.CreateRecord].
+// ErrQueueFull is returned, if there are already maxQueued records in the queue.
+func (d *ActionDB) EnqueueRecord(
+	pipeID, project, deliveryID, deliveryRecordID, hash string,
+	replay bool,
+	push *PushRecord,
+	conf config.Action,
+	entry QueueEntry,
+	maxQueued int,
+) error {
+	return d.createRecord(pipeID, project, deliveryID, deliveryRecordID, hash, replay, push, conf, &entry, maxQueued)
+}
+
+// ListQueuedRecords returns the records in the queue, oldest first. Payloads
+// aren't loaded, they're returned by [ActionDB.StartQueuedRecord].
+func (d *ActionDB) ListQueuedRecords() ([]QueuedRecord, error) {
+	rows, err := d.db.Query(`
+SELECT q.pipe_id, q.action_idx, q.args, p.project, p.config
+FROM pipeline_queue q JOIN pipelines p ON p.pipe_id = q.pipe_id
+ORDER BY p.created_at, p.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var records []QueuedRecord
+	for rows.Next() {
+		var record QueuedRecord
+		if err := rows.Scan(&record.PipeID, &record.ActionIdx, &record.Args, &record.Project, &record.Config); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// StartQueuedRecord removes the record from the queue, marking it as started,
+// and returns its payload. sql.ErrNoRows is returned if the record isn't in
+// the queue.
+func (d *ActionDB) StartQueuedRecord(pipeID string) (payload []byte, err error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// removing the entry first, so the transaction takes the write lock right
+	// away, and the same entry can't be started twice
+	row := tx.QueryRow(`DELETE FROM pipeline_queue WHERE pipe_id = ? RETURNING payload`, pipeID)
+	if err = row.Scan(&payload); err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`UPDATE pipelines SET started_at = ? WHERE pipe_id = ?`, time.Now().UTC().UnixMilli(), pipeID)
+	if err != nil {
+		return nil, fmt.Errorf("error while marking the pipeline record as started: %w", err)
+	}
+	return payload, nil
+}
+
+// CountQueuedRecords returns the amount of records in the queue.
+func (d *ActionDB) CountQueuedRecords() (int, error) {
+	var count int
+	if err := d.db.Get(&count, `SELECT count(*) FROM pipeline_queue`); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,155 @@
+package actionsdb_test
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+)
+
+func TestPipelineQueue(t *testing.T) {
+	newDB := func(t *testing.T) *actionsdb.ActionDB {
+		t.Helper()
+		db, err := actionsdb.New(filepath.Join(t.TempDir(), "actions.sqlite3"), defaultMaxActionsStored)
+		if err != nil {
+			t.Fatalf("Unable to create a db: %s", err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+		return db
+	}
+	enqueue := func(t *testing.T, db *actionsdb.ActionDB, pipeID string, maxQueued int) error {
+		t.Helper()
+		entry := actionsdb.QueueEntry{ActionIdx: 1, Args: []byte(`{"event":"push"}`), Payload: []byte(`{"ref":"refs/heads/main"}`)}
+		return db.EnqueueRecord(pipeID, projectName, deliveryID, "", hash, false, nil, action, entry, maxQueued)
+	}
+
+	t.Run("creates queued records", func(t *testing.T) {
+		db := newDB(t)
+		if err := enqueue(t, db, "pipe1", 10); err != nil {
+			t.Fatalf("Unable to enqueue the record: %s", err)
+		}
+		record, err := db.GetPipelineRecord("pipe1")
+		if err != nil {
+			t.Fatalf("Unable to retrieve the queued record: %s", err)
+		}
+		if got := record.Status(); got != actionsdb.PipeStatusQueued {
+			t.Errorf("status: want %q, got %q", actionsdb.PipeStatusQueued, got)
+		}
+		if record.StartedAt != nil {
+			t.Errorf("started at: want nil, got %v", record.StartedAt)
+		}
+		if n, err := db.CountQueuedRecords(); err != nil || n != 1 {
+			t.Errorf("queued records count: want 1, got %d, %v", n, err)
+		}
+	})
+
+	t.Run("rejects records above the max depth", func(t *testing.T) {
+		db := newDB(t)
+		for _, id := range []string{"pipe1", "pipe2"} {
+			if err := enqueue(t, db, id, 2); err != nil {
+				t.Fatalf("Unable to enqueue the record: %s", err)
+			}
+		}
+		if err := enqueue(t, db, "pipe3", 2); !errors.Is(err, actionsdb.ErrQueueFull) {
+			t.Fatalf("want ErrQueueFull, got %v", err)
+		}
+		if _, err := db.GetPipelineRecord("pipe3"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("rejected record shouldn't be stored, got %v", err)
+		}
+	})
+
+	t.Run("lists records in order and starts them", func(t *testing.T) {
+		db := newDB(t)
+		for _, id := range []string{"pipe1", "pipe2"} {
+			if err := enqueue(t, db, id, 10); err != nil {
+				t.Fatalf("Unable to enqueue the record: %s", err)
+			}
+		}
+		queued, err := db.ListQueuedRecords()
+		if err != nil {
+			t.Fatalf("Unable to list the queued records: %s", err)
+		}
+		if len(queued) != 2 {
+			t.Fatalf("want 2 queued records, got %d", len(queued))
+		}
+		for i, id := range []string{"pipe1", "pipe2"} {
+			if queued[i].PipeID != id || queued[i].Project != projectName || queued[i].ActionIdx != 1 {
+				t.Errorf("want %s of %s, action 1, got %+v", id, projectName, queued[i])
+			}
+			if string(queued[i].Args) != `{"event":"push"}` || len(queued[i].Config) == 0 {
+				t.Errorf("unexpected args %q or config %q", queued[i].Args, queued[i].Config)
+			}
+		}
+		// starting out of order, as the newer one may be the first to run
+		payload, err := db.StartQueuedRecord("pipe2")
+		if err != nil {
+			t.Fatalf("Unable to start the record: %s", err)
+		}
+		if string(payload) != `{"ref":"refs/heads/main"}` {
+			t.Errorf("unexpected payload %q", payload)
+		}
+		if _, err := db.StartQueuedRecord("pipe2"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("want sql.ErrNoRows on the started record, got %v", err)
+		}
+		for id, want := range map[string]actionsdb.PipeStatus{
+			"pipe1": actionsdb.PipeStatusQueued,
+			"pipe2": actionsdb.PipeStatusPending,
+		} {
+			record, err := db.GetPipelineRecord(id)
+			if err != nil {
+				t.Fatalf("Unable to retrieve the record: %s", err)
+			}
+			if got := record.Status(); got != want {
+				t.Errorf("%s status: want %q, got %q", id, want, got)
+			}
+		}
+		if queued, err := db.ListQueuedRecords(); err != nil || len(queued) != 1 || queued[0].PipeID != "pipe1" {
+			t.Errorf("want only pipe1 left in the queue, got %+v, %v", queued, err)
+		}
+	})
+
+	t.Run("filters records by the queued status", func(t *testing.T) {
+		db := newDB(t)
+		if err := db.CreateRecord("running", projectName, deliveryID, "", hash, false, nil, action); err != nil {
+			t.Fatalf("Unable to create the record: %s", err)
+		}
+		if err := enqueue(t, db, "queued", 10); err != nil {
+			t.Fatalf("Unable to enqueue the record: %s", err)
+		}
+		for status, want := range map[actionsdb.PipeStatus]string{
+			actionsdb.PipeStatusQueued:  "queued",
+			actionsdb.PipeStatusPending: "running",
+		} {
+			page, err := db.ListPipelineRecords(actionsdb.ListPipelineRecordsQuery{Status: status})
+			if err != nil {
+				t.Fatalf("Unable to list the records: %s", err)
+			}
+			if len(page.Items) != 1 || page.Items[0].PipeID != want {
+				t.Errorf("%s records: want only %q, got %+v", status, want, page.Items)
+			}
+		}
+	})
+
+	t.Run("stale records sweep keeps queued records", func(t *testing.T) {
+		db := newDB(t)
+		if err := db.CreateRecord("running", projectName, deliveryID, "", hash, false, nil, action); err != nil {
+			t.Fatalf("Unable to create the record: %s", err)
+		}
+		if err := enqueue(t, db, "queued", 10); err != nil {
+			t.Fatalf("Unable to enqueue the record: %s", err)
+		}
+		n, err := db.SweepStaleRecords()
+		if err != nil || n != 1 {
+			t.Fatalf("want 1 swept record, got %d, %v", n, err)
+		}
+		record, err := db.GetPipelineRecord("queued")
+		if err != nil {
+			t.Fatalf("Unable to retrieve the record: %s", err)
+		}
+		if got := record.Status(); got != actionsdb.PipeStatusQueued {
+			t.Errorf("status: want %q, got %q", actionsdb.PipeStatusQueued, got)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package actionsdb
+
+import (
+	"fmt"
+)
+
+// MarkDeliverySeen stores the delivery ID of the project, and reports if it
+// was already stored. Only maxStored most recent deliveries of each project
+// are kept.
+func (d *ActionDB) MarkDeliverySeen(project, deliveryID string, maxStored int) (seen bool, err error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	result, err := tx.Exec(
+		`INSERT OR IGNORE INTO seen_deliveries (project, delivery_id) VALUES (?, ?)`,
+		project, deliveryID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("error while storing the delivery id: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error while determining result of the delivery id insert: %w", err)
+	}
+	if rowsAffected == 0 {
+		return true, nil
+	}
+
+	if maxStored > 0 {
+		autoRemoveQuery := `
+DELETE FROM seen_deliveries WHERE project = ? AND rowid IN (
+	SELECT rowid FROM seen_deliveries WHERE project = ? ORDER BY received_at DESC, rowid DESC LIMIT -1 OFFSET ?
+)`
+		if _, err = tx.Exec(autoRemoveQuery, project, project, maxStored); err != nil {
+			return false, fmt.Errorf("error while removing old delivery ids: %w", err)
+		}
+	}
+	return false, nil
+}
+
+// ForgetDelivery removes the delivery ID of the project, so its next delivery
+// isn't considered a duplicate.
+func (d *ActionDB) ForgetDelivery(project, deliveryID string) error {
+	_, err := d.db.Exec(`DELETE FROM seen_deliveries WHERE project = ? AND delivery_id = ?`, project, deliveryID)
+	if err != nil {
+		return fmt.Errorf("error while removing the delivery id: %w", err)
+	}
+	return nil
+}
@@ -6,7 +6,7 @@ import (
 	"strings"
 )
 
-// paginationCursor is a db cursor used in list pipeline records
+// paginationCursor is a db cursor used in list pipeline and delivery records
 type paginationCursor struct {
 	CreatedAt int64
 	ID        int64
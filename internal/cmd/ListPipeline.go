@@ -16,7 +16,7 @@ type ListPipelinesArgs struct {
 	File       string `short:"i" help:"Actions db file (default to the file, specified in config)" type:"path"`
 	Limit      int    `short:"l" default:"20" help:"Maximum number of pipeline records to output"`
 	Skip       int    `short:"s" default:"0" help:"Skip first N entries"`
-	Status     string `short:"e" help:"filter by status" enum:"ok,error,pending,any" default:"any"`
+	Status     string `short:"e" help:"filter by status" enum:"ok,error,pending,queued,any" default:"any"`
 	Project    string `short:"p" help:"filter by project"`
 	DeliveryID string `short:"d" help:"filter by deliveryId"`
 	Format     string `short:"f" help:"output format" enum:"simple,jq,json" default:"simple"`
@@ -96,9 +96,12 @@ func formatActionRecordsSimple(pipelines []actionsdb.PipeLineRecord) error {
 		}
 
 		var result string
-		if pl.Error != nil {
+		switch pl.Status() {
+		case actionsdb.PipeStatusError:
 			result = pl.Error.Error()
-		} else {
+		case actionsdb.PipeStatusQueued:
+			result = "queued"
+		default:
 			result = "ok"
 		}
 		_, err := fmt.Fprintf(w, "%s-%s\t%s\t%s\t%s\t%s\n", createAt, endedAt, pl.PipeID, pl.DeliveryID, pl.Project, result)
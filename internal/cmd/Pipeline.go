@@ -51,7 +51,10 @@ func Pipeline(cfg config.Config, args PipelineArgs) {
 }
 
 func displayPipeDetails(w io.Writer, pipe actionsdb.PipeLineRecord) error {
-	var endedAt string
+	var startedAt, endedAt string
+	if pipe.StartedAt != nil {
+		startedAt = pipe.StartedAt.Format(time.DateTime)
+	}
 	if pipe.EndedAt != nil {
 		endedAt = pipe.EndedAt.Format(time.DateTime)
 	}
@@ -69,7 +72,9 @@ func displayPipeDetails(w io.Writer, pipe actionsdb.PipeLineRecord) error {
 		print("deliveryId", pipe.DeliveryID),
 		print("config    ", pipe.Config),
 		print("error     ", pipeErr),
+		print("status    ", pipe.Status()),
 		print("created at", pipe.CreatedAt.Format(time.DateTime)),
+		print("started at", startedAt),
 		print("ended at  ", endedAt),
 	)
 }
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/actionrunner"
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/http/webhook"
+	"github.com/religiosa1/git-webhook-receiver/internal/logger"
+)
+
+type RedeliverArgs struct {
+	DeliveryID string `arg:"" name:"deliveryId" help:"Id of the stored delivery to redeliver, as listed in /api/deliveries"`
+	File       string `short:"f" help:"Actions db file (default to the file, specified in config)" type:"path"`
+}
+
+// Redeliver replays the stored delivery against the current config. Pipelines
+// are queued in the actions DB, to be run by the running service, or on its
+// next start.
+func Redeliver(cfg config.Config, args RedeliverArgs) {
+	if args.File == "" {
+		args.File = cfg.ActionsDBFile
+	}
+	dbActions, err := actionsdb.New(args.File, cfg.MaxActionsStored)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening actions db: %s\n", err)
+		os.Exit(ExitCodeActionsDB)
+	}
+	if dbActions == nil {
+		fmt.Fprintln(os.Stderr, "Actions db is disabled in the config, there are no stored deliveries")
+		os.Exit(ExitCodeActionsDB)
+	}
+	defer func() {
+		err := dbActions.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error closing actions DB: %s\n", err)
+			os.Exit(ExitCodeActionsDB)
+		}
+	}()
+
+	logger, err := logger.SetupLogger(cfg.LogLevel, cfg.LogType, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error setting up the logger: %s\n", err)
+		os.Exit(ExitCodeLoggerDB)
+	}
+
+	redeliverer := webhook.Redeliverer{Queue: actionrunner.NewDBQueue(dbActions, cfg, logger), Config: cfg, DB: dbActions}
+	output, err := redeliverer.Redeliver(logger, args.DeliveryID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to redeliver the delivery: %s\n", err)
+		// the actions queued before the error are still run
+		if len(output) > 0 {
+			_ = displayRedeliveryOutput(os.Stdout, output)
+		}
+		os.Exit(ExitCodeRun)
+	}
+	if err := displayRedeliveryOutput(os.Stdout, output); err != nil {
+		fmt.Fprintf(os.Stderr, "error writing output: %v\n", err)
+	}
+}
+
+func displayRedeliveryOutput(w io.Writer, output []webhook.ActionOutput) error {
+	if len(output) == 0 {
+		_, err := fmt.Fprintln(w, "No actions matched the delivery")
+		return err
+	}
+	for _, action := range output {
+		var err error
+		if action.Skipped != "" {
+			_, err = fmt.Fprintf(w, "action %d skipped by %q\n", action.Index, action.Skipped)
+		} else {
+			_, err = fmt.Fprintf(w, "action %d queued pipeline %s\n", action.Index, action.PipeID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
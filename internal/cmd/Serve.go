@@ -80,19 +80,42 @@ func Serve(cfg config.Config) {
 		}
 	}
 
+	var queue actionrunner.Queue
+	if dbActions != nil {
+		queue = actionrunner.NewDBQueue(dbActions, cfg, logger)
+		n, err := dbActions.CountQueuedRecords()
+		if err != nil {
+			logger.Error("Error while counting queued pipeline records", slog.Any("error", err))
+		} else if n != 0 {
+			logger.Info("Resuming queued pipelines", slog.Int("n_records", n))
+		}
+	} else {
+		queue = actionrunner.NewMemoryQueue(cfg.MaxQueuedActions)
+	}
+
 	killCtx, cancelKillCtx := context.WithCancel(context.Background())
-	actionArgsStream := make(chan actionrunner.ActionArgs)
 	defer cancelKillCtx()
 	tmpOutputMgr := tmpoutput.NewInMemoryTmpOutput(cfg.MaxOutputBytes)
-	actionRunner := actionrunner.New(killCtx, actionArgsStream, cfg.MaxConcurrentActions, dbActions, tmpOutputMgr)
+	actionRunner := actionrunner.New(killCtx, queue, cfg.MaxConcurrentActions, dbActions, tmpOutputMgr)
 
 	//==========================================================================
 	// HTTP-Server
-	mux, err := createProjectsMux(actionArgsStream, cfg, logger)
+	var deliveries webhook.DeliveryStore
+	var deliveryRecorder webhook.DeliveryRecorder
+	if dbActions != nil {
+		deliveries = webhook.NewDBDeliveryStore(dbActions, cfg.MaxSeenDeliveries)
+		deliveryRecorder = webhook.NewDBDeliveryRecorder(dbActions, cfg.MaxDeliveriesStored)
+	} else {
+		deliveries = webhook.NewMemoryDeliveryStore(cfg.MaxSeenDeliveries)
+	}
+	mux, err := createProjectsMux(queue, deliveries, deliveryRecorder, cfg, logger)
 	if err != nil {
 		logger.Error("Error creating the server", slog.Any("error", err))
 		os.Exit(ExitReadConfig)
 	}
+	redeliverer := webhook.Redeliverer{Queue: queue, Config: cfg, DB: dbActions}
+	// forms are submitted with the browser's basic auth credentials
+	crossOriginProtection := http.NewCrossOriginProtection()
 	if !cfg.DisableUI {
 		middlewares := middleware.Chain(
 			middleware.WithLogger(logger),
@@ -122,6 +145,10 @@ func Serve(cfg config.Config) {
 			mux.Handle("GET /pipelines/{pipeId}", middlewares(admin.GetPipeline{DB: dbActions, TmpOutputMgr: tmpOutputMgr}))
 			mux.Handle("GET /pipelines/{pipeId}/output", middlewares(admin.GetPipelineOutput{DB: dbActions}))
 			mux.Handle("GET /pipelines/{pipeId}/output/stream", middlewares(admin.GetPipelineOutputStream{DB: dbActions, TmpOutputMgr: tmpOutputMgr}))
+			// redelivering runs the actions, so it's never available without auth
+			if cfg.HasBasicAuth() {
+				mux.Handle("POST /deliveries/{deliveryId}/redeliver", crossOriginProtection.Handler(middlewares(admin.RedeliverDelivery{Redeliverer: redeliverer})))
+			}
 		} else {
 			logger.Info("actions_db_file config value is an empty string. All of /pipelines pages won't be available")
 		}
@@ -147,8 +174,15 @@ func Serve(cfg config.Config) {
 			mux.Handle("GET /api/pipelines", middlewares(api.ListPipelines{DB: dbActions, PublicURL: cfg.PublicURL}))
 			mux.Handle("GET /api/pipelines/{pipeId}", middlewares(api.GetPipeline{DB: dbActions}))
 			mux.Handle("GET /api/pipelines/{pipeId}/output", middlewares(api.GetPipelineOutput{DB: dbActions, TmpOutputMgr: tmpOutputMgr}))
+			mux.Handle("GET /api/deliveries", middlewares(api.ListDeliveries{DB: dbActions, PublicURL: cfg.PublicURL}))
+			mux.Handle("GET /api/deliveries/{deliveryId}", middlewares(api.GetDelivery{DB: dbActions}))
+			if cfg.HasBasicAuth() {
+				mux.Handle("POST /api/deliveries/{deliveryId}/redeliver", crossOriginProtection.Handler(middlewares(api.RedeliverDelivery{Redeliverer: redeliverer})))
+			} else {
+				logger.Info("auth_user and auth_password aren't configured, redelivering stored deliveries from the API and web UI won't be available")
+			}
 		} else {
-			logger.Info("actions_db_file config value is an empty string. All of /api/pipelines API endpoints won't be available")
+			logger.Info("actions_db_file config value is an empty string. All of /api/pipelines and /api/deliveries API endpoints won't be available")
 		}
 		if dbLogs != nil {
 			logger.Debug("HTTP API enabled for logs")
@@ -190,7 +224,8 @@ func Serve(cfg config.Config) {
 		}
 	}
 	logger.Info("Server closed")
-	close(actionArgsStream)
+	// queued actions are left in the queue, to be picked up on the next start
+	actionRunner.Close()
 
 	waitDone := make(chan struct{})
 	go func() {
@@ -256,7 +291,13 @@ func runServer(ctx context.Context, srv *http.Server, sslConfig config.SslConfig
 	return <-errCh
 }
 
-func createProjectsMux(actionsCh chan<- actionrunner.ActionArgs, cfg config.Config, logger *slog.Logger) (*http.ServeMux, error) {
+func createProjectsMux(
+	queue actionrunner.Queue,
+	deliveries webhook.DeliveryStore,
+	deliveryRecorder webhook.DeliveryRecorder,
+	cfg config.Config,
+	logger *slog.Logger,
+) (*http.ServeMux, error) {
 	mux := http.NewServeMux()
 	basicAuth := middleware.WithBasicAuth(cfg.AuthUser, cfg.AuthPassword.RawContents(), cfg.AuthRealm)
 	for projectName, project := range cfg.Projects {
@@ -276,11 +317,13 @@ func createProjectsMux(actionsCh chan<- actionrunner.ActionArgs, cfg config.Conf
 		projectLogger := logger.With(slog.String("project", projectName))
 		path := fmt.Sprintf("/projects/%s", projectName)
 		handler := webhook.Webhook{
-			ActionsCh:   actionsCh,
+			Queue:       queue,
 			Config:      cfg,
 			ProjectName: projectName,
 			Project:     project,
 			Receiver:    receiver,
+			Deliveries:  deliveries,
+			Recorder:    deliveryRecorder,
 		}
 		mux.Handle(
 			"POST "+path,
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/actionrunner"
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+)
+
+func TestCreateProjectsMuxCapabilities(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	queue := actionrunner.NewMemoryQueue(1)
+
+	tests := []struct {
+		name    string
+		project config.Project
+		wantErr bool
+	}{
+		{
+			"authorization on a receiver without authorization support",
+			config.Project{GitProvider: "github", Repo: "user/repo", Authorization: config.SecretList{"foo"}},
+			true,
+		},
+		{
+			"secret on a receiver without signature support",
+			config.Project{GitProvider: "azure-devops", Repo: "Proj/repo", Secret: config.SecretList{"foo"}},
+			true,
+		},
+		{
+			"authorization on a receiver with authorization support",
+			config.Project{GitProvider: "azure-devops", Repo: "Proj/repo", Authorization: config.SecretList{"user:pass"}},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Config{Projects: map[string]config.Project{"proj": tt.project}}
+			_, err := createProjectsMux(queue, nil, nil, cfg, logger)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("unexpected createProjectsMux result, want error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,448 @@
+// Package condexpr implements a small expression language for the action
+// conditions. It's intentionally limited: expressions can only read the
+// variables they're given, compare values and call a few string functions,
+// so evaluating one can't have any side effects.
+//
+// Syntax, by precedence from the lowest:
+//
+//	a || b                      logical or
+//	a && b                      logical and
+//	a == b, a != b, a < b, ...  comparison
+//	!a                          logical not
+//	var.field, var['field'], var[0], f(a, b), [a, b], (a)
+//
+// Literals are single quoted (a quote is escaped by doubling it) or double
+// quoted (Go escape sequences) strings, numbers, true, false and null.
+//
+// Accessing a missing field or index yields null instead of an error, so
+// conditions on optional payload fields don't need extra checks. Values are
+// truthy, unless they're false, null, 0 or an empty string.
+//
+// Functions:
+//
+//	contains(haystack, needle)  substring, or an item of the array
+//	startsWith(str, prefix)
+//	endsWith(str, suffix)
+package condexpr
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrSyntax = errors.New("expression syntax error")
+	ErrEval   = errors.New("expression evaluation error")
+)
+
+// Expression is a compiled expression, safe for concurrent use.
+type Expression struct {
+	src  string
+	root node
+}
+
+// Compile parses the expression source. vars are the names of variables the
+// expression can refer to, any other name is a syntax error.
+func Compile(src string, vars []string) (*Expression, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens, vars: vars}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, syntaxError(t.pos, "unexpected %s", t)
+	}
+	return &Expression{src: src, root: root}, nil
+}
+
+// String returns the expression source.
+func (e *Expression) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression against the variables and returns its
+// result. Variable values are expected in the generic JSON form, i.e. nil,
+// bool, numbers, string, []any and map[string]any.
+func (e *Expression) Eval(vars map[string]any) (any, error) {
+	return e.root.eval(vars)
+}
+
+// EvalBool evaluates the expression and returns its result's truthiness.
+func (e *Expression) EvalBool(vars map[string]any) (bool, error) {
+	value, err := e.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	return isTruthy(value), nil
+}
+
+//------------------------------------------------------------------------------
+// Parser
+
+type parser struct {
+	tokens []token
+	pos    int
+	vars   []string
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOperator(op string) bool {
+	t := p.peek()
+	return t.kind == tokenOperator && t.value == op
+}
+
+func (p *parser) expect(op string) error {
+	if t := p.next(); t.kind != tokenOperator || t.value != op {
+		return syntaxError(t.pos, "expected %q, got %s", op, t)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+var comparisonOperators = []string{"==", "!=", "<", "<=", ">", ">="}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokenOperator && slices.Contains(comparisonOperators, t.value) {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return compareNode{t.value, left, right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("!") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.isOperator("."):
+			p.next()
+			t := p.next()
+			if t.kind != tokenIdent {
+				return nil, syntaxError(t.pos, "expected a field name, got %s", t)
+			}
+			n = indexNode{n, literalNode{t.value}}
+		case p.isOperator("["):
+			p.next()
+			key, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = indexNode{n, key}
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return literalNode{t.value}, nil
+	case tokenNumber:
+		value, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, syntaxError(t.pos, "malformed number %s", t)
+		}
+		return literalNode{value}, nil
+	case tokenIdent:
+		switch t.value {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		case "null":
+			return literalNode{nil}, nil
+		}
+		if p.isOperator("(") {
+			return p.parseCall(t)
+		}
+		if !slices.Contains(p.vars, t.value) {
+			return nil, syntaxError(t.pos, "unknown variable %s, available ones are: %s", t, strings.Join(p.vars, ", "))
+		}
+		return varNode{t.value}, nil
+	case tokenOperator:
+		switch t.value {
+		case "(":
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return arrayNode{items}, nil
+		}
+	}
+	return nil, syntaxError(t.pos, "unexpected %s", t)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.value]
+	if !ok {
+		return nil, syntaxError(name.pos, "unknown function %s", name)
+	}
+	p.next() // "("
+	args, err := p.parseList(")")
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != fn.arity {
+		return nil, syntaxError(name.pos, "function %s expects %d arguments, got %d", name, fn.arity, len(args))
+	}
+	return callNode{name.value, fn.call, args}, nil
+}
+
+// parseList parses comma separated expressions up to the closing operator
+func (p *parser) parseList(closing string) ([]node, error) {
+	var items []node
+	if p.isOperator(closing) {
+		p.next()
+		return items, nil
+	}
+	for {
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.isOperator(",") {
+			p.next()
+			continue
+		}
+		return items, p.expect(closing)
+	}
+}
+
+//------------------------------------------------------------------------------
+// AST
+
+type node interface {
+	eval(vars map[string]any) (any, error)
+}
+
+type literalNode struct{ value any }
+
+func (n literalNode) eval(map[string]any) (any, error) {
+	return n.value, nil
+}
+
+type varNode struct{ name string }
+
+func (n varNode) eval(vars map[string]any) (any, error) {
+	return normalize(vars[n.name]), nil
+}
+
+type indexNode struct{ object, key node }
+
+func (n indexNode) eval(vars map[string]any) (any, error) {
+	object, err := n.object.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	key, err := n.key.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch o := object.(type) {
+	case map[string]any:
+		if k, ok := key.(string); ok {
+			return normalize(o[k]), nil
+		}
+	case []any:
+		if k, ok := key.(float64); ok && k >= 0 && k < float64(len(o)) && k == float64(int(k)) {
+			return normalize(o[int(k)]), nil
+		}
+	}
+	return nil, nil
+}
+
+type arrayNode struct{ items []node }
+
+func (n arrayNode) eval(vars map[string]any) (any, error) {
+	values := make([]any, len(n.items))
+	for i, item := range n.items {
+		value, err := item.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(vars map[string]any) (any, error) {
+	value, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return !isTruthy(value), nil
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(vars map[string]any) (any, error) {
+	left, err := n.left.eval(vars)
+	if err != nil || !isTruthy(left) {
+		return false, err
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return isTruthy(right), nil
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(vars map[string]any) (any, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	if isTruthy(left) {
+		return true, nil
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return isTruthy(right), nil
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(vars map[string]any) (any, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return isEqual(left, right), nil
+	case "!=":
+		return !isEqual(left, right), nil
+	}
+	cmp, err := compareOrdered(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+type callNode struct {
+	name string
+	call func(args []any) (any, error)
+	args []node
+}
+
+func (n callNode) eval(vars map[string]any) (any, error) {
+	args := make([]any, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	result, err := n.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %w", n.name, err)
+	}
+	return result, nil
+}
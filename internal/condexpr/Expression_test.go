@@ -0,0 +1,130 @@
+package condexpr_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/condexpr"
+	"github.com/religiosa1/git-webhook-receiver/internal/jsonpointer"
+)
+
+var testVars = []string{"payload", "headers", "webhook"}
+
+func makeTestEnv(t *testing.T) map[string]any {
+	t.Helper()
+	payload, err := jsonpointer.Unmarshal([]byte(`{
+		"pusher": {"name": "octocat"},
+		"repository": {"private": false, "stargazers_count": 42},
+		"commits": [{"message": "Fix the build [deploy]"}],
+		"labels": ["bug", "deploy"]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]any{
+		"payload": payload,
+		"headers": map[string]any{"x-github-event": "push"},
+		"webhook": map[string]any{"branch": "main", "tag": "", "commitCount": 3},
+	}
+}
+
+func TestEval(t *testing.T) {
+	env := makeTestEnv(t)
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"true", true},
+		{"false", false},
+		{"null", false},
+		{"webhook.branch == 'main'", true},
+		{`webhook.branch == "main"`, true},
+		{"webhook.branch != 'main'", false},
+		{"webhook['branch'] == 'main'", true},
+		{"webhook.tag", false},
+		{"!webhook.tag", true},
+		{"payload.repository.private == false", true},
+		{"!payload.repository.private", true},
+		{"payload.repository.stargazers_count > 10", true},
+		{"payload.repository.stargazers_count <= 10", false},
+		{"payload.repository.stargazers_count == 42", true},
+		{"webhook.commitCount >= 3", true},
+		{"payload.commits[0].message == 'Fix the build [deploy]'", true},
+		{"contains(payload.commits[0].message, '[deploy]')", true},
+		{"contains(payload.labels, 'deploy')", true},
+		{"contains(payload.labels, 'feature')", false},
+		{"contains(['octocat', 'hubot'], payload.pusher.name)", true},
+		{"contains(['hubot'], payload.pusher.name)", false},
+		{"startsWith(webhook.branch, 'ma')", true},
+		{"endsWith(webhook.branch, 'ma')", false},
+		{"headers['x-github-event'] == 'push'", true},
+		{"payload.missing.field == null", true},
+		{"payload.commits[5].message == null", true},
+		{"contains(payload.missing, 'x')", false},
+		{"webhook.branch == 'main' && payload.pusher.name == 'octocat'", true},
+		{"webhook.branch == 'dev' || payload.pusher.name == 'octocat'", true},
+		{"webhook.branch == 'dev' || payload.pusher.name == 'hubot'", false},
+		{"!(webhook.branch == 'dev' || webhook.branch == 'main')", false},
+		{"'it''s' == \"it's\"", true},
+		{"-1 < 0", true},
+		{"'1' == 1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := condexpr.Compile(tt.expr, testVars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := expr.EvalBool(env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("%s = %t, want %t", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"webhook.branch ==",
+		"webhook.branch = 'main'",
+		"(webhook.branch == 'main'",
+		"webhook.",
+		"paylaod.pusher.name == 'octocat'",
+		"unknown(webhook.branch)",
+		"contains(webhook.branch)",
+		"'unterminated",
+		"webhook.branch == 'main' 'extra'",
+		"webhook.branch ~ 'main'",
+	} {
+		t.Run(src, func(t *testing.T) {
+			_, err := condexpr.Compile(src, testVars)
+			if !errors.Is(err, condexpr.ErrSyntax) {
+				t.Errorf("expected a syntax error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	env := makeTestEnv(t)
+	for _, src := range []string{
+		"payload.pusher.name > 1",
+		"payload.repository < 1",
+		"contains(payload.repository.stargazers_count, 1)",
+		"startsWith(payload.labels, 'bug')",
+	} {
+		t.Run(src, func(t *testing.T) {
+			expr, err := condexpr.Compile(src, testVars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := expr.EvalBool(env); !errors.Is(err, condexpr.ErrEval) {
+				t.Errorf("expected an evaluation error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package condexpr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.value)
+}
+
+// operators are sorted so longer ones are tried first
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ",", "."}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	pos := 0
+	for pos < len(src) {
+		c := src[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case isIdentStart(c):
+			start := pos
+			for pos < len(src) && isIdentPart(src[pos]) {
+				pos++
+			}
+			tokens = append(tokens, token{tokenIdent, src[start:pos], start})
+		case isDigit(c) || (c == '-' && pos+1 < len(src) && isDigit(src[pos+1])):
+			start := pos
+			pos++
+			for pos < len(src) && (isDigit(src[pos]) || src[pos] == '.') {
+				pos++
+			}
+			tokens = append(tokens, token{tokenNumber, src[start:pos], start})
+		case c == '\'':
+			value, end, err := scanSingleQuoted(src, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokenString, value, pos})
+			pos = end
+		case c == '"':
+			value, end, err := scanDoubleQuoted(src, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokenString, value, pos})
+			pos = end
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(src[pos:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, syntaxError(pos, "unexpected character %q", c)
+			}
+			tokens = append(tokens, token{tokenOperator, op, pos})
+			pos += len(op)
+		}
+	}
+	return append(tokens, token{tokenEOF, "", pos}), nil
+}
+
+// scanSingleQuoted scans a 'single quoted' string, where a quote is escaped
+// by doubling it.
+func scanSingleQuoted(src string, start int) (string, int, error) {
+	var sb strings.Builder
+	pos := start + 1
+	for pos < len(src) {
+		if src[pos] == '\'' {
+			if pos+1 < len(src) && src[pos+1] == '\'' {
+				sb.WriteByte('\'')
+				pos += 2
+				continue
+			}
+			return sb.String(), pos + 1, nil
+		}
+		sb.WriteByte(src[pos])
+		pos++
+	}
+	return "", 0, syntaxError(start, "unterminated string")
+}
+
+// scanDoubleQuoted scans a "double quoted" string with Go escape sequences.
+func scanDoubleQuoted(src string, start int) (string, int, error) {
+	pos := start + 1
+	for pos < len(src) {
+		switch src[pos] {
+		case '\\':
+			pos += 2
+		case '"':
+			value, err := strconv.Unquote(src[start : pos+1])
+			if err != nil {
+				return "", 0, syntaxError(start, "malformed string: %v", err)
+			}
+			return value, pos + 1, nil
+		default:
+			pos++
+		}
+	}
+	return "", 0, syntaxError(start, "unterminated string")
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func syntaxError(pos int, format string, args ...any) error {
+	return fmt.Errorf("%w at position %d: %s", ErrSyntax, pos+1, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,147 @@
+package condexpr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// normalize converts numeric values to float64, so they can be compared
+// regardless of how the variables were built.
+func normalize(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		return f
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	default:
+		return value
+	}
+}
+
+func isTruthy(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	default:
+		return true
+	}
+}
+
+// isEqual compares scalar values of the same type; values of different types,
+// arrays and objects are never equal.
+func isEqual(left, right any) bool {
+	switch l := left.(type) {
+	case nil:
+		return right == nil
+	case bool:
+		r, ok := right.(bool)
+		return ok && l == r
+	case float64:
+		r, ok := right.(float64)
+		return ok && l == r
+	case string:
+		r, ok := right.(string)
+		return ok && l == r
+	default:
+		return false
+	}
+}
+
+// compareOrdered compares two numbers or two strings.
+func compareOrdered(left, right any) (int, error) {
+	switch l := left.(type) {
+	case float64:
+		if r, ok := right.(float64); ok {
+			switch {
+			case l < r:
+				return -1, nil
+			case l > r:
+				return 1, nil
+			default:
+				return 0, nil
+			}
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return strings.Compare(l, r), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: can't compare %s and %s", ErrEval, typeName(left), typeName(right))
+}
+
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+type function struct {
+	arity int
+	call  func(args []any) (any, error)
+}
+
+var functions = map[string]function{
+	"contains": {2, func(args []any) (any, error) {
+		switch haystack := args[0].(type) {
+		case nil:
+			return false, nil
+		case string:
+			needle, ok := args[1].(string)
+			return ok && strings.Contains(haystack, needle), nil
+		case []any:
+			for _, item := range haystack {
+				if isEqual(normalize(item), args[1]) {
+					return true, nil
+				}
+			}
+			return false, nil
+		default:
+			return nil, fmt.Errorf("%w: expected a string or an array, got %s", ErrEval, typeName(haystack))
+		}
+	}},
+	"startsWith": {2, stringFunction(strings.HasPrefix)},
+	"endsWith":   {2, stringFunction(strings.HasSuffix)},
+}
+
+// stringFunction wraps a string predicate; null arguments yield false.
+func stringFunction(fn func(s, arg string) bool) func(args []any) (any, error) {
+	return func(args []any) (any, error) {
+		if args[0] == nil || args[1] == nil {
+			return false, nil
+		}
+		s, ok1 := args[0].(string)
+		arg, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%w: expected strings, got %s and %s", ErrEval, typeName(args[0]), typeName(args[1]))
+		}
+		return fn(s, arg), nil
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Possible values of the concurrency `policy`: wait for the running pipelines
+// of the group to finish, cancel them, or drop the new pipeline.
+const (
+	ConcurrencyPolicyQueue            = "queue"
+	ConcurrencyPolicyCancelInProgress = "cancel-in-progress"
+	ConcurrencyPolicySkip             = "skip"
+)
+
+// Variables available in the concurrency group template, e.g. "deploy-${branch}"
+const (
+	ConcurrencyVarProject = "project"
+	ConcurrencyVarBranch  = "branch"
+	ConcurrencyVarTag     = "tag"
+	ConcurrencyVarEvent   = "event"
+)
+
+var concurrencyVars = []string{ConcurrencyVarProject, ConcurrencyVarBranch, ConcurrencyVarTag, ConcurrencyVarEvent}
+
+// Concurrency is the `concurrency` setting of a project or an action: only one
+// pipeline of the group runs at a time, Policy decides what happens with a new
+// pipeline of a busy group.
+//
+// In the config it can be either a group string (with the queue policy), or a
+// mapping with `group` and `policy` fields. Group names are shared between the
+// projects, so a group without `${project}` serializes the pipelines of all of
+// the projects using it.
+type Concurrency struct {
+	Group  string `yaml:"group" json:"group"`
+	Policy string `yaml:"policy" json:"policy,omitempty"` // "queue" (default) | "cancel-in-progress" | "skip"
+}
+
+// ConcurrencyVars are the values of the group template variables.
+type ConcurrencyVars struct {
+	Project string
+	Branch  string
+	Tag     string
+	Event   string
+}
+
+var _ yaml.Unmarshaler = (*Concurrency)(nil)
+
+// UnmarshalYAML implements [yaml.Unmarshaler], accepting both a scalar group
+// and a mapping.
+func (c *Concurrency) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*c = Concurrency{}
+		return value.Decode(&c.Group)
+	case yaml.MappingNode:
+		// alias type, so Decode doesn't recurse into this method
+		type concurrency Concurrency
+		var decoded concurrency
+		if err := value.Decode(&decoded); err != nil {
+			return err
+		}
+		*c = Concurrency(decoded)
+		return nil
+	default:
+		return fmt.Errorf("line %d: expected a group string or a mapping with 'group'", value.Line)
+	}
+}
+
+func (c Concurrency) IsZero() bool {
+	return c == Concurrency{}
+}
+
+// Validate checks the policy and the variables used in the group template.
+func (c Concurrency) Validate() error {
+	if c.IsZero() {
+		return nil
+	}
+	if c.Group == "" {
+		return fmt.Errorf("invalid 'concurrency': 'group' is required")
+	}
+	switch c.Policy {
+	case "", ConcurrencyPolicyQueue, ConcurrencyPolicyCancelInProgress, ConcurrencyPolicySkip:
+	default:
+		return fmt.Errorf(
+			"invalid 'concurrency': unknown 'policy' %q, possible values are '%s', '%s' and '%s'",
+			c.Policy, ConcurrencyPolicyQueue, ConcurrencyPolicyCancelInProgress, ConcurrencyPolicySkip,
+		)
+	}
+	var unknown []string
+	os.Expand(c.Group, func(name string) string {
+		if !slices.Contains(concurrencyVars, name) {
+			unknown = append(unknown, name)
+		}
+		return ""
+	})
+	if len(unknown) > 0 {
+		return fmt.Errorf("invalid 'concurrency' group %q: unknown variables %q, possible variables are %q", c.Group, unknown, concurrencyVars)
+	}
+	return nil
+}
+
+// GroupKey expands the `${name}` variables of the group template.
+func (c Concurrency) GroupKey(vars ConcurrencyVars) string {
+	return os.Expand(c.Group, func(name string) string {
+		switch name {
+		case ConcurrencyVarProject:
+			return vars.Project
+		case ConcurrencyVarBranch:
+			return vars.Branch
+		case ConcurrencyVarTag:
+			return vars.Tag
+		case ConcurrencyVarEvent:
+			return vars.Event
+		default:
+			return ""
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/jsonpointer"
+)
+
+const CustomGitProvider = "custom"
+
+// CustomProvider describes a git provider in the config, for the forges that
+// don't have a dedicated receiver (`git_provider: custom`).
+//
+// Header values are read from the request headers, while ref, hash and repo
+// are extracted from the JSON payload with RFC 6901 JSON pointers.
+type CustomProvider struct {
+	// Required, otherwise the actions can't match the event
+	EventHeader    string `yaml:"event_header" json:"eventHeader,omitempty"`
+	DeliveryHeader string `yaml:"delivery_header" json:"deliveryHeader,omitempty"`
+	// Header compared verbatim against project's `authorization`. Empty
+	// string means the provider can't authorize requests.
+	AuthorizationHeader string `yaml:"authorization_header" json:"authorizationHeader,omitempty"`
+	// Header holding the payload HMAC signature. Empty string means the
+	// provider doesn't sign the payload.
+	SignatureHeader string `yaml:"signature_header" json:"signatureHeader,omitempty"`
+	// "sha1" | "sha256" (default) | "sha512"
+	SignatureAlgorithm string `yaml:"signature_algorithm" json:"signatureAlgorithm,omitempty"`
+	// "hex" (default) | "base64"
+	SignatureEncoding string `yaml:"signature_encoding" json:"signatureEncoding,omitempty"`
+	// Optional prefix of the signature header value, e.g. "sha256="
+	SignaturePrefix string `yaml:"signature_prefix" json:"signaturePrefix,omitempty"`
+	RefPath         string `yaml:"ref_path" json:"refPath,omitempty"`
+	HashPath        string `yaml:"hash_path" json:"hashPath,omitempty"`
+	RepoPath        string `yaml:"repo_path" json:"repoPath,omitempty"`
+}
+
+func (p CustomProvider) IsZero() bool {
+	return p == CustomProvider{}
+}
+
+const (
+	defaultSignatureAlgorithm = "sha256"
+	defaultSignatureEncoding  = "hex"
+)
+
+// validateAndSetDefaultsCustomProvider checks the custom provider description
+// and fills in the default signature algorithm and encoding.
+func validateAndSetDefaultsCustomProvider(p CustomProvider) (CustomProvider, error) {
+	if p.EventHeader == "" {
+		return p, fmt.Errorf("'event_header' is required")
+	}
+	if p.RepoPath == "" {
+		return p, fmt.Errorf("'repo_path' is required")
+	}
+	paths := []struct {
+		name  string
+		value string
+	}{
+		{"ref_path", p.RefPath},
+		{"hash_path", p.HashPath},
+		{"repo_path", p.RepoPath},
+	}
+	for _, path := range paths {
+		if path.value == "" {
+			continue
+		}
+		if _, err := jsonpointer.Parse(path.value); err != nil {
+			return p, fmt.Errorf("'%s': %w", path.name, err)
+		}
+	}
+
+	if p.SignatureAlgorithm == "" {
+		p.SignatureAlgorithm = defaultSignatureAlgorithm
+	}
+	switch p.SignatureAlgorithm {
+	case "sha1", "sha256", "sha512":
+	default:
+		return p, fmt.Errorf("unknown 'signature_algorithm' %q, possible values are 'sha1', 'sha256' and 'sha512'", p.SignatureAlgorithm)
+	}
+
+	if p.SignatureEncoding == "" {
+		p.SignatureEncoding = defaultSignatureEncoding
+	}
+	switch p.SignatureEncoding {
+	case "hex", "base64":
+	default:
+		return p, fmt.Errorf("unknown 'signature_encoding' %q, possible values are 'hex' and 'base64'", p.SignatureEncoding)
+	}
+	return p, nil
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/religiosa1/git-webhook-receiver/internal/pathglob"
+	"gopkg.in/yaml.v3"
+)
+
+// PatternList is a list of patterns for the action's `branch` and `on`
+// matching. In the config it can be either a single string, or a list of
+// strings.
+//
+// Each pattern is either a glob, as in [pathglob.Match], or a regular
+// expression if it's prefixed with "regex:". Unlike globs, regular
+// expressions aren't anchored, so use ^ and $ to match a whole value. A lone
+// "*" matches anything, including an empty value. Pattern prefixed with "!"
+// excludes the values it matches.
+//
+// A value matches the list, if it matches any of the including patterns and
+// none of the excluding ones. If there are only excluding patterns, any
+// non-empty value not excluded matches, so e.g. "!main" doesn't match tag
+// pushes and other events without a branch.
+type PatternList []string
+
+var (
+	_ json.Marshaler   = PatternList(nil)
+	_ json.Unmarshaler = (*PatternList)(nil)
+	_ yaml.Unmarshaler = (*PatternList)(nil)
+	_ cleanenv.Setter  = (*PatternList)(nil)
+)
+
+const (
+	patternNegationPrefix = "!"
+	patternRegexPrefix    = "regex:"
+	patternAny            = "*"
+)
+
+// CompiledPatternList is a [PatternList] with its patterns compiled, so the
+// regular expressions aren't compiled again on every match.
+type CompiledPatternList []compiledPattern
+
+// Validate checks the syntax of every pattern in the list.
+func (l PatternList) Validate() error {
+	_, err := l.Compile()
+	return err
+}
+
+// Compile compiles every pattern in the list.
+func (l PatternList) Compile() (CompiledPatternList, error) {
+	compiled := make(CompiledPatternList, 0, len(l))
+	for _, pattern := range l {
+		p, err := compilePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled, nil
+}
+
+// Match reports whether any of the values matches the list. Patterns are
+// compiled on each call, use [PatternList.Compile] to match repeatedly.
+//
+// Patterns are expected to be validated, invalid ones never match.
+func (l PatternList) Match(values ...string) bool {
+	return l.compileValid().Match(values...)
+}
+
+// compileValid compiles the list, skipping invalid patterns.
+func (l PatternList) compileValid() CompiledPatternList {
+	compiled := make(CompiledPatternList, 0, len(l))
+	for _, pattern := range l {
+		if p, err := compilePattern(pattern); err == nil {
+			compiled = append(compiled, p)
+		}
+	}
+	return compiled
+}
+
+// Match reports whether any of the values matches the list. Multiple values
+// are used when something can be referred to in different ways, e.g. an event
+// as "pull_request" and "pull_request.opened".
+func (l CompiledPatternList) Match(values ...string) bool {
+	included := false
+	hasInclusions := false
+	for _, p := range l {
+		if !p.negated {
+			hasInclusions = true
+		}
+		if !p.matchAny(values) {
+			continue
+		}
+		if p.negated {
+			return false
+		}
+		included = true
+	}
+	return included || (!hasInclusions && len(l) > 0 && slices.ContainsFunc(values, isNonEmpty))
+}
+
+// MatchExplicitly reports whether any of the values is matched by an including
+// pattern other than the lone "*". Excluding patterns aren't checked, so it's
+// meant to be used along with [CompiledPatternList.Match].
+func (l CompiledPatternList) MatchExplicitly(values ...string) bool {
+	return slices.ContainsFunc(l, func(p compiledPattern) bool {
+		return !p.negated && p.glob != patternAny && p.matchAny(values)
+	})
+}
+
+func isNonEmpty(value string) bool {
+	return value != ""
+}
+
+// String implements [fmt.Stringer].
+func (l PatternList) String() string {
+	return strings.Join(l, ", ")
+}
+
+// MarshalJSON implements [json.Marshaler]. A single pattern is marshaled as
+// a plain string, the same as before lists were supported.
+func (l PatternList) MarshalJSON() ([]byte, error) {
+	if len(l) == 1 {
+		return json.Marshal(l[0])
+	}
+	return json.Marshal([]string(l))
+}
+
+// UnmarshalJSON implements [json.Unmarshaler], accepting both a string and
+// an array of strings.
+func (l *PatternList) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*l = newPatternList([]string{str})
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*l = newPatternList(values)
+	return nil
+}
+
+// UnmarshalYAML implements [yaml.Unmarshaler], accepting both a scalar and a
+// sequence of scalars.
+func (l *PatternList) UnmarshalYAML(value *yaml.Node) error {
+	var values []string
+	switch value.Kind {
+	case yaml.ScalarNode:
+		var str string
+		if err := value.Decode(&str); err != nil {
+			return err
+		}
+		values = []string{str}
+	case yaml.SequenceNode:
+		if err := value.Decode(&values); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("line %d: expected a string or a list of strings", value.Line)
+	}
+	*l = newPatternList(values)
+	return nil
+}
+
+// SetValue implements [cleanenv.Setter]. Unlike [SecretList], the value isn't
+// split on commas, as those can be a part of a regular expression.
+func (l *PatternList) SetValue(v string) error {
+	*l = newPatternList([]string{v})
+	return nil
+}
+
+// newPatternList creates a list out of non-empty values.
+func newPatternList(values []string) PatternList {
+	list := make(PatternList, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+type compiledPattern struct {
+	negated bool
+	glob    string
+	re      *regexp.Regexp
+}
+
+func compilePattern(pattern string) (compiledPattern, error) {
+	var p compiledPattern
+	pattern, p.negated = strings.CutPrefix(pattern, patternNegationPrefix)
+	if expr, ok := strings.CutPrefix(pattern, patternRegexPrefix); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return p, fmt.Errorf("invalid regex pattern %q: %w", expr, err)
+		}
+		p.re = re
+		return p, nil
+	}
+	if err := pathglob.Validate(pattern); err != nil {
+		return p, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+	}
+	p.glob = pattern
+	return p, nil
+}
+
+func (p compiledPattern) matchAny(values []string) bool {
+	for _, value := range values {
+		if p.match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p compiledPattern) match(value string) bool {
+	if p.re != nil {
+		return p.re.MatchString(value)
+	}
+	if p.glob == patternAny {
+		return true
+	}
+	matched, _ := pathglob.Match(p.glob, value)
+	return matched
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/jsonpointer"
+	"gopkg.in/yaml.v3"
+)
+
+// PayloadEnv is the `env_from_payload` map of the environment variables, taken
+// from the webhook payload, keyed by the variable name.
+type PayloadEnv map[string]PayloadEnvVar
+
+// PayloadEnvVar is an environment variable resolved against the JSON payload
+// with an RFC 6901 JSON pointer, e.g. "/head_commit/author/email". In the
+// config it can be either a pointer string, or a mapping with the pointer in
+// `path` and optional `default` and `required` fields.
+//
+// If the pointed value is missing, the variable is set to its default, unless
+// it's required, in which case the pipeline fails.
+type PayloadEnvVar struct {
+	Path     string `yaml:"path" json:"path"`
+	Default  string `yaml:"default" json:"default,omitempty"`
+	Required bool   `yaml:"required" json:"required,omitempty"`
+}
+
+var _ yaml.Unmarshaler = (*PayloadEnvVar)(nil)
+
+// UnmarshalYAML implements [yaml.Unmarshaler], accepting both a scalar
+// pointer and a mapping.
+func (v *PayloadEnvVar) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		*v = PayloadEnvVar{}
+		return value.Decode(&v.Path)
+	case yaml.MappingNode:
+		// alias type, so Decode doesn't recurse into this method
+		type payloadEnvVar PayloadEnvVar
+		var decoded payloadEnvVar
+		if err := value.Decode(&decoded); err != nil {
+			return err
+		}
+		*v = PayloadEnvVar(decoded)
+		return nil
+	default:
+		return fmt.Errorf("line %d: expected a json pointer string or a mapping with 'path'", value.Line)
+	}
+}
+
+// Validate checks the variable names and the pointers.
+func (e PayloadEnv) Validate() error {
+	for key, v := range e {
+		if err := isValidEnvKey(key); err != nil {
+			return fmt.Errorf("invalid 'env_from_payload' entry %q: %w", key, err)
+		}
+		if v.Path == "" {
+			return fmt.Errorf("invalid 'env_from_payload' entry %q: 'path' is required", key)
+		}
+		if _, err := jsonpointer.Parse(v.Path); err != nil {
+			return fmt.Errorf("invalid 'env_from_payload' entry %q: %w", key, err)
+		}
+		if v.Required && v.Default != "" {
+			return fmt.Errorf("invalid 'env_from_payload' entry %q: required variable can't have a default", key)
+		}
+	}
+	return nil
+}
+
+// mergePayloadEnv layers the action's entries over the project's ones.
+func mergePayloadEnv(project, action PayloadEnv) PayloadEnv {
+	if len(project) == 0 {
+		return action
+	}
+	merged := maps.Clone(project)
+	maps.Copy(merged, action)
+	return merged
+}
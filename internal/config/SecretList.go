@@ -0,0 +1,107 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"gopkg.in/yaml.v3"
+)
+
+// SecretList is a list of accepted [Secret] values, so a project can accept
+// both the old and the new secret while it's being rotated. In the config it
+// can be either a single string, or a list of strings; in env variables the
+// values are newline separated, as a secret can contain commas.
+//
+// Like [Secret] and [EnvList], it masks itself in every rendered form.
+type SecretList []Secret
+
+var (
+	_ encoding.TextMarshaler = SecretList(nil)
+	_ json.Marshaler         = SecretList(nil)
+	_ yaml.Unmarshaler       = (*SecretList)(nil)
+	_ cleanenv.Setter        = (*SecretList)(nil)
+)
+
+// RawContents exposes unmasked contents of the secrets.
+func (s SecretList) RawContents() []string {
+	contents := make([]string, len(s))
+	for i, secret := range s {
+		contents[i] = secret.RawContents()
+	}
+	return contents
+}
+
+func (s SecretList) IsZero() bool {
+	return len(s) == 0
+}
+
+// String implements [fmt.Stringer].
+func (s SecretList) String() string {
+	if len(s) == 0 {
+		return ""
+	}
+	return maskValue
+}
+
+// MarshalJSON implements [json.Marshaler]. A non-empty list collapses to a
+// single masked entry so the count doesn't leak.
+func (s SecretList) MarshalJSON() ([]byte, error) {
+	if len(s) == 0 {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string{maskValue})
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+func (s SecretList) MarshalText() ([]byte, error) {
+	if len(s) == 0 {
+		return []byte(""), nil
+	}
+	return []byte(maskValue), nil
+}
+
+// UnmarshalYAML implements [yaml.Unmarshaler], accepting both a scalar and a
+// sequence of scalars.
+func (s *SecretList) UnmarshalYAML(value *yaml.Node) error {
+	var values []string
+	switch value.Kind {
+	case yaml.ScalarNode:
+		var str string
+		if err := value.Decode(&str); err != nil {
+			return err
+		}
+		values = []string{str}
+	case yaml.SequenceNode:
+		if err := value.Decode(&values); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("line %d: expected a string or a list of strings", value.Line)
+	}
+	*s = newSecretList(values)
+	return nil
+}
+
+// SetValue implements [cleanenv.Setter].
+func (s *SecretList) SetValue(v string) error {
+	values := strings.Split(v, "\n")
+	for i, value := range values {
+		values[i] = strings.TrimSuffix(value, "\r")
+	}
+	*s = newSecretList(values)
+	return nil
+}
+
+// newSecretList creates a list out of non-empty values.
+func newSecretList(values []string) SecretList {
+	list := make(SecretList, 0, len(values))
+	for _, value := range values {
+		if value != "" {
+			list = append(list, Secret(value))
+		}
+	}
+	return list
+}
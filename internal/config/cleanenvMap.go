@@ -3,12 +3,15 @@ package config
 // As cleanenv doesn't really like our nested map[string]Project, we're applying
 // Missing parts of the functionality through this helper functions.
 // Those helpers using reflect to get struct-tag data and currently only support
-// string data in config, as it's the only type of data we need.
+// string data and types implementing [cleanenv.Setter] in config, as it's the
+// only type of data we need.
 
 import (
 	"fmt"
 	"os"
 	"reflect"
+
+	"github.com/ilyakaznacheev/cleanenv"
 )
 
 // env values are not applied to the nested map/slice, so we're applying them
@@ -41,6 +44,10 @@ func applyEnvToProjectAndActions(cfg *Config) {
 }
 
 func setEnvValue(fieldValue reflect.Value, str string) {
+	if setter, ok := fieldValue.Addr().Interface().(cleanenv.Setter); ok {
+		_ = setter.SetValue(str)
+		return
+	}
 	switch fieldValue.Kind() {
 	case reflect.String:
 		fieldValue.SetString(str)
@@ -77,14 +84,18 @@ func setDefaultAndCheckRequired[T Project | Action](item *T) error {
 	typesValue := reflect.ValueOf(item).Elem()
 	for i := 0; i < typesType.NumField(); i++ {
 		field := typesType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
 		fieldValue := typesValue.Field(i)
 		isRequired := field.Tag.Get("env-required") == "true"
 
-		// Only string type
-		if fieldValue.Type().Kind() == reflect.String && fieldValue.String() == "" {
+		// Only string type and setters
+		_, isSetter := fieldValue.Addr().Interface().(cleanenv.Setter)
+		if (isSetter || fieldValue.Type().Kind() == reflect.String) && fieldValue.IsZero() {
 			defaultValue := field.Tag.Get("env-default")
 			if defaultValue != "" {
-				fieldValue.SetString(defaultValue)
+				setEnvValue(fieldValue, defaultValue)
 			} else if isRequired {
 				return fmt.Errorf("required field %q is missing", field.Name)
 			}
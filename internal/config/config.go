@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"os/user"
+	"path"
 	"runtime"
 	"slices"
 	"strings"
@@ -11,11 +12,18 @@ import (
 	"unicode"
 
 	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/religiosa1/git-webhook-receiver/internal/condexpr"
+	"github.com/religiosa1/git-webhook-receiver/internal/pathglob"
 )
 
 const (
-	DefaultMaxActionsStored = 1_000
-	DefaultMaxOutputBytes   = 1_048_576 // 1 MiB
+	DefaultMaxActionsStored  = 1_000
+	DefaultMaxOutputBytes    = 1_048_576 // 1 MiB
+	DefaultMaxSeenDeliveries = 1_000
+	DefaultMaxQueuedActions  = 100
+	// Deliveries stored for the inspection
+	DefaultMaxDeliveriesStored     = 1_000
+	DefaultMaxDeliveryPayloadBytes = 65_536 // 64 KiB
 )
 
 const (
@@ -38,6 +46,11 @@ type Config struct {
 	MaxActionsStored        int                `yaml:"max_actions_stored" env:"MAX_ACTIONS_STORED" env-default:"1000"` // the same as DefaultMaxActionsStored
 	MaxOutputBytes          int                `yaml:"max_output_bytes" env:"MAX_OUTPUT_BYTES" env-default:"1048576"`  // the same as DefaultMaxOutputBytes
 	MaxConcurrentActions    int                `yaml:"max_concurrent_actions" env:"MAX_CONCURRENT_ACTIONS" env-default:"8"`
+	MaxQueuedActions        int                `yaml:"max_queued_actions" env:"MAX_QUEUED_ACTIONS" env-default:"100"`    // the same as DefaultMaxQueuedActions
+	MaxSeenDeliveries       int                `yaml:"max_seen_deliveries" env:"MAX_SEEN_DELIVERIES" env-default:"1000"` // per project, the same as DefaultMaxSeenDeliveries
+	RedeliveryToken         Secret             `yaml:"redelivery_token" env:"REDELIVERY_TOKEN"`
+	MaxDeliveriesStored     int                `yaml:"max_deliveries_stored" env:"MAX_DELIVERIES_STORED" env-default:"1000"`            // the same as DefaultMaxDeliveriesStored
+	MaxDeliveryPayloadBytes int                `yaml:"max_delivery_payload_bytes" env:"MAX_DELIVERY_PAYLOAD_BYTES" env-default:"65536"` // the same as DefaultMaxDeliveryPayloadBytes
 	ActionsTimeout          time.Duration      `yaml:"actions_timeout" env:"ACTIONS_TIMEOUT" env-default:"10m"`
 	ActionsGracefulShutdown time.Duration      `yaml:"actions_graceful_shutdown" env:"ACTIONS_GRACEFUL_SHUTDOWN" env-default:"15s"`
 	Ssl                     SslConfig          `yaml:"ssl" env-prefix:"SSL__"`
@@ -46,6 +59,12 @@ type Config struct {
 	Projects                map[string]Project `yaml:"projects" env-required:"true"`
 }
 
+// HasBasicAuth reports whether the inspection API and the web UI are protected
+// with the basic auth.
+func (cfg Config) HasBasicAuth() bool {
+	return cfg.AuthUser != "" && cfg.AuthPassword.RawContents() != ""
+}
+
 type SslConfig struct {
 	CertFilePath string `yaml:"cert_file_path" env:"CERT_FILE_PATH"`
 	KeyFilePath  string `yaml:"key_file_path" env:"KEY_FILE_PATH"`
@@ -55,28 +74,116 @@ type SslConfig struct {
 // tag can be set through the env variables. See [applyEnvToProjectAndActions]
 
 type Project struct {
-	GitProvider   string   `yaml:"git_provider" env-default:"github"`
-	Repo          string   `yaml:"repo" env-required:"true"`
-	Authorization Secret   `yaml:"authorization" env:"AUTH" json:"authorization,omitzero"`
-	Secret        Secret   `yaml:"secret" env:"SECRET" json:"secret,omitzero"`
-	Environment   EnvList  `yaml:"environment" json:"environment,omitempty"`
-	User          string   `yaml:"user" json:"user,omitempty"`
-	Actions       []Action `yaml:"actions" env-required:"true"`
+	GitProvider        string         `yaml:"git_provider" env-default:"github"`
+	CustomProvider     CustomProvider `yaml:"custom_provider" json:"customProvider,omitzero"`
+	Repo               string         `yaml:"repo" env-required:"true"`
+	Authorization      SecretList     `yaml:"authorization" env:"AUTH" json:"authorization,omitzero"`
+	Secret             SecretList     `yaml:"secret" env:"SECRET" json:"secret,omitzero"`
+	Environment        EnvList        `yaml:"environment" json:"environment,omitempty"`
+	EnvFromPayload     PayloadEnv     `yaml:"env_from_payload" json:"envFromPayload,omitempty"` // merged into the actions' ones
+	User               string         `yaml:"user" json:"user,omitempty"`
+	AllowSha1Signature bool           `yaml:"allow_sha1_signature" json:"allowSha1Signature,omitempty"` // github only, for older GitHub Enterprise instances
+	SkipMarkers        []string       `yaml:"skip_markers" json:"skipMarkers,omitempty"`                // nil means DefaultSkipMarkers
+	SkipMarkersIn      string         `yaml:"skip_markers_in" json:"skipMarkersIn,omitempty"`           // "head" (default) | "all"
+	Concurrency        Concurrency    `yaml:"concurrency" json:"concurrency,omitzero"`                  // default of the actions' one
+	Actions            []Action       `yaml:"actions" env-required:"true"`
 }
 
 type Action struct {
-	On               string        `yaml:"on" env-default:"push" json:"on,omitempty"`
-	Branch           string        `yaml:"branch" env-default:"master" json:"branch,omitempty"`
+	On               PatternList   `yaml:"on" env-default:"push" json:"on,omitempty"`
+	Branch           PatternList   `yaml:"branch" env-default:"master" json:"branch,omitempty"`
+	Tag              string        `yaml:"tag" json:"tag,omitempty"`                  // glob pattern, as in [path.Match]; tag actions aren't filtered by branch
+	ReleaseType      string        `yaml:"release_type" json:"releaseType,omitempty"` // "stable" | "prerelease" | "draft", only matches release events if set
+	Paths            []string      `yaml:"paths" json:"paths,omitempty"`              // glob patterns, as in [pathglob.Match], of the changed files to match
+	PathsIgnore      []string      `yaml:"paths_ignore" json:"pathsIgnore,omitempty"` // glob patterns of the changed files to disregard
+	If               string        `yaml:"if" json:"if,omitempty"`                    // condition expression, see [condexpr]
+	SkipMarkers      []string      `yaml:"skip_markers" json:"skipMarkers,omitempty"` // nil means the project's markers
+	SkipMarkersIn    string        `yaml:"skip_markers_in" json:"skipMarkersIn,omitempty"`
 	Cwd              string        `yaml:"cwd" json:"cwd,omitempty"`
 	WithTempDir      bool          `yaml:"with_temp_dir" json:"withTempDir,omitempty"`
+	PayloadStdin     bool          `yaml:"payload_stdin" json:"payloadStdin,omitempty"`
+	Concurrency      Concurrency   `yaml:"concurrency" json:"concurrency,omitzero"` // the project's one, if not set
 	User             string        `yaml:"user" json:"user,omitempty"`
 	Script           string        `yaml:"script" json:"script,omitempty"`
 	Run              []string      `yaml:"run" json:"run,omitempty"`
 	Environment      EnvList       `yaml:"environment" json:"environment,omitempty"`
+	EnvFromPayload   PayloadEnv    `yaml:"env_from_payload" json:"envFromPayload,omitempty"` // includes the project's entries, after the config load
 	Timeout          time.Duration `yaml:"timeout"`
 	GracefulShutdown time.Duration `yaml:"graceful_shutdown"`
+	// condition is the compiled If expression
+	condition *condexpr.Expression
+	// compiled On and Branch patterns
+	onPatterns     CompiledPatternList
+	branchPatterns CompiledPatternList
 }
 
+// Variables available in the action's `if` condition: the parsed payload,
+// request headers (lower-cased names) and the data extracted from the webhook.
+const (
+	ConditionVarPayload = "payload"
+	ConditionVarHeaders = "headers"
+	ConditionVarWebhook = "webhook"
+)
+
+var conditionVars = []string{ConditionVarPayload, ConditionVarHeaders, ConditionVarWebhook}
+
+// Condition returns the compiled `if` expression of the action, or nil if it
+// doesn't have one. Expression is compiled on config load, actions created
+// otherwise have it compiled on demand.
+func (a Action) Condition() (*condexpr.Expression, error) {
+	if a.condition != nil || a.If == "" {
+		return a.condition, nil
+	}
+	return condexpr.Compile(a.If, conditionVars)
+}
+
+// MatchOn reports whether any of the event names matches the action's `on`
+// patterns. Patterns are compiled on config load, actions created otherwise
+// have them compiled on demand.
+func (a Action) MatchOn(events ...string) bool {
+	if a.onPatterns == nil {
+		return a.On.Match(events...)
+	}
+	return a.onPatterns.Match(events...)
+}
+
+// MatchOnExplicitly reports whether any of the event names is matched by the
+// action's `on` patterns other than the lone "*", i.e. the action is meant for
+// the event, rather than catching all of them.
+func (a Action) MatchOnExplicitly(events ...string) bool {
+	if a.onPatterns == nil {
+		return a.On.compileValid().MatchExplicitly(events...)
+	}
+	return a.onPatterns.MatchExplicitly(events...)
+}
+
+// MatchBranch reports whether the branch matches the action's `branch`
+// patterns, compiled the same way as in [Action.MatchOn].
+func (a Action) MatchBranch(branch string) bool {
+	if a.branchPatterns == nil {
+		return a.Branch.Match(branch)
+	}
+	return a.branchPatterns.Match(branch)
+}
+
+// DefaultSkipMarkers are the commit message markers skipping actions, unless
+// the project or the action overrides them.
+var DefaultSkipMarkers = []string{"[skip ci]", "[ci skip]", "[no ci]", "[skip actions]", "[actions skip]"}
+
+// Possible values of `skip_markers_in`: look for the skip markers in the head
+// commit message, or require all of the pushed commits to have one.
+const (
+	SkipMarkersInHead = "head"
+	SkipMarkersInAll  = "all"
+)
+
+// Possible values of the action's `release_type` filter
+const (
+	ReleaseTypeStable     = "stable"
+	ReleaseTypePrerelease = "prerelease"
+	ReleaseTypeDraft      = "draft"
+)
+
 func Load(configPath string) (Config, error) {
 	var cfg Config
 
@@ -116,6 +223,18 @@ func Load(configPath string) (Config, error) {
 	if cfg.MaxConcurrentActions <= 0 {
 		return cfg, fmt.Errorf("'max_concurrent_actions' must be a positive integer")
 	}
+	if cfg.MaxQueuedActions <= 0 {
+		return cfg, fmt.Errorf("'max_queued_actions' must be a positive integer")
+	}
+	if cfg.MaxSeenDeliveries <= 0 {
+		return cfg, fmt.Errorf("'max_seen_deliveries' must be a positive integer")
+	}
+	if cfg.MaxDeliveriesStored <= 0 {
+		return cfg, fmt.Errorf("'max_deliveries_stored' must be a positive integer")
+	}
+	if cfg.MaxDeliveryPayloadBytes <= 0 {
+		return cfg, fmt.Errorf("'max_delivery_payload_bytes' must be a positive integer")
+	}
 
 	if err := validateEnvEntries(cfg.Environment); err != nil {
 		return cfg, fmt.Errorf("root environment: %w", err)
@@ -170,6 +289,35 @@ func validateAndSetDefaultsConfigProjects(projects map[string]Project, global gl
 		if err := validateEnvEntries(project.Environment); err != nil {
 			return nil, fmt.Errorf("project %q environment: %w", projectName, err)
 		}
+		if err := project.EnvFromPayload.Validate(); err != nil {
+			return nil, fmt.Errorf("project %q: %w", projectName, err)
+		}
+		if err := project.Concurrency.Validate(); err != nil {
+			return nil, fmt.Errorf("project %q: %w", projectName, err)
+		}
+
+		if project.GitProvider == CustomGitProvider {
+			customProvider, err := validateAndSetDefaultsCustomProvider(project.CustomProvider)
+			if err != nil {
+				return nil, fmt.Errorf("project %q custom_provider: %w", projectName, err)
+			}
+			project.CustomProvider = customProvider
+		} else if !project.CustomProvider.IsZero() {
+			return nil, fmt.Errorf(
+				"project %q has 'custom_provider' field, but its git_provider is %q; 'custom_provider' requires `git_provider: %s`",
+				projectName,
+				project.GitProvider,
+				CustomGitProvider,
+			)
+		}
+
+		if project.AllowSha1Signature && project.GitProvider != "github" {
+			return nil, fmt.Errorf(
+				"project %q has 'allow_sha1_signature' field, but its git_provider is %q; it's only supported for github",
+				projectName,
+				project.GitProvider,
+			)
+		}
 
 		if len(project.Actions) == 0 {
 			return nil, fmt.Errorf(
@@ -188,7 +336,16 @@ func validateAndSetDefaultsConfigProjects(projects map[string]Project, global gl
 		if projectUser == "" {
 			projectUser = rootUser
 		}
-		actionsWithDefaults, err := validateAndSetDefaultConfigActions(projectName, project.Actions, global, projectEnv, projectUser)
+		if project.SkipMarkers == nil {
+			project.SkipMarkers = DefaultSkipMarkers
+		}
+		if project.SkipMarkersIn == "" {
+			project.SkipMarkersIn = SkipMarkersInHead
+		}
+		if err := validateSkipMarkers(project.SkipMarkers, project.SkipMarkersIn); err != nil {
+			return nil, fmt.Errorf("project %q: %w", projectName, err)
+		}
+		actionsWithDefaults, err := validateAndSetDefaultConfigActions(projectName, project, global, projectEnv, projectUser)
 		if err != nil {
 			return nil, fmt.Errorf("action validation failed: %w", err)
 		}
@@ -198,7 +355,8 @@ func validateAndSetDefaultsConfigProjects(projects map[string]Project, global gl
 	return projects, nil
 }
 
-func validateAndSetDefaultConfigActions(projectName string, actions []Action, global globalDefaults, projectEnv EnvList, projectUser string) ([]Action, error) {
+func validateAndSetDefaultConfigActions(projectName string, project Project, global globalDefaults, projectEnv EnvList, projectUser string) ([]Action, error) {
+	actions := project.Actions
 	for i, action := range actions {
 		wrapActionErr := func(err error) error {
 			return fmt.Errorf(
@@ -210,9 +368,61 @@ func validateAndSetDefaultConfigActions(projectName string, actions []Action, gl
 			)
 		}
 
+		if action.Tag != "" && len(action.Branch) > 0 {
+			return nil, wrapActionErr(fmt.Errorf("has both 'tag' and 'branch' simultaneously, you must use one"))
+		}
+		if _, err := path.Match(action.Tag, ""); err != nil {
+			return nil, wrapActionErr(fmt.Errorf("invalid 'tag' pattern %q: %w", action.Tag, err))
+		}
+
+		if action.If != "" {
+			condition, err := condexpr.Compile(action.If, conditionVars)
+			if err != nil {
+				return nil, wrapActionErr(fmt.Errorf("invalid 'if' condition: %w", err))
+			}
+			action.condition = condition
+		}
+		for _, pattern := range action.Paths {
+			if err := pathglob.Validate(pattern); err != nil {
+				return nil, wrapActionErr(fmt.Errorf("invalid 'paths' pattern %q: %w", pattern, err))
+			}
+		}
+		for _, pattern := range action.PathsIgnore {
+			if err := pathglob.Validate(pattern); err != nil {
+				return nil, wrapActionErr(fmt.Errorf("invalid 'paths_ignore' pattern %q: %w", pattern, err))
+			}
+		}
+
+		switch action.ReleaseType {
+		case "", ReleaseTypeStable, ReleaseTypePrerelease, ReleaseTypeDraft:
+		default:
+			return nil, wrapActionErr(fmt.Errorf(
+				"unknown 'release_type' %q, possible values are '%s', '%s' and '%s'",
+				action.ReleaseType, ReleaseTypeStable, ReleaseTypePrerelease, ReleaseTypeDraft,
+			))
+		}
+		if action.ReleaseType != "" && len(action.Branch) > 0 {
+			return nil, wrapActionErr(fmt.Errorf("has both 'release_type' and 'branch', but releases don't have a branch"))
+		}
+
 		if err := setDefaultAndCheckRequired(&action); err != nil {
 			return nil, wrapActionErr(fmt.Errorf("action  has issue with its fields: %w", err))
 		}
+		if action.Tag != "" || action.ReleaseType != "" {
+			// default branch doesn't apply to tag and release actions
+			action.Branch = nil
+		}
+		// compiled after the defaults are set, so they're matched as well
+		onPatterns, err := action.On.Compile()
+		if err != nil {
+			return nil, wrapActionErr(fmt.Errorf("invalid 'on' pattern: %w", err))
+		}
+		action.onPatterns = onPatterns
+		branchPatterns, err := action.Branch.Compile()
+		if err != nil {
+			return nil, wrapActionErr(fmt.Errorf("invalid 'branch' pattern: %w", err))
+		}
+		action.branchPatterns = branchPatterns
 		if action.Script == "" && len(action.Run) == 0 {
 			return nil, wrapActionErr(fmt.Errorf("has neither 'script' nor 'run' fields and can not be executed"))
 		}
@@ -223,11 +433,34 @@ func validateAndSetDefaultConfigActions(projectName string, actions []Action, gl
 		if err := validateEnvEntries(action.Environment); err != nil {
 			return nil, wrapActionErr(err)
 		}
+		if err := action.EnvFromPayload.Validate(); err != nil {
+			return nil, wrapActionErr(err)
+		}
 
 		if action.User == "" {
 			action.User = projectUser
 		}
 
+		if action.Concurrency.IsZero() {
+			action.Concurrency = project.Concurrency
+		}
+		if err := action.Concurrency.Validate(); err != nil {
+			return nil, wrapActionErr(err)
+		}
+		if action.Concurrency.Group != "" && action.Concurrency.Policy == "" {
+			action.Concurrency.Policy = ConcurrencyPolicyQueue
+		}
+
+		if action.SkipMarkers == nil {
+			action.SkipMarkers = project.SkipMarkers
+		}
+		if action.SkipMarkersIn == "" {
+			action.SkipMarkersIn = project.SkipMarkersIn
+		}
+		if err := validateSkipMarkers(action.SkipMarkers, action.SkipMarkersIn); err != nil {
+			return nil, wrapActionErr(err)
+		}
+
 		if runtime.GOOS != "windows" && action.User != "" {
 			if _, err := user.Lookup(action.User); err != nil {
 				return nil, wrapActionErr(fmt.Errorf("has a user field = %q, but this user can't be found: %w", action.User, err))
@@ -246,12 +479,25 @@ func validateAndSetDefaultConfigActions(projectName string, actions []Action, gl
 		}
 
 		action.Environment = slices.Concat(projectEnv, action.Environment)
+		action.EnvFromPayload = mergePayloadEnv(project.EnvFromPayload, action.EnvFromPayload)
 
 		actions[i] = action
 	}
 	return actions, nil
 }
 
+func validateSkipMarkers(markers []string, in string) error {
+	if slices.Contains(markers, "") {
+		return fmt.Errorf("'skip_markers' can't contain an empty marker")
+	}
+	switch in {
+	case SkipMarkersInHead, SkipMarkersInAll:
+		return nil
+	default:
+		return fmt.Errorf("unknown 'skip_markers_in' %q, possible values are '%s' and '%s'", in, SkipMarkersInHead, SkipMarkersInAll)
+	}
+}
+
 // validateEnvEntries checks that each action `environment` entry has the
 // "KEY=VALUE" shape with a POSIX-conformant KEY. The VALUE itself is only
 // resolved at run time (it depends on the process environment), so we don't
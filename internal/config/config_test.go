@@ -7,7 +7,9 @@ import (
 	"log/slog"
 	"os"
 	"os/user"
+	"reflect"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -92,11 +94,11 @@ actions_db_file: db2.sqlite3
 		project := cfg.Projects["test-proj"]
 		action := project.Actions[0]
 
-		if want, got := "push", action.On; want != got {
+		if want, got := (config.PatternList{"push"}), action.On; !slices.Equal(want, got) {
 			t.Errorf("Incorrect default on event, want %q, got %q", want, got)
 		}
 
-		if want, got := "master", action.Branch; want != got {
+		if want, got := (config.PatternList{"master"}), action.Branch; !slices.Equal(want, got) {
 			t.Errorf("Incorrect default branch, want %q, got %q", want, got)
 		}
 	})
@@ -130,11 +132,11 @@ func TestConfigLoadEnv(t *testing.T) {
 			t.Errorf("incorrect action db file read from config, want %q, got %q", want, got)
 		}
 
-		if want, got := secret, project.Secret.RawContents(); want != got {
+		if want, got := []string{secret}, project.Secret.RawContents(); !slices.Equal(want, got) {
 			t.Errorf("incorrect secret value read from config, want %q, got %q", want, got)
 		}
 
-		if want, got := auth, project.Authorization.RawContents(); want != got {
+		if want, got := []string{auth}, project.Authorization.RawContents(); !slices.Equal(want, got) {
 			t.Errorf("incorrect auth value read from config, want %q, got %q", want, got)
 		}
 	})
@@ -302,6 +304,76 @@ projects:
 	}
 }
 
+func TestConfigCustomProvider(t *testing.T) {
+	makeConfig := func(providerYAML string) string {
+		return `
+projects:
+  test-proj:
+    git_provider: custom
+    repo: "username/reponame"
+    custom_provider:
+` + providerYAML + `
+    actions:
+      - run: ["node", "--version"]
+`
+	}
+
+	t.Run("loads the provider and sets the defaults", func(t *testing.T) {
+		cfg := loadMockConfig(t, makeConfig(`      event_header: X-Event
+      signature_header: X-Signature
+      ref_path: /ref
+      repo_path: /repository/full_name`))
+		provider := cfg.Projects["test-proj"].CustomProvider
+		if want, got := "X-Event", provider.EventHeader; want != got {
+			t.Errorf("incorrect event_header, want %q, got %q", want, got)
+		}
+		if want, got := "/repository/full_name", provider.RepoPath; want != got {
+			t.Errorf("incorrect repo_path, want %q, got %q", want, got)
+		}
+		if want, got := "sha256", provider.SignatureAlgorithm; want != got {
+			t.Errorf("incorrect default signature_algorithm, want %q, got %q", want, got)
+		}
+		if want, got := "hex", provider.SignatureEncoding; want != got {
+			t.Errorf("incorrect default signature_encoding, want %q, got %q", want, got)
+		}
+	})
+
+	badProviders := []struct {
+		desc     string
+		provider string
+	}{
+		{"missing event_header", "      repo_path: /repo"},
+		{"missing repo_path", "      event_header: X-Event\n      ref_path: /ref"},
+		{"bad json pointer", "      event_header: X-Event\n      repo_path: repository.full_name"},
+		{"unknown algorithm", "      event_header: X-Event\n      repo_path: /repo\n      signature_algorithm: md5"},
+		{"unknown encoding", "      event_header: X-Event\n      repo_path: /repo\n      signature_encoding: base32"},
+	}
+	for _, tt := range badProviders {
+		t.Run(tt.desc, func(t *testing.T) {
+			configFileName := tmpConfigFile(t, makeConfig(tt.provider))
+			if _, err := config.Load(configFileName); err == nil {
+				t.Errorf("validation wasn't triggered for %s", tt.desc)
+			}
+		})
+	}
+
+	t.Run("custom_provider without custom git_provider is rejected", func(t *testing.T) {
+		configFileName := tmpConfigFile(t, `
+projects:
+  test-proj:
+    git_provider: gitea
+    repo: "username/reponame"
+    custom_provider:
+      repo_path: /repo
+    actions:
+      - run: ["node", "--version"]
+`)
+		if _, err := config.Load(configFileName); err == nil {
+			t.Errorf("validation wasn't triggered")
+		}
+	})
+}
+
 func TestDefaultMaxActionsStored(t *testing.T) {
 	baseCfg := `
 host: test.example.com
@@ -729,6 +801,498 @@ projects:
 	}
 }
 
+func TestConfigSecretList(t *testing.T) {
+	tests := []struct {
+		name       string
+		secret     string
+		env        string
+		wantSecret []string
+	}{
+		{"single value", `secret: "foo"`, "", []string{"foo"}},
+		{"list of values", "secret: [\"foo\", \"bar\"]", "", []string{"foo", "bar"}},
+		{"empty value", `secret: ""`, "", []string{}},
+		{"newline separated env", "", "foo\nbar\r\n", []string{"foo", "bar"}},
+		{"commas are kept in env", "", "foo,bar", []string{"foo,bar"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("PROJECTS__test-proj__SECRET", tt.env)
+			}
+			cfg := loadMockConfig(t, fmt.Sprintf(`projects:
+  test-proj:
+    git_provider: gitea
+    repo: "username/reponame"
+    %s
+    actions:
+      - run: ["node", "--version"]
+`, tt.secret))
+			if got := cfg.Projects["test-proj"].Secret.RawContents(); !slices.Equal(tt.wantSecret, got) {
+				t.Errorf("incorrect secret values read from config, want %q, got %q", tt.wantSecret, got)
+			}
+		})
+	}
+
+	t.Run("rejects mappings", func(t *testing.T) {
+		_, err := config.Load(tmpConfigFile(t, `projects:
+  test-proj:
+    git_provider: gitea
+    repo: "username/reponame"
+    secret:
+      foo: bar
+    actions:
+      - run: ["node", "--version"]
+`))
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
+
+func TestConfigAllowSha1Signature(t *testing.T) {
+	t.Run("allowed for github", func(t *testing.T) {
+		cfg := loadMockConfig(t, `projects:
+  test-proj:
+    git_provider: github
+    repo: "username/reponame"
+    allow_sha1_signature: true
+    actions:
+      - run: ["node", "--version"]
+`)
+		if !cfg.Projects["test-proj"].AllowSha1Signature {
+			t.Error("expected allow_sha1_signature to be read from config")
+		}
+	})
+
+	t.Run("rejected for other providers", func(t *testing.T) {
+		_, err := config.Load(tmpConfigFile(t, `projects:
+  test-proj:
+    git_provider: gitea
+    repo: "username/reponame"
+    allow_sha1_signature: true
+    actions:
+      - run: ["node", "--version"]
+`))
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
+
+func TestConfigActionTag(t *testing.T) {
+	loadActionConfig := func(t *testing.T, action string) (config.Config, error) {
+		t.Helper()
+		return config.Load(tmpConfigFile(t, `projects:
+  test-proj:
+    git_provider: gitea
+    repo: "username/reponame"
+    actions:
+      - run: ["node", "--version"]
+`+action))
+	}
+
+	t.Run("tag actions don't get the default branch", func(t *testing.T) {
+		cfg, err := loadActionConfig(t, `        tag: "v*"`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		action := cfg.Projects["test-proj"].Actions[0]
+		if want, got := "v*", action.Tag; want != got {
+			t.Errorf("incorrect tag, want %q, got %q", want, got)
+		}
+		if got := action.Branch; len(got) > 0 {
+			t.Errorf("expected branch to be empty, got %q", got)
+		}
+	})
+
+	t.Run("tag and branch are mutually exclusive", func(t *testing.T) {
+		_, err := loadActionConfig(t, `        tag: "v*"
+        branch: main`)
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+
+	t.Run("rejects malformed patterns", func(t *testing.T) {
+		_, err := loadActionConfig(t, `        tag: "v["`)
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
+
+func TestConfigActionReleaseType(t *testing.T) {
+	loadActionConfig := func(t *testing.T, action string) (config.Config, error) {
+		t.Helper()
+		return config.Load(tmpConfigFile(t, `projects:
+  test-proj:
+    git_provider: gitea
+    repo: "username/reponame"
+    actions:
+      - run: ["node", "--version"]
+        on: release
+`+action))
+	}
+
+	t.Run("release actions don't get the default branch", func(t *testing.T) {
+		cfg, err := loadActionConfig(t, `        release_type: prerelease`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cfg.Projects["test-proj"].Actions[0].Branch; len(got) > 0 {
+			t.Errorf("expected branch to be empty, got %q", got)
+		}
+	})
+
+	t.Run("release type and branch are mutually exclusive", func(t *testing.T) {
+		_, err := loadActionConfig(t, `        release_type: stable
+        branch: main`)
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+
+	t.Run("rejects unknown release types", func(t *testing.T) {
+		_, err := loadActionConfig(t, `        release_type: beta`)
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
+
+func TestConfigActionPaths(t *testing.T) {
+	loadActionConfig := func(t *testing.T, action string) (config.Config, error) {
+		t.Helper()
+		return config.Load(tmpConfigFile(t, `projects:
+  test-proj:
+    git_provider: gitea
+    repo: "username/reponame"
+    actions:
+      - run: ["node", "--version"]
+`+action))
+	}
+
+	t.Run("reads paths and paths_ignore", func(t *testing.T) {
+		cfg, err := loadActionConfig(t, `        paths: ["backend/**", "go.mod"]
+        paths_ignore: ["**/*.md"]`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		action := cfg.Projects["test-proj"].Actions[0]
+		if want, got := []string{"backend/**", "go.mod"}, action.Paths; !slices.Equal(want, got) {
+			t.Errorf("incorrect paths, want %q, got %q", want, got)
+		}
+		if want, got := []string{"**/*.md"}, action.PathsIgnore; !slices.Equal(want, got) {
+			t.Errorf("incorrect paths_ignore, want %q, got %q", want, got)
+		}
+	})
+
+	t.Run("rejects malformed paths patterns", func(t *testing.T) {
+		_, err := loadActionConfig(t, `        paths: ["backend/["]`)
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+
+	t.Run("rejects malformed paths_ignore patterns", func(t *testing.T) {
+		_, err := loadActionConfig(t, `        paths_ignore: ["docs/[a-"]`)
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
+
+func TestConfigActionPatterns(t *testing.T) {
+	loadActionConfig := func(t *testing.T, action string) (config.Config, error) {
+		t.Helper()
+		return config.Load(tmpConfigFile(t, `projects:
+  test-proj:
+    git_provider: gitea
+    repo: "username/reponame"
+    actions:
+      - run: ["node", "--version"]
+`+action))
+	}
+
+	t.Run("reads a list of patterns", func(t *testing.T) {
+		cfg, err := loadActionConfig(t, `        on: [push, "pull_request.*"]
+        branch: ["release/*", "regex:^(main|develop)$", "!release/old-*"]`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		action := cfg.Projects["test-proj"].Actions[0]
+		if want, got := (config.PatternList{"push", "pull_request.*"}), action.On; !slices.Equal(want, got) {
+			t.Errorf("incorrect on, want %q, got %q", want, got)
+		}
+		if want, got := (config.PatternList{"release/*", "regex:^(main|develop)$", "!release/old-*"}), action.Branch; !slices.Equal(want, got) {
+			t.Errorf("incorrect branch, want %q, got %q", want, got)
+		}
+		if !action.MatchOn("pull_request", "pull_request.opened") || action.MatchOn("release") {
+			t.Error("compiled on patterns don't match as expected")
+		}
+		if !action.MatchBranch("develop") || action.MatchBranch("release/old-1") {
+			t.Error("compiled branch patterns don't match as expected")
+		}
+	})
+
+	t.Run("compiles the default patterns", func(t *testing.T) {
+		cfg, err := loadActionConfig(t, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		action := cfg.Projects["test-proj"].Actions[0]
+		if !action.MatchOn("push") || !action.MatchBranch("master") || action.MatchBranch("main") {
+			t.Error("default patterns don't match as expected")
+		}
+	})
+
+	t.Run("rejects malformed glob patterns", func(t *testing.T) {
+		_, err := loadActionConfig(t, `        branch: ["main", "release/["]`)
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+
+	t.Run("rejects malformed regex patterns", func(t *testing.T) {
+		_, err := loadActionConfig(t, `        on: "regex:push("`)
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
+
+func TestConfigActionCondition(t *testing.T) {
+	loadActionConfig := func(t *testing.T, action string) (config.Config, error) {
+		t.Helper()
+		return config.Load(tmpConfigFile(t, `projects:
+  test-proj:
+    git_provider: gitea
+    repo: "username/reponame"
+    actions:
+      - run: ["node", "--version"]
+`+action))
+	}
+
+	t.Run("compiles the condition", func(t *testing.T) {
+		cfg, err := loadActionConfig(t, `        if: "payload.pusher.login == 'octocat' && !payload.repository.private"`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		condition, err := cfg.Projects["test-proj"].Actions[0].Condition()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if condition == nil {
+			t.Error("expected the condition to be compiled")
+		}
+	})
+
+	t.Run("rejects malformed conditions", func(t *testing.T) {
+		_, err := loadActionConfig(t, `        if: "payload.pusher.login =="`)
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+
+	t.Run("rejects unknown variables", func(t *testing.T) {
+		_, err := loadActionConfig(t, `        if: "github.actor == 'octocat'"`)
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
+
+func TestConfigSkipMarkers(t *testing.T) {
+	loadProjectConfig := func(t *testing.T, project string, action string) (config.Config, error) {
+		t.Helper()
+		return config.Load(tmpConfigFile(t, `projects:
+  test-proj:
+    git_provider: gitea
+    repo: "username/reponame"
+`+project+`
+    actions:
+      - run: ["node", "--version"]
+`+action))
+	}
+
+	t.Run("defaults to the skip ci markers in the head commit", func(t *testing.T) {
+		cfg, err := loadProjectConfig(t, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		action := cfg.Projects["test-proj"].Actions[0]
+		if !slices.Equal(action.SkipMarkers, config.DefaultSkipMarkers) {
+			t.Errorf("want %q, got %q", config.DefaultSkipMarkers, action.SkipMarkers)
+		}
+		if action.SkipMarkersIn != config.SkipMarkersInHead {
+			t.Errorf("want %q, got %q", config.SkipMarkersInHead, action.SkipMarkersIn)
+		}
+	})
+
+	t.Run("action inherits project markers", func(t *testing.T) {
+		cfg, err := loadProjectConfig(t, `    skip_markers: ["[skip deploy]"]
+    skip_markers_in: all`, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		action := cfg.Projects["test-proj"].Actions[0]
+		if want := []string{"[skip deploy]"}; !slices.Equal(action.SkipMarkers, want) {
+			t.Errorf("want %q, got %q", want, action.SkipMarkers)
+		}
+		if action.SkipMarkersIn != config.SkipMarkersInAll {
+			t.Errorf("want %q, got %q", config.SkipMarkersInAll, action.SkipMarkersIn)
+		}
+	})
+
+	t.Run("action overrides project markers", func(t *testing.T) {
+		cfg, err := loadProjectConfig(t, `    skip_markers: ["[skip deploy]"]`, `        skip_markers: []`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cfg.Projects["test-proj"].Actions[0].SkipMarkers; got == nil || len(got) != 0 {
+			t.Errorf("want an empty list, got %q", got)
+		}
+	})
+
+	t.Run("rejects unknown skip_markers_in", func(t *testing.T) {
+		_, err := loadProjectConfig(t, "", `        skip_markers_in: some`)
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+
+	t.Run("rejects empty markers", func(t *testing.T) {
+		_, err := loadProjectConfig(t, `    skip_markers: [""]`, "")
+		if err == nil {
+			t.Error("expected an error, got nil")
+		}
+	})
+}
+
+func TestConfigEnvFromPayload(t *testing.T) {
+	loadProjectConfig := func(t *testing.T, project string, action string) (config.Config, error) {
+		t.Helper()
+		return config.Load(tmpConfigFile(t, `projects:
+  test-proj:
+    git_provider: gitea
+    repo: "username/reponame"
+`+project+`
+    actions:
+      - run: ["node", "--version"]
+`+action))
+	}
+
+	t.Run("parses both short and full forms, merging project and action", func(t *testing.T) {
+		cfg, err := loadProjectConfig(t, `    env_from_payload:
+      AUTHOR_EMAIL: /head_commit/author/email
+      CLONE_URL: /repository/clone_url`, `        env_from_payload:
+          CLONE_URL:
+            path: /repository/ssh_url
+            required: true
+          SENDER:
+            path: /sender/login
+            default: unknown`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := config.PayloadEnv{
+			"AUTHOR_EMAIL": {Path: "/head_commit/author/email"},
+			"CLONE_URL":    {Path: "/repository/ssh_url", Required: true},
+			"SENDER":       {Path: "/sender/login", Default: "unknown"},
+		}
+		if got := cfg.Projects["test-proj"].Actions[0].EnvFromPayload; !reflect.DeepEqual(want, got) {
+			t.Errorf("want %v, got %v", want, got)
+		}
+	})
+
+	cases := []struct {
+		name   string
+		action string
+	}{
+		{"rejects bad variable names", `        env_from_payload:
+          1VAR: /foo`},
+		{"rejects bad pointers", `        env_from_payload:
+          VAR: foo`},
+		{"rejects empty path", `        env_from_payload:
+          VAR:
+            default: foo`},
+		{"rejects required variables with a default", `        env_from_payload:
+          VAR:
+            path: /foo
+            default: bar
+            required: true`},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadProjectConfig(t, "", tt.action); err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestPatternListMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns config.PatternList
+		value    string
+		want     bool
+	}{
+		{"exact", config.PatternList{"main"}, "main", true},
+		{"exact mismatch", config.PatternList{"main"}, "develop", false},
+		{"any of the list", config.PatternList{"main", "develop"}, "develop", true},
+		{"glob", config.PatternList{"release/*"}, "release/1.0", true},
+		{"glob doesn't cross slashes", config.PatternList{"release/*"}, "release/1.0/hotfix", false},
+		{"double star glob", config.PatternList{"release/**"}, "release/1.0/hotfix", true},
+		{"lone star matches anything", config.PatternList{"*"}, "feature/foo", true},
+		{"lone star matches empty value", config.PatternList{"*"}, "", true},
+		{"regex", config.PatternList{"regex:^(main|develop)$"}, "develop", true},
+		{"regex isn't anchored", config.PatternList{"regex:hotfix"}, "release/hotfix-1", true},
+		{"regex mismatch", config.PatternList{"regex:^(main|develop)$"}, "develop2", false},
+		{"negation excludes", config.PatternList{"*", "!wip/*"}, "wip/foo", false},
+		{"negation doesn't exclude others", config.PatternList{"*", "!wip/*"}, "feature/foo", true},
+		{"only negations", config.PatternList{"!wip/*"}, "feature/foo", true},
+		{"only negations excludes", config.PatternList{"!wip/*"}, "wip/foo", false},
+		{"only negations don't match empty value", config.PatternList{"!main"}, "", false},
+		{"negation takes precedence", config.PatternList{"!release/old-*", "release/*"}, "release/old-1", false},
+		{"negated regex", config.PatternList{"!regex:^tmp-"}, "tmp-1", false},
+		{"empty list", config.PatternList{}, "main", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.patterns.Match(tt.value); got != tt.want {
+				t.Errorf("%q.Match(%q) = %t, want %t", tt.patterns, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternListJSON(t *testing.T) {
+	for _, tt := range []struct {
+		patterns config.PatternList
+		json     string
+	}{
+		{config.PatternList{"main"}, `"main"`},
+		{config.PatternList{"main", "develop"}, `["main","develop"]`},
+	} {
+		data, err := json.Marshal(tt.patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tt.json {
+			t.Errorf("unexpected json, want %s, got %s", tt.json, data)
+		}
+		var got config.PatternList
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.patterns) {
+			t.Errorf("json round trip failed, want %q, got %q", tt.patterns, got)
+		}
+	}
+}
+
 func TestSensitiveDataMasking(t *testing.T) {
 	makeTestCfg := func() config.Config {
 		cfg := config.Config{
@@ -737,11 +1301,11 @@ func TestSensitiveDataMasking(t *testing.T) {
 		cfg.AuthPassword = "t3stPa55w0rd"
 		cfg.Environment = config.EnvList{"ROOT_TOKEN=r00tEnvS3cr3t"}
 		cfg.Projects["proj1"] = config.Project{
-			Authorization: "B3ar3rT0k3nV4lu3",
+			Authorization: config.SecretList{"B3ar3rT0k3nV4lu3"},
 			Environment:   config.EnvList{"PROJ_TOKEN=pr0jEnvS3cr3t"},
 		}
 		cfg.Projects["proj2"] = config.Project{
-			Secret: "wh00kS3cr3tV4lu3",
+			Secret: config.SecretList{"wh00kS3cr3tV4lu3", "0ldS3cr3tV4lu3"},
 			Actions: []config.Action{
 				{Environment: config.EnvList{"ACTION_TOKEN=acti0nEnvS3cr3t"}},
 			},
@@ -750,7 +1314,7 @@ func TestSensitiveDataMasking(t *testing.T) {
 	}
 
 	passwords := []string{
-		"t3stPa55w0rd", "B3ar3rT0k3nV4lu3", "wh00kS3cr3tV4lu3",
+		"t3stPa55w0rd", "B3ar3rT0k3nV4lu3", "wh00kS3cr3tV4lu3", "0ldS3cr3tV4lu3",
 		"r00tEnvS3cr3t", "pr0jEnvS3cr3t", "acti0nEnvS3cr3t",
 		// env keys are masked too, so they must not appear either
 		"ROOT_TOKEN", "PROJ_TOKEN", "ACTION_TOKEN",
@@ -796,3 +1360,87 @@ func TestSensitiveDataMasking(t *testing.T) {
 		}
 	})
 }
+
+func TestConfigConcurrency(t *testing.T) {
+	loadProjectConfig := func(t *testing.T, project string, action string) (config.Config, error) {
+		t.Helper()
+		return config.Load(tmpConfigFile(t, `projects:
+  test-proj:
+    git_provider: gitea
+    repo: "username/reponame"
+`+project+`
+    actions:
+      - run: ["node", "--version"]
+`+action+`
+      - run: ["node", "--version"]
+`))
+	}
+
+	t.Run("parses the short form with the default policy, inherited by the actions", func(t *testing.T) {
+		cfg, err := loadProjectConfig(t, `    concurrency: deploy-${project}-${branch}`, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := config.Concurrency{Group: "deploy-${project}-${branch}", Policy: config.ConcurrencyPolicyQueue}
+		for i, action := range cfg.Projects["test-proj"].Actions {
+			if action.Concurrency != want {
+				t.Errorf("action %d: want %v, got %v", i, want, action.Concurrency)
+			}
+		}
+	})
+
+	t.Run("action overrides the project's concurrency", func(t *testing.T) {
+		cfg, err := loadProjectConfig(t, `    concurrency: deploy`, `        concurrency:
+          group: migrations
+          policy: skip`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actions := cfg.Projects["test-proj"].Actions
+		if want := (config.Concurrency{Group: "migrations", Policy: config.ConcurrencyPolicySkip}); actions[0].Concurrency != want {
+			t.Errorf("want %v, got %v", want, actions[0].Concurrency)
+		}
+		if want := (config.Concurrency{Group: "deploy", Policy: config.ConcurrencyPolicyQueue}); actions[1].Concurrency != want {
+			t.Errorf("want %v, got %v", want, actions[1].Concurrency)
+		}
+	})
+
+	t.Run("no concurrency by default", func(t *testing.T) {
+		cfg, err := loadProjectConfig(t, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cfg.Projects["test-proj"].Actions[0].Concurrency; !got.IsZero() {
+			t.Errorf("want zero concurrency, got %v", got)
+		}
+	})
+
+	cases := []struct {
+		name    string
+		project string
+		action  string
+	}{
+		{"rejects unknown policies", "", `        concurrency:
+          group: deploy
+          policy: parallel`},
+		{"rejects a policy without a group", "", `        concurrency:
+          policy: skip`},
+		{"rejects unknown variables", "", `        concurrency: deploy-${ref}`},
+		{"rejects bad project concurrency", `    concurrency: deploy-${unknown}`, ""},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadProjectConfig(t, tt.project, tt.action); err == nil {
+				t.Error("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestConcurrencyGroupKey(t *testing.T) {
+	c := config.Concurrency{Group: "${project}/${event}/${branch}${tag}"}
+	got := c.GroupKey(config.ConcurrencyVars{Project: "proj", Branch: "main", Event: "push"})
+	if want := "proj/push/main"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
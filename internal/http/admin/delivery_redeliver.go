@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/http/middleware"
+	"github.com/religiosa1/git-webhook-receiver/internal/http/webhook"
+	"github.com/religiosa1/git-webhook-receiver/internal/views"
+)
+
+var errNothingRedelivered = errors.New("no actions of the current config matched the redelivered delivery")
+
+// RedeliverDelivery redelivers the stored delivery and redirects to the first
+// started pipeline.
+type RedeliverDelivery struct {
+	Redeliverer webhook.Redeliverer
+}
+
+func (s RedeliverDelivery) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	recordID := req.PathValue("deliveryId")
+	logger := middleware.GetLogger(req.Context()).With(slog.String("delivery_record_id", recordID))
+	if s.Redeliverer.DB == nil {
+		logger.Error("redeliver form submitted, while no actions db is provided")
+		w.WriteHeader(http.StatusNotFound)
+		if writeErr := views.NotFound().Render(req.Context(), w); writeErr != nil {
+			logger.Error("error while writing error response", slog.Any("error", writeErr))
+		}
+		return
+	}
+
+	output, err := s.Redeliverer.Redeliver(logger, recordID)
+	if err == nil && (len(output) == 0 || output[0].PipeID == "") {
+		err = errNothingRedelivered
+	}
+	if err != nil {
+		if mapError(err) == http.StatusInternalServerError {
+			logger.Error("Error processing redeliver ui request", slog.Any("error", err))
+		}
+		if writeErr := renderErr(w, req, err); writeErr != nil {
+			logger.Error("error while writing error response", slog.Any("error", writeErr))
+		}
+		return
+	}
+	location := views.MakePublicURL(req.Context(), fmt.Sprintf("/pipelines/%s", url.PathEscape(output[0].PipeID)))
+	http.Redirect(w, req, location, http.StatusSeeOther)
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/a-h/templ"
 	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
 	"github.com/religiosa1/git-webhook-receiver/internal/http/middleware"
+	"github.com/religiosa1/git-webhook-receiver/internal/http/webhook"
 	"github.com/religiosa1/git-webhook-receiver/internal/logsdb"
 	"github.com/religiosa1/git-webhook-receiver/internal/views"
 )
@@ -19,7 +20,11 @@ func mapError(err error) int {
 	case errors.Is(err, actionsdb.ErrBadCursor),
 		errors.Is(err, actionsdb.ErrCursorAndOffset),
 		errors.Is(err, logsdb.ErrBadCursor),
-		errors.Is(err, logsdb.ErrCursorAndOffset):
+		errors.Is(err, logsdb.ErrCursorAndOffset),
+		errors.Is(err, webhook.ErrPayloadTruncated),
+		errors.Is(err, webhook.ErrUnknownProject),
+		errors.Is(err, webhook.ErrBadDelivery),
+		errors.Is(err, errNothingRedelivered):
 		return http.StatusBadRequest
 	default:
 		return http.StatusInternalServerError
@@ -0,0 +1,161 @@
+package api_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+	"github.com/religiosa1/git-webhook-receiver/internal/http/api"
+)
+
+type deliveryListResponse struct {
+	Items      []deliveryResponse `json:"items"`
+	TotalCount int                `json:"totalCount"`
+	NextPage   *string            `json:"nextPage"`
+}
+
+type deliveryResponse struct {
+	ID               string  `json:"id"`
+	Project          string  `json:"project"`
+	DeliveryID       string  `json:"deliveryId"`
+	StatusCode       int     `json:"statusCode"`
+	Payload          *string `json:"payload"`
+	PayloadTruncated *bool   `json:"payloadTruncated"`
+}
+
+func seedDeliveryRecord(t *testing.T, db *actionsdb.ActionDB, project, deliveryID, payload string) string {
+	t.Helper()
+	recordID := ulid.Make().String()
+	err := db.CreateDeliveryRecord(actionsdb.DeliveryRecord{
+		RecordID:     recordID,
+		Project:      project,
+		DeliveryID:   deliveryID,
+		Event:        "push",
+		Headers:      http.Header{"Content-Type": {"application/json"}},
+		Payload:      []byte(payload),
+		PayloadSize:  len(payload),
+		Verification: actionsdb.DeliveryVerificationNone,
+		StatusCode:   http.StatusCreated,
+	}, 1000)
+	if err != nil {
+		t.Fatalf("seed delivery %s: %v", recordID, err)
+	}
+	return recordID
+}
+
+func TestListDeliveries(t *testing.T) {
+	db := newTestActionDB(t)
+	for i := range 3 {
+		seedDeliveryRecord(t, db, "myproject", ulid.Make().String(), fmt.Sprintf(`{"n":%d}`, i))
+	}
+	seedDeliveryRecord(t, db, "other", "del-other", `{}`)
+	handler := api.ListDeliveries{DB: db}
+
+	decode := func(t *testing.T, rec *httptest.ResponseRecorder) deliveryListResponse {
+		t.Helper()
+		var resp deliveryListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode list response: %v", err)
+		}
+		return resp
+	}
+
+	t.Run("paginates with a cursor", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/deliveries?limit=3", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Code; got != http.StatusOK {
+			t.Fatalf("status: want %d, got %d", http.StatusOK, got)
+		}
+		resp := decode(t, rec)
+		if resp.TotalCount != 4 {
+			t.Errorf("totalCount: want 4, got %d", resp.TotalCount)
+		}
+		if len(resp.Items) != 3 {
+			t.Errorf("items: want 3, got %d", len(resp.Items))
+		}
+		if resp.NextPage == nil {
+			t.Fatal("nextPage: want a link, got nil")
+		}
+		for _, item := range resp.Items {
+			if item.Payload != nil {
+				t.Errorf("payload: want it omitted in the list, got %q", *item.Payload)
+			}
+		}
+
+		req = httptest.NewRequest(http.MethodGet, *resp.NextPage, nil)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		resp = decode(t, rec)
+		if len(resp.Items) != 1 || resp.NextPage != nil {
+			t.Errorf("unexpected last page: %+v", resp)
+		}
+	})
+
+	t.Run("filters by project", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/deliveries?project=other", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		resp := decode(t, rec)
+		if len(resp.Items) != 1 || resp.Items[0].DeliveryID != "del-other" {
+			t.Errorf("unexpected filtered items: %+v", resp.Items)
+		}
+	})
+
+	t.Run("returns 400 for a bad cursor", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/deliveries?cursor=bad", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Code; got != http.StatusBadRequest {
+			t.Errorf("status: want %d, got %d", http.StatusBadRequest, got)
+		}
+	})
+}
+
+func TestGetDelivery(t *testing.T) {
+	db := newTestActionDB(t)
+	recordID := seedDeliveryRecord(t, db, "myproject", "del-123", `{"ref":"refs/heads/main"}`)
+	handler := api.GetDelivery{DB: db}
+
+	t.Run("returns 200 with the payload for existing id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/deliveries/"+recordID, nil)
+		req.SetPathValue("deliveryId", recordID)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Code; got != http.StatusOK {
+			t.Fatalf("status: want %d, got %d", http.StatusOK, got)
+		}
+		var resp deliveryResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if resp.ID != recordID || resp.DeliveryID != "del-123" || resp.StatusCode != http.StatusCreated {
+			t.Errorf("unexpected delivery: %+v", resp)
+		}
+		if resp.Payload == nil || *resp.Payload != `{"ref":"refs/heads/main"}` {
+			t.Errorf("unexpected payload: %v", resp.Payload)
+		}
+		if resp.PayloadTruncated == nil || *resp.PayloadTruncated {
+			t.Errorf("payloadTruncated: want false, got %v", resp.PayloadTruncated)
+		}
+	})
+
+	t.Run("returns 404 for non-existent id", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/deliveries/nosuchid", nil)
+		req.SetPathValue("deliveryId", "nosuchid")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if got := rec.Code; got != http.StatusNotFound {
+			t.Errorf("status: want %d, got %d", http.StatusNotFound, got)
+		}
+	})
+}
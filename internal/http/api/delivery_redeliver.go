@@ -0,0 +1,73 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/http/middleware"
+	"github.com/religiosa1/git-webhook-receiver/internal/http/utils"
+	"github.com/religiosa1/git-webhook-receiver/internal/http/webhook"
+)
+
+type RedeliverDelivery struct {
+	Redeliverer webhook.Redeliverer
+}
+
+func (h RedeliverDelivery) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	logger := middleware.GetLogger(req.Context())
+	recordID := req.PathValue("deliveryId")
+
+	if h.Redeliverer.DB == nil {
+		logger.Error("redeliver endpoint accessed, while no actions db is provided")
+		if writeErr := utils.WriteErrorResponse(w, http.StatusNotFound, "not found"); writeErr != nil {
+			logger.Error("error while writing error response", slog.Any("error", writeErr))
+		}
+		return
+	}
+
+	output, err := h.Redeliverer.Redeliver(logger, recordID)
+	if err != nil {
+		statusCode := mapRedeliverError(err)
+		if statusCode == http.StatusInternalServerError {
+			logger.Error("Error processing redeliver request", slog.Any("error", err))
+		}
+		if writeErr := utils.WriteErrorResponse(w, statusCode, err.Error()); writeErr != nil {
+			logger.Error("error while writing error message", slog.Any("error", writeErr))
+		}
+		return
+	}
+
+	if len(output) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if output[0].Skipped != "" {
+		// launched actions go first, so nothing was launched
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	err = json.NewEncoder(w).Encode(output)
+	if err != nil {
+		logger.Error("Error writing output", slog.Any("error", err))
+	}
+}
+
+func mapRedeliverError(err error) int {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, webhook.ErrPayloadTruncated),
+		errors.Is(err, webhook.ErrUnknownProject),
+		errors.Is(err, webhook.ErrBadDelivery):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, webhook.ErrQueueFull):
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,88 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/religiosa1/git-webhook-receiver/internal/actionrunner"
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/http/api"
+	"github.com/religiosa1/git-webhook-receiver/internal/http/webhook"
+	"github.com/religiosa1/git-webhook-receiver/internal/requestmock"
+)
+
+func TestRedeliverDelivery(t *testing.T) {
+	dump := requestmock.LoadRequestMock(t, "../../requestmock/captured-requests/gitea.json")
+	db := newTestActionDB(t)
+	seedDump := func(t *testing.T, payload []byte) string {
+		t.Helper()
+		recordID := ulid.Make().String()
+		err := db.CreateDeliveryRecord(actionsdb.DeliveryRecord{
+			RecordID:     recordID,
+			Project:      "myproject",
+			Headers:      dump.ToHTTPRequest("/").Header,
+			Payload:      payload,
+			PayloadSize:  len(dump.Body),
+			Verification: actionsdb.DeliveryVerificationPassed,
+			StatusCode:   http.StatusCreated,
+		}, 1000)
+		if err != nil {
+			t.Fatalf("seed delivery %s: %v", recordID, err)
+		}
+		return recordID
+	}
+	cfg := config.Config{Projects: map[string]config.Project{
+		"myproject": {
+			GitProvider: "gitea",
+			Repo:        "religiosa/staticus",
+			Actions: []config.Action{
+				{On: config.PatternList{"push"}, Branch: config.PatternList{"master"}, Script: "echo test"},
+			},
+		},
+	}}
+	queue := actionrunner.NewMemoryQueue(10)
+	handler := api.RedeliverDelivery{Redeliverer: webhook.Redeliverer{Queue: queue, Config: cfg, DB: db}}
+
+	doRequest := func(t *testing.T, recordID string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/deliveries/"+recordID+"/redeliver", nil)
+		req.SetPathValue("deliveryId", recordID)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("returns 201 and queues the actions", func(t *testing.T) {
+		recordID := seedDump(t, []byte(dump.Body))
+		if got := doRequest(t, recordID); got != http.StatusCreated {
+			t.Fatalf("status: want %d, got %d", http.StatusCreated, got)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		args, ok := queue.Pop(ctx, actionrunner.Oldest)
+		if !ok {
+			t.Fatal("expected an action to be queued")
+		}
+		if !args.Replay || args.DeliveryRecordID != recordID {
+			t.Errorf("unexpected queued action: replay %t, delivery record id %q", args.Replay, args.DeliveryRecordID)
+		}
+	})
+
+	t.Run("returns 422 for truncated payloads", func(t *testing.T) {
+		recordID := seedDump(t, []byte(dump.Body[:10]))
+		if got := doRequest(t, recordID); got != http.StatusUnprocessableEntity {
+			t.Errorf("status: want %d, got %d", http.StatusUnprocessableEntity, got)
+		}
+	})
+
+	t.Run("returns 404 for non-existent id", func(t *testing.T) {
+		if got := doRequest(t, "nosuchid"); got != http.StatusNotFound {
+			t.Errorf("status: want %d, got %d", http.StatusNotFound, got)
+		}
+	})
+}
@@ -18,14 +18,14 @@ func newTestActionDB(t *testing.T) *actionsdb.ActionDB {
 }
 
 var testAction = config.Action{
-	On:     "push",
-	Branch: "main",
+	On:     config.PatternList{"push"},
+	Branch: config.PatternList{"main"},
 	Script: "echo test",
 }
 
 func seedActionDBRecord(t *testing.T, db *actionsdb.ActionDB, pipeID, project, hash, deliveryID string) {
 	t.Helper()
-	if err := db.CreateRecord(pipeID, project, deliveryID, hash, testAction); err != nil {
+	if err := db.CreateRecord(pipeID, project, deliveryID, "", hash, false, nil, testAction); err != nil {
 		t.Fatalf("seed record %s: %v", pipeID, err)
 	}
 }
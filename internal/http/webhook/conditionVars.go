@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/jsonpointer"
+	"github.com/religiosa1/git-webhook-receiver/internal/whreceiver"
+)
+
+// conditionVars lazily builds the variables of the actions' `if` conditions,
+// so the payload is only parsed if some of the actions has a condition.
+type conditionVars struct {
+	req  whreceiver.WebhookPostRequest
+	info *whreceiver.WebhookPostInfo
+	vars map[string]any
+}
+
+func (c *conditionVars) get(logger *slog.Logger) map[string]any {
+	if c.vars != nil {
+		return c.vars
+	}
+	var payload any
+	jsonPayload, err := whreceiver.JSONPayload(c.req)
+	if err == nil {
+		payload, err = jsonpointer.Unmarshal(jsonPayload)
+	}
+	if err != nil {
+		logger.Warn("Unable to parse the payload for action conditions, it's considered null", slog.Any("error", err))
+	}
+	headers := make(map[string]any, len(c.req.Headers))
+	for name := range c.req.Headers {
+		headers[strings.ToLower(name)] = c.req.Headers.Get(name)
+	}
+	c.vars = map[string]any{
+		config.ConditionVarPayload: payload,
+		config.ConditionVarHeaders: headers,
+		config.ConditionVarWebhook: webhookInfoToVars(c.info),
+	}
+	return c.vars
+}
+
+// webhookInfoToVars converts webhook info to the generic JSON form, with the
+// same naming as in the inspection API.
+func webhookInfoToVars(info *whreceiver.WebhookPostInfo) map[string]any {
+	vars := map[string]any{
+		"deliveryId":   info.DeliveryID,
+		"event":        info.Event,
+		"action":       info.Action,
+		"branch":       info.Branch,
+		"tag":          info.Tag,
+		"hash":         info.Hash,
+		"pullRequest":  nil,
+		"release":      nil,
+		"push":         nil,
+		"changedFiles": nil,
+	}
+	if pr := info.PullRequest; pr != nil {
+		vars["pullRequest"] = map[string]any{
+			"number":     pr.Number,
+			"title":      pr.Title,
+			"author":     pr.Author,
+			"headBranch": pr.HeadBranch,
+			"baseBranch": pr.BaseBranch,
+			"headHash":   pr.HeadHash,
+		}
+	}
+	if release := info.Release; release != nil {
+		vars["release"] = map[string]any{
+			"tag":        release.Tag,
+			"name":       release.Name,
+			"prerelease": release.Prerelease,
+			"draft":      release.Draft,
+		}
+	}
+	if push := info.Push; push != nil {
+		vars["push"] = map[string]any{
+			"pusher":        push.Pusher,
+			"before":        push.Before,
+			"commitMessage": push.CommitMessage,
+			"commitCount":   push.CommitCount,
+			"compareUrl":    push.CompareURL,
+			"created":       push.Created,
+			"deleted":       push.Deleted,
+			"forced":        push.Forced,
+		}
+	}
+	if info.ChangedFiles != nil {
+		changedFiles := make([]any, len(info.ChangedFiles))
+		for i, file := range info.ChangedFiles {
+			changedFiles[i] = file
+		}
+		vars["changedFiles"] = changedFiles
+	}
+	return vars
+}
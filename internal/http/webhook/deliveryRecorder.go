@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+)
+
+// DeliveryRecorder stores the received deliveries, so they can be inspected
+// later.
+type DeliveryRecorder interface {
+	Record(record actionsdb.DeliveryRecord) error
+}
+
+// NewDBDeliveryRecorder creates a DeliveryRecorder persisted in the actions
+// DB, keeping up to maxStored most recent deliveries.
+func NewDBDeliveryRecorder(db *actionsdb.ActionDB, maxStored int) DeliveryRecorder {
+	return dbDeliveryRecorder{db: db, maxStored: maxStored}
+}
+
+type dbDeliveryRecorder struct {
+	db        *actionsdb.ActionDB
+	maxStored int
+}
+
+func (r dbDeliveryRecorder) Record(record actionsdb.DeliveryRecord) error {
+	return r.db.CreateDeliveryRecord(record, r.maxStored)
+}
+
+const maskedHeaderValue = "********"
+
+// sensitiveHeaderParts are substrings of header names, which values must not
+// be stored, e.g. "Authorization", "X-Hub-Signature-256" or "X-Gitlab-Token".
+var sensitiveHeaderParts = []string{"authorization", "signature", "token", "secret", "cookie"}
+
+// maskDeliveryHeaders returns a copy of the headers with the values of
+// authorization and signature headers masked. Custom provider's authorization
+// and signature headers are masked regardless of their names.
+func maskDeliveryHeaders(headers http.Header, customProvider config.CustomProvider) http.Header {
+	masked := make(http.Header, len(headers))
+	for name, values := range headers {
+		if isSensitiveHeader(name, customProvider) {
+			maskedValues := make([]string, len(values))
+			for i := range maskedValues {
+				maskedValues[i] = maskedHeaderValue
+			}
+			masked[name] = maskedValues
+		} else {
+			masked[name] = append([]string(nil), values...)
+		}
+	}
+	return masked
+}
+
+func isSensitiveHeader(name string, customProvider config.CustomProvider) bool {
+	if customProvider.AuthorizationHeader != "" && strings.EqualFold(name, customProvider.AuthorizationHeader) {
+		return true
+	}
+	if customProvider.SignatureHeader != "" && strings.EqualFold(name, customProvider.SignatureHeader) {
+		return true
+	}
+	lowerName := strings.ToLower(name)
+	for _, part := range sensitiveHeaderParts {
+		if strings.Contains(lowerName, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// statusRecorder captures the status code written by the handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Status returns the written status code, 200 if nothing was written.
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
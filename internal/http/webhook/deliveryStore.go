@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"sync"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+)
+
+// DeliveryStore keeps the keys of the recently seen deliveries of the
+// projects, to reject retried or replayed deliveries. The key is the signed
+// delivery ID or the payload hash, see [Webhook.deliveryKey].
+type DeliveryStore interface {
+	// MarkSeen stores the delivery key and reports if it was already stored.
+	MarkSeen(project, deliveryKey string) (seen bool, err error)
+	// Forget removes the delivery key, so the delivery can be retried.
+	Forget(project, deliveryKey string) error
+}
+
+// NewDBDeliveryStore creates a DeliveryStore persisted in the actions DB,
+// keeping up to maxStored deliveries per project.
+func NewDBDeliveryStore(db *actionsdb.ActionDB, maxStored int) DeliveryStore {
+	return dbDeliveryStore{db: db, maxStored: maxStored}
+}
+
+type dbDeliveryStore struct {
+	db        *actionsdb.ActionDB
+	maxStored int
+}
+
+func (s dbDeliveryStore) MarkSeen(project, deliveryKey string) (bool, error) {
+	return s.db.MarkDeliverySeen(project, deliveryKey, s.maxStored)
+}
+
+func (s dbDeliveryStore) Forget(project, deliveryKey string) error {
+	return s.db.ForgetDelivery(project, deliveryKey)
+}
+
+// NewMemoryDeliveryStore creates an in-memory DeliveryStore, for when the
+// actions DB is disabled, keeping up to maxStored deliveries per project.
+// Seen deliveries are lost on restart.
+func NewMemoryDeliveryStore(maxStored int) DeliveryStore {
+	return &memoryDeliveryStore{
+		maxStored: maxStored,
+		projects:  make(map[string]*seenDeliveries),
+	}
+}
+
+type memoryDeliveryStore struct {
+	mu        sync.Mutex
+	maxStored int
+	projects  map[string]*seenDeliveries
+}
+
+type seenDeliveries struct {
+	ids map[string]struct{}
+	// order is the insertion order of ids, oldest first
+	order []string
+}
+
+func (s *memoryDeliveryStore) MarkSeen(project, deliveryKey string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen, ok := s.projects[project]
+	if !ok {
+		seen = &seenDeliveries{ids: make(map[string]struct{})}
+		s.projects[project] = seen
+	}
+	if _, ok := seen.ids[deliveryKey]; ok {
+		return true, nil
+	}
+	seen.ids[deliveryKey] = struct{}{}
+	seen.order = append(seen.order, deliveryKey)
+	for s.maxStored > 0 && len(seen.order) > s.maxStored {
+		delete(seen.ids, seen.order[0])
+		seen.order = seen.order[1:]
+	}
+	return false, nil
+}
+
+func (s *memoryDeliveryStore) Forget(project, deliveryKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen, ok := s.projects[project]
+	if !ok {
+		return nil
+	}
+	if _, ok := seen.ids[deliveryKey]; !ok {
+		return nil
+	}
+	delete(seen.ids, deliveryKey)
+	for i, id := range seen.order {
+		if id == deliveryKey {
+			seen.order = append(seen.order[:i], seen.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package webhook_test
+
+import (
+	"testing"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+	"github.com/religiosa1/git-webhook-receiver/internal/http/webhook"
+)
+
+func TestDeliveryStores(t *testing.T) {
+	stores := []struct {
+		name string
+		new  func(t *testing.T, maxStored int) webhook.DeliveryStore
+	}{
+		{"memory", func(t *testing.T, maxStored int) webhook.DeliveryStore {
+			return webhook.NewMemoryDeliveryStore(maxStored)
+		}},
+		{"db", func(t *testing.T, maxStored int) webhook.DeliveryStore {
+			db, err := actionsdb.New(":memory:", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = db.Close() })
+			return webhook.NewDBDeliveryStore(db, maxStored)
+		}},
+	}
+
+	for _, store := range stores {
+		t.Run(store.name, func(t *testing.T) {
+			assertSeen := func(t *testing.T, s webhook.DeliveryStore, project, deliveryID string, want bool) {
+				t.Helper()
+				got, err := s.MarkSeen(project, deliveryID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("MarkSeen(%q, %q) = %t, want %t", project, deliveryID, got, want)
+				}
+			}
+
+			t.Run("reports seen deliveries", func(t *testing.T) {
+				s := store.new(t, 10)
+				assertSeen(t, s, "proj", "1", false)
+				assertSeen(t, s, "proj", "1", true)
+				assertSeen(t, s, "proj", "2", false)
+			})
+
+			t.Run("deliveries are per project", func(t *testing.T) {
+				s := store.new(t, 10)
+				assertSeen(t, s, "proj", "1", false)
+				assertSeen(t, s, "other", "1", false)
+			})
+
+			t.Run("keeps only max stored deliveries", func(t *testing.T) {
+				s := store.new(t, 2)
+				assertSeen(t, s, "proj", "1", false)
+				assertSeen(t, s, "proj", "2", false)
+				assertSeen(t, s, "proj", "3", false)
+				assertSeen(t, s, "proj", "3", true)
+				assertSeen(t, s, "proj", "1", false)
+			})
+
+			t.Run("forgotten delivery isn't seen", func(t *testing.T) {
+				s := store.new(t, 10)
+				assertSeen(t, s, "proj", "1", false)
+				if err := s.Forget("proj", "1"); err != nil {
+					t.Fatal(err)
+				}
+				assertSeen(t, s, "proj", "1", false)
+			})
+		})
+	}
+}
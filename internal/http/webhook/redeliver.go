@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/actionrunner"
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/whreceiver"
+)
+
+var (
+	// ErrPayloadTruncated is returned for deliveries, which payload was bigger
+	// than max_delivery_payload_bytes, so it wasn't stored completely.
+	ErrPayloadTruncated = errors.New("delivery payload was truncated when stored, it can't be redelivered")
+	// ErrUnknownProject is returned for deliveries of projects, that are no
+	// longer in the config.
+	ErrUnknownProject = errors.New("delivery project is not in the config")
+	// ErrBadDelivery is returned if the stored delivery can't be processed by
+	// the project's receiver, e.g. the project repo was changed, or if it
+	// failed the authorization or signature verification when received.
+	ErrBadDelivery = errors.New("unable to process the stored delivery")
+	// ErrQueueFull is returned if the action runner is at full queue capacity.
+	ErrQueueFull = actionrunner.ErrQueueFull
+)
+
+// Redeliverer replays the stored deliveries against the current config,
+// starting fresh pipelines marked as replays and linked to the original
+// delivery.
+type Redeliverer struct {
+	Queue  actionrunner.Queue
+	Config config.Config
+	DB     *actionsdb.ActionDB
+}
+
+// Redeliver matches the actions of the stored delivery and queues them. The
+// delivery isn't verified again, neither it's checked for duplicates, so only
+// the deliveries, which passed the verification when received (or didn't
+// require one), are redelivered.
+//
+// Output is the same as the webhook response: launched actions, followed by
+// the ones skipped by commit message markers. On ErrQueueFull the actions
+// queued before the error are still returned.
+func (r Redeliverer) Redeliver(logger *slog.Logger, recordID string) ([]ActionOutput, error) {
+	delivery, err := r.DB.GetDeliveryRecord(recordID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting the delivery %s: %w", recordID, err)
+	}
+	switch delivery.Verification {
+	case actionsdb.DeliveryVerificationPassed, actionsdb.DeliveryVerificationNone:
+	default:
+		return nil, fmt.Errorf("%w: delivery verification is %q", ErrBadDelivery, delivery.Verification)
+	}
+	if delivery.IsPayloadTruncated() {
+		return nil, ErrPayloadTruncated
+	}
+	project, ok := r.Config.Projects[delivery.Project]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProject, delivery.Project)
+	}
+	receiver := whreceiver.New(project)
+	if receiver == nil {
+		return nil, fmt.Errorf("%w: unknown git webhook provider type %q", ErrBadDelivery, project.GitProvider)
+	}
+
+	whReq := whreceiver.WebhookPostRequest{Payload: delivery.Payload, Headers: delivery.Headers}
+	webhookInfo, err := receiver.GetWebhookInfo(whReq)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadDelivery, err)
+	}
+	deliveryLogger := logger.With(
+		slog.String("project", delivery.Project),
+		slog.String("deliveryId", webhookInfo.DeliveryID),
+		slog.String("deliveryRecordId", recordID),
+	)
+	deliveryLogger.Info("Redelivering a stored webhook post")
+
+	actions := getProjectsActionsForWebhookPost(deliveryLogger, delivery.Project, project, whReq, webhookInfo)
+	actions, skipped := splitSkippedActions(deliveryLogger, actions, webhookInfo)
+	jsonPayload := getJSONPayload(deliveryLogger, whReq)
+	for i, actionDesc := range actions {
+		actionLogger := deliveryLogger.With(slog.Any("action", actionDesc.ActionIdentifier))
+		args := newActionArgs(actionLogger, actionDesc, webhookInfo, jsonPayload, recordID)
+		args.Replay = true
+		if err := r.Queue.Push(args); err != nil {
+			actionLogger.Error("Unable to queue the redelivered action", slog.Any("error", err))
+			return actionsToOutput(r.Config, actions[:i], nil), err
+		}
+		actionLogger.Info("Queued redelivered action")
+	}
+	return actionsToOutput(r.Config, actions, skipped), nil
+}
@@ -0,0 +1,151 @@
+package webhook_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/actionrunner"
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/http/webhook"
+	"github.com/religiosa1/git-webhook-receiver/internal/requestmock"
+)
+
+func TestRedeliver(t *testing.T) {
+	requestDump := loadMockRequest(t)
+	prj := config.Project{
+		GitProvider: "gitea",
+		Repo:        "religiosa/staticus",
+		Secret:      config.SecretList{config.Secret(secret)},
+		Actions:     makeActionsList(config.Action{}),
+	}
+	cfg := config.Config{Projects: map[string]config.Project{projectName: prj}}
+	logger := slog.New(slog.DiscardHandler)
+
+	// receives the delivery with the webhook handler, returning its record id
+	setupRequest := func(t *testing.T, cfg config.Config, requestDump requestmock.RequestMock) (*actionsdb.ActionDB, string) {
+		t.Helper()
+		db, err := actionsdb.New(":memory:", 100)
+		if err != nil {
+			t.Fatalf("Unable to create a db: %s", err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+		h := newTestHandler(cfg, prj)
+		h.Recorder = webhook.NewDBDeliveryRecorder(db, 100)
+		h.ServeHTTP(httptest.NewRecorder(), requestDump.ToHTTPRequest(projectEndPoint))
+		page, err := db.ListDeliveryRecords(actionsdb.ListDeliveryRecordsQuery{})
+		if err != nil || len(page.Items) != 1 {
+			t.Fatalf("Expected the delivery to be recorded, got %v, %v", page.Items, err)
+		}
+		return db, page.Items[0].RecordID
+	}
+	setup := func(t *testing.T, cfg config.Config) (*actionsdb.ActionDB, string) {
+		t.Helper()
+		return setupRequest(t, cfg, requestDump)
+	}
+
+	t.Run("queues the matched actions as replays", func(t *testing.T) {
+		db, recordID := setup(t, cfg)
+		queue := actionrunner.NewMemoryQueue(10)
+		r := webhook.Redeliverer{Queue: queue, Config: cfg, DB: db}
+
+		output, err := r.Redeliver(logger, recordID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if len(output) != 1 || output[0].PipeID == "" {
+			t.Fatalf("Expected one launched action, got %+v", output)
+		}
+		args := popQueued(t, queue)
+		if !args.Replay {
+			t.Error("Expected the action to be marked as a replay")
+		}
+		if args.DeliveryRecordID != recordID {
+			t.Errorf("Unexpected delivery record id, want %q, got %q", recordID, args.DeliveryRecordID)
+		}
+		if args.ActionDesc.PipeID != output[0].PipeID {
+			t.Errorf("Unexpected pipe id, want %q, got %q", output[0].PipeID, args.ActionDesc.PipeID)
+		}
+		if args.Branch != "master" {
+			t.Errorf("Unexpected branch, want %q, got %q", "master", args.Branch)
+		}
+	})
+
+	t.Run("uses the current config", func(t *testing.T) {
+		db, recordID := setup(t, cfg)
+		changedPrj := prj
+		changedPrj.Actions = makeActionsList(config.Action{Branch: config.PatternList{"main"}})
+		changedCfg := config.Config{Projects: map[string]config.Project{projectName: changedPrj}}
+		r := webhook.Redeliverer{Queue: actionrunner.NewMemoryQueue(10), Config: changedCfg, DB: db}
+
+		output, err := r.Redeliver(logger, recordID)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if len(output) != 0 {
+			t.Errorf("Expected no actions to match, got %+v", output)
+		}
+	})
+
+	t.Run("rejects truncated payloads", func(t *testing.T) {
+		truncatingCfg := cfg
+		truncatingCfg.MaxDeliveryPayloadBytes = 10
+		db, recordID := setup(t, truncatingCfg)
+		r := webhook.Redeliverer{Queue: actionrunner.NewMemoryQueue(10), Config: cfg, DB: db}
+
+		if _, err := r.Redeliver(logger, recordID); !errors.Is(err, webhook.ErrPayloadTruncated) {
+			t.Errorf("Expected ErrPayloadTruncated, got %v", err)
+		}
+	})
+
+	t.Run("rejects deliveries which failed the verification", func(t *testing.T) {
+		forgedDump := requestDump
+		forgedDump.Headers = maps.Clone(requestDump.Headers)
+		forgedDump.Headers["x-gitea-signature"] = "bad signature"
+		db, recordID := setupRequest(t, cfg, forgedDump)
+		queue := actionrunner.NewMemoryQueue(10)
+		r := webhook.Redeliverer{Queue: queue, Config: cfg, DB: db}
+
+		if _, err := r.Redeliver(logger, recordID); !errors.Is(err, webhook.ErrBadDelivery) {
+			t.Errorf("Expected ErrBadDelivery, got %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, ok := queue.Pop(ctx, actionrunner.Oldest); ok {
+			t.Error("Expected no actions to be queued")
+		}
+	})
+
+	t.Run("rejects deliveries of removed projects", func(t *testing.T) {
+		db, recordID := setup(t, cfg)
+		r := webhook.Redeliverer{Queue: actionrunner.NewMemoryQueue(10), Config: config.Config{}, DB: db}
+
+		if _, err := r.Redeliver(logger, recordID); !errors.Is(err, webhook.ErrUnknownProject) {
+			t.Errorf("Expected ErrUnknownProject, got %v", err)
+		}
+	})
+
+	t.Run("returns sql.ErrNoRows for unknown deliveries", func(t *testing.T) {
+		db, _ := setup(t, cfg)
+		r := webhook.Redeliverer{Queue: actionrunner.NewMemoryQueue(10), Config: cfg, DB: db}
+
+		if _, err := r.Redeliver(logger, "nosuchid"); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("Expected sql.ErrNoRows, got %v", err)
+		}
+	})
+
+	t.Run("reports full queue", func(t *testing.T) {
+		db, recordID := setup(t, cfg)
+		r := webhook.Redeliverer{Queue: actionrunner.NewMemoryQueue(0), Config: cfg, DB: db}
+
+		if _, err := r.Redeliver(logger, recordID); !errors.Is(err, webhook.ErrQueueFull) {
+			t.Errorf("Expected ErrQueueFull, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/actionrunner"
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/whreceiver"
+)
+
+// skippedAction is a matched action, which isn't run because of a skip marker
+// in the pushed commits.
+type skippedAction struct {
+	actionrunner.ActionDescriptor
+	Marker string
+}
+
+// splitSkippedActions separates the actions skipped by a commit message marker
+// from the ones to run.
+func splitSkippedActions(
+	logger *slog.Logger,
+	actions []actionrunner.ActionDescriptor,
+	webhookInfo *whreceiver.WebhookPostInfo,
+) (run []actionrunner.ActionDescriptor, skipped []skippedAction) {
+	run = make([]actionrunner.ActionDescriptor, 0, len(actions))
+	for _, action := range actions {
+		actionLogger := logger.With(slog.Int("action_index", action.Index))
+		if marker := findSkipMarker(actionLogger, action.Config, webhookInfo); marker != "" {
+			actionLogger.Info("Action is skipped by a commit message marker", slog.String("marker", marker))
+			skipped = append(skipped, skippedAction{action, marker})
+			continue
+		}
+		run = append(run, action)
+	}
+	return run, skipped
+}
+
+// findSkipMarker returns the action's skip marker found in the pushed commit
+// messages, or an empty string if the action shouldn't be skipped.
+//
+// Markers aren't looked for in tag pushes, as the tagged commit is usually an
+// older one, and in events without the commit messages. If all of the commits
+// must have a marker, but the provider truncated the commits list, we can't
+// check them all, so the action isn't skipped.
+func findSkipMarker(logger *slog.Logger, action config.Action, webhookInfo *whreceiver.WebhookPostInfo) string {
+	if len(action.SkipMarkers) == 0 || webhookInfo.Tag != "" {
+		return ""
+	}
+	if action.SkipMarkersIn != config.SkipMarkersInAll {
+		if webhookInfo.Push != nil && webhookInfo.Push.CommitMessage != "" {
+			return findMarkerInMessage(action.SkipMarkers, webhookInfo.Push.CommitMessage)
+		}
+		if n := len(webhookInfo.CommitMessages); n > 0 {
+			return findMarkerInMessage(action.SkipMarkers, webhookInfo.CommitMessages[n-1])
+		}
+		return ""
+	}
+	if len(webhookInfo.CommitMessages) == 0 {
+		return ""
+	}
+	var marker string
+	for _, message := range webhookInfo.CommitMessages {
+		marker = findMarkerInMessage(action.SkipMarkers, message)
+		if marker == "" {
+			return ""
+		}
+	}
+	if webhookInfo.ChangedFilesTruncated {
+		logger.Info("Commits list of the webhook post is truncated, skip markers can't be checked in all of the commits")
+		return ""
+	}
+	return marker
+}
+
+// findMarkerInMessage returns the first of the markers contained in the
+// message, ignoring case.
+func findMarkerInMessage(markers []string, message string) string {
+	message = strings.ToLower(message)
+	for _, marker := range markers {
+		if strings.Contains(message, strings.ToLower(marker)) {
+			return marker
+		}
+	}
+	return ""
+}
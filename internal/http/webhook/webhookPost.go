@@ -1,35 +1,65 @@
 package webhook
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"path"
 
 	"github.com/oklog/ulid/v2"
 	"github.com/religiosa1/git-webhook-receiver/internal/actionrunner"
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
 	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/cryptoutils"
 	"github.com/religiosa1/git-webhook-receiver/internal/http/middleware"
 	"github.com/religiosa1/git-webhook-receiver/internal/http/utils"
+	"github.com/religiosa1/git-webhook-receiver/internal/pathglob"
 	"github.com/religiosa1/git-webhook-receiver/internal/whreceiver"
 )
 
 // 300 KiB max body size
 const maxBodySize int64 = 1024 * 300
 
+// RedeliveryTokenHeader is the header to supply the configured redelivery
+// token in, to intentionally redeliver an already received webhook.
+const RedeliveryTokenHeader = "X-Redelivery-Token"
+
 type Webhook struct {
-	ActionsCh   chan<- actionrunner.ActionArgs
+	Queue       actionrunner.Queue
 	Config      config.Config
 	ProjectName string
 	Project     config.Project
 	Receiver    whreceiver.Receiver
+	// Deliveries is used to reject duplicate deliveries; nil disables the check
+	Deliveries DeliveryStore
+	// Recorder stores every received request with its outcome; nil disables
+	// the recording
+	Recorder DeliveryRecorder
 }
 
 func (h Webhook) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	logger := middleware.GetLogger(req.Context())
 	req.Body = http.MaxBytesReader(w, req.Body, maxBodySize)
+
+	delivery := actionsdb.DeliveryRecord{
+		Project:      h.ProjectName,
+		Verification: actionsdb.DeliveryVerificationUnchecked,
+	}
+	if h.Recorder != nil {
+		delivery.RecordID = ulid.Make().String()
+		recorder := &statusRecorder{ResponseWriter: w}
+		w = recorder
+		defer func() {
+			delivery.StatusCode = recorder.Status()
+			h.recordDelivery(logger, req, delivery)
+		}()
+	}
+
 	// setting noop-closer body, so we can read it multiple times
 	payload, err := io.ReadAll(req.Body)
 	if _, ok := errors.AsType[*http.MaxBytesError](err); ok {
@@ -41,6 +71,8 @@ func (h Webhook) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	delivery.Payload = payload
+	delivery.PayloadSize = len(payload)
 	whReq := whreceiver.WebhookPostRequest{Payload: payload, Headers: req.Header}
 
 	webhookInfo, err := h.Receiver.GetWebhookInfo(whReq)
@@ -52,26 +84,41 @@ func (h Webhook) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		}
 		return
 	}
+	delivery.DeliveryID = webhookInfo.DeliveryID
+	delivery.Event = webhookInfo.Event
 	deliveryLogger := logger.With(slog.String("deliveryId", webhookInfo.DeliveryID))
 	deliveryLogger.Info("Received a webhook post", slog.Any("webhookInfo", webhookInfo))
 	if webhookInfo.Branch == "" {
 		deliveryLogger.Info("no branch name captured out of the payload ref")
 	}
 
+	// Index of the matched key is logged, so it's visible when an old one is
+	// no longer in use and can be removed after the rotation.
 	if !h.Project.Authorization.IsZero() {
-		if authed, err := h.Receiver.Authorize(whReq, h.Project.Authorization.RawContents()); err != nil || !authed {
+		idx, err := whreceiver.AuthorizeAny(h.Receiver, whReq, h.Project.Authorization.RawContents())
+		if err != nil || idx == -1 {
 			deliveryLogger.Warn("Request authentications failed", slog.Any("error", err))
+			delivery.Verification = actionsdb.DeliveryVerificationAuthorizationFailed
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
+		deliveryLogger = deliveryLogger.With(slog.Int("authorizationIndex", idx))
 	}
 
 	if !h.Project.Secret.IsZero() {
-		if verified, err := h.Receiver.VerifySignature(whReq, h.Project.Secret.RawContents()); err != nil || !verified {
+		idx, err := whreceiver.VerifySignatureAny(h.Receiver, whReq, h.Project.Secret.RawContents())
+		if err != nil || idx == -1 {
 			deliveryLogger.Warn("Request signature is not valid", slog.Any("error", err))
+			delivery.Verification = actionsdb.DeliveryVerificationSignatureFailed
 			w.WriteHeader(http.StatusForbidden)
 			return
 		}
+		deliveryLogger = deliveryLogger.With(slog.Int("secretIndex", idx))
+	}
+	if h.Project.Authorization.IsZero() && h.Project.Secret.IsZero() {
+		delivery.Verification = actionsdb.DeliveryVerificationNone
+	} else {
+		delivery.Verification = actionsdb.DeliveryVerificationPassed
 	}
 
 	if h.Receiver.IsPingRequest(whReq) {
@@ -79,44 +126,164 @@ func (h Webhook) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	actions := getProjectsActionsForWebhookPost(h.ProjectName, h.Project, webhookInfo)
+	// Checked after the signature verification, so forged requests can't
+	// mark deliveries as seen.
+	deliveryKey := h.deliveryKey(webhookInfo, payload)
+	markedSeen, duplicate := h.markDeliverySeen(deliveryLogger, req, deliveryKey)
+	if duplicate {
+		deliveryLogger.Warn("Duplicate delivery rejected, it was already received")
+		if err := utils.WriteErrorResponse(w, http.StatusConflict, "Delivery "+webhookInfo.DeliveryID+" was already received"); err != nil {
+			deliveryLogger.Error("error while writing error message", slog.Any("error", err))
+		}
+		return
+	}
+
+	actions := getProjectsActionsForWebhookPost(deliveryLogger, h.ProjectName, h.Project, whReq, webhookInfo)
 	if len(actions) == 0 {
 		deliveryLogger.Info("No applicable actions found in webhook post")
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
+	actions, skipped := splitSkippedActions(deliveryLogger, actions, webhookInfo)
+	jsonPayload := getJSONPayload(deliveryLogger, whReq)
 
 	for _, actionDesc := range actions {
 		actionLogger := deliveryLogger.With(slog.Any("action", actionDesc.ActionIdentifier))
-		args := actionrunner.ActionArgs{
-			Logger:     actionLogger,
-			ActionDesc: actionDesc,
-			DeliveryID: webhookInfo.DeliveryID,
-			Hash:       webhookInfo.Hash,
-			Branch:     webhookInfo.Branch,
-			Event:      webhookInfo.Event,
-		}
-		select {
-		case h.ActionsCh <- args:
-			actionLogger.Info("Launched action")
-		default:
-			actionLogger.Error("Unable to queue the action, as action runner is at full queue capacity")
-			w.WriteHeader(http.StatusTooManyRequests)
-			// we're not accounting for partial success here, returning 429 on any blockage.
+		args := newActionArgs(actionLogger, actionDesc, webhookInfo, jsonPayload, delivery.RecordID)
+		if err := h.Queue.Push(args); err != nil {
+			if markedSeen {
+				// so the retry isn't rejected as a duplicate
+				h.forgetDelivery(deliveryLogger, deliveryKey)
+			}
+			if errors.Is(err, actionrunner.ErrQueueFull) {
+				actionLogger.Error("Unable to queue the action, as action runner is at full queue capacity")
+				w.WriteHeader(http.StatusTooManyRequests)
+			} else {
+				actionLogger.Error("Error while queuing the action", slog.Any("error", err))
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			// we're not accounting for partial success here, returning an error on any blockage.
 			// The idea is -- there will be a retry; trade-off is that successful actions
 			// will run twice on retries, than failed ones
 			return
 		}
+		actionLogger.Info("Queued action")
+		delivery.MatchedActions = append(delivery.MatchedActions, actionsdb.DeliveryAction{
+			Index:  actionDesc.Index,
+			PipeID: actionDesc.PipeID,
+		})
+	}
+
+	for _, action := range skipped {
+		delivery.MatchedActions = append(delivery.MatchedActions, actionsdb.DeliveryAction{
+			Index:   action.Index,
+			Skipped: action.Marker,
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	err = json.NewEncoder(w).Encode(actionsToOutput(h.Config, actions))
+	if len(actions) == 0 {
+		deliveryLogger.Info("All of the applicable actions are skipped by commit message markers")
+		// nothing was created, but the response still tells which actions were skipped
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	err = json.NewEncoder(w).Encode(actionsToOutput(h.Config, actions, skipped))
 	if err != nil {
 		deliveryLogger.Error("Error while encoding action's output", slog.Any("error", err))
 	}
 }
 
+func newActionArgs(
+	logger *slog.Logger,
+	actionDesc actionrunner.ActionDescriptor,
+	webhookInfo *whreceiver.WebhookPostInfo,
+	jsonPayload []byte,
+	deliveryRecordID string,
+) actionrunner.ActionArgs {
+	return actionrunner.ActionArgs{
+		Logger:           logger,
+		ActionDesc:       actionDesc,
+		DeliveryID:       webhookInfo.DeliveryID,
+		DeliveryRecordID: deliveryRecordID,
+		Hash:             webhookInfo.Hash,
+		Branch:           webhookInfo.Branch,
+		Tag:              webhookInfo.Tag,
+		Event:            webhookInfo.Event,
+		Action:           webhookInfo.Action,
+		PullRequest:      webhookInfo.PullRequest,
+		Release:          webhookInfo.Release,
+		Push:             webhookInfo.Push,
+		Payload:          jsonPayload,
+	}
+}
+
+// getJSONPayload extracts the JSON payload for the actions. On error actions
+// get no payload, failing only if they need it.
+func getJSONPayload(logger *slog.Logger, whReq whreceiver.WebhookPostRequest) []byte {
+	jsonPayload, err := whreceiver.JSONPayload(whReq)
+	if err != nil {
+		logger.Warn("Unable to extract the JSON payload for the actions", slog.Any("error", err))
+		return nil
+	}
+	return jsonPayload
+}
+
+// recordDelivery stores the delivery with masked headers and the payload capped
+// to the configured size. Errors are only logged, as the response is already
+// sent.
+func (h Webhook) recordDelivery(logger *slog.Logger, req *http.Request, delivery actionsdb.DeliveryRecord) {
+	delivery.Headers = maskDeliveryHeaders(req.Header, h.Project.CustomProvider)
+	if maxBytes := h.Config.MaxDeliveryPayloadBytes; maxBytes > 0 && len(delivery.Payload) > maxBytes {
+		delivery.Payload = delivery.Payload[:maxBytes]
+	}
+	if err := h.Recorder.Record(delivery); err != nil {
+		logger.Error("Error while recording the delivery", slog.Any("error", err))
+	}
+}
+
+// deliveryKey returns the key, the delivery is checked for duplicates by. It
+// has to be covered by the signature, as delivery ID headers aren't, and a
+// captured delivery could be replayed with a fresh one. So it's the signed
+// Standard Webhooks ID, or the payload hash otherwise.
+func (h Webhook) deliveryKey(webhookInfo *whreceiver.WebhookPostInfo, payload []byte) string {
+	if webhookInfo.SignedDeliveryID != "" && !h.Project.Secret.IsZero() {
+		return webhookInfo.SignedDeliveryID
+	}
+	sum := sha256.Sum256(payload)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// markDeliverySeen stores the delivery key in the delivery store, reporting if
+// it was stored and if it's a duplicate. Deliveries with a valid redelivery
+// token aren't checked. Store errors are logged, but the delivery is processed
+// as usual.
+func (h Webhook) markDeliverySeen(logger *slog.Logger, req *http.Request, deliveryKey string) (marked bool, duplicate bool) {
+	if h.Deliveries == nil {
+		return false, false
+	}
+	if token := req.Header.Get(RedeliveryTokenHeader); token != "" {
+		if !h.Config.RedeliveryToken.IsZero() && cryptoutils.NewConstantTimeComparer(h.Config.RedeliveryToken.RawContents()).Eq(token) {
+			logger.Info("Redelivery token supplied, duplicate check is skipped")
+			return false, false
+		}
+		logger.Warn("Invalid redelivery token supplied, ignoring it")
+	}
+	seen, err := h.Deliveries.MarkSeen(h.ProjectName, deliveryKey)
+	if err != nil {
+		logger.Error("Error while checking the delivery for duplicates", slog.Any("error", err))
+		return false, false
+	}
+	return !seen, seen
+}
+
+func (h Webhook) forgetDelivery(logger *slog.Logger, deliveryKey string) {
+	if err := h.Deliveries.Forget(h.ProjectName, deliveryKey); err != nil {
+		logger.Error("Error while removing the delivery from the seen ones", slog.Any("error", err))
+	}
+}
+
 type ErrorInfo struct {
 	StatusCode int
 	Message    string
@@ -133,16 +300,29 @@ func getWebhookErrorCode(err error) ErrorInfo {
 }
 
 func getProjectsActionsForWebhookPost(
+	logger *slog.Logger,
 	projectName string,
 	project config.Project,
+	whReq whreceiver.WebhookPostRequest,
 	webhookInfo *whreceiver.WebhookPostInfo,
 ) []actionrunner.ActionDescriptor {
 	actions := make([]actionrunner.ActionDescriptor, 0)
+	condVars := conditionVars{req: whReq, info: webhookInfo}
 	for index, action := range project.Actions {
-		if action.Branch != "*" && action.Branch != webhookInfo.Branch {
+		actionLogger := logger.With(slog.Int("action_index", index))
+		if !isActionRefMatching(action, webhookInfo) {
+			continue
+		}
+		if !isActionEventMatching(action, webhookInfo) {
+			continue
+		}
+		if !isActionReleaseMatching(action, webhookInfo) {
+			continue
+		}
+		if !isActionPathsMatching(actionLogger, action, webhookInfo) {
 			continue
 		}
-		if action.On != "*" && action.On != webhookInfo.Event {
+		if !isActionConditionMatching(actionLogger, action, &condVars) {
 			continue
 		}
 		actions = append(actions, actionrunner.ActionDescriptor{
@@ -159,18 +339,167 @@ func getProjectsActionsForWebhookPost(
 	return actions
 }
 
+// isActionEventMatching checks the action's `on` patterns against the
+// webhook event. A pattern can match either the plain event name, so it
+// matches any of its subtypes, or "event.action", e.g. "pull_request.opened",
+// to match only the specified one.
+//
+// Pushes creating a ref also match "branch_create" or "tag_create". Pushes
+// deleting a ref only match "branch_delete" or "tag_delete", as there's
+// nothing to deploy.
+func isActionEventMatching(action config.Action, webhookInfo *whreceiver.WebhookPostInfo) bool {
+	events := eventNames(webhookInfo)
+	if push := webhookInfo.Push; push != nil && (push.Created || push.Deleted) {
+		refType := "branch"
+		if webhookInfo.Tag != "" {
+			refType = "tag"
+		}
+		if push.Deleted {
+			events = []string{refType + "_delete"}
+		} else {
+			events = append(events, refType+"_create")
+		}
+	}
+	return action.MatchOn(events...)
+}
+
+// eventNames returns the names of the webhook event, `on` patterns are
+// matched against: the event, its aliases, and both of them with the action.
+func eventNames(webhookInfo *whreceiver.WebhookPostInfo) []string {
+	var events []string
+	for _, name := range append([]string{webhookInfo.Event}, webhookInfo.EventAliases...) {
+		events = append(events, name)
+		if webhookInfo.Action != "" {
+			events = append(events, name+"."+webhookInfo.Action)
+		}
+	}
+	return events
+}
+
+// isActionRefMatching checks the action's branch patterns, or its tag pattern
+// for tag actions, against the webhook ref.
+func isActionRefMatching(action config.Action, webhookInfo *whreceiver.WebhookPostInfo) bool {
+	if action.Tag != "" {
+		if webhookInfo.Tag == "" {
+			return false
+		}
+		// pattern is validated on config load
+		matched, _ := path.Match(action.Tag, webhookInfo.Tag)
+		return matched
+	}
+	// pull requests don't have a branch of their own, so the base branch is
+	// matched instead. But only for the actions meant for pull requests, so
+	// the catch-all ones don't run on the head commits of untrusted forks.
+	if pr := webhookInfo.PullRequest; pr != nil {
+		return action.MatchOnExplicitly(eventNames(webhookInfo)...) && action.MatchBranch(pr.BaseBranch)
+	}
+	// release actions have no branch patterns
+	if len(action.Branch) == 0 {
+		return webhookInfo.Branch == ""
+	}
+	return action.MatchBranch(webhookInfo.Branch)
+}
+
+// isActionReleaseMatching checks the action's release_type filter. Actions
+// with release_type only match release events.
+func isActionReleaseMatching(action config.Action, webhookInfo *whreceiver.WebhookPostInfo) bool {
+	if action.ReleaseType == "" {
+		return true
+	}
+	release := webhookInfo.Release
+	if release == nil {
+		return false
+	}
+	switch action.ReleaseType {
+	case config.ReleaseTypeDraft:
+		return release.Draft
+	case config.ReleaseTypePrerelease:
+		return !release.Draft && release.Prerelease
+	default:
+		return !release.Draft && !release.Prerelease
+	}
+}
+
+// isActionPathsMatching checks the action's paths and paths_ignore filters
+// against the files changed by a push: the action matches, if any of the files
+// matches paths (or there's no paths) and doesn't match paths_ignore.
+//
+// Filters aren't applied to tag pushes and events without the changed files
+// list. If the commits list was truncated by the provider, the list is
+// incomplete, so we're playing it safe and match the action.
+func isActionPathsMatching(logger *slog.Logger, action config.Action, webhookInfo *whreceiver.WebhookPostInfo) bool {
+	if len(action.Paths) == 0 && len(action.PathsIgnore) == 0 {
+		return true
+	}
+	if webhookInfo.ChangedFiles == nil || webhookInfo.Tag != "" {
+		logger.Debug("No changed files list in the webhook post, paths filters are not applied")
+		return true
+	}
+	if webhookInfo.ChangedFilesTruncated {
+		logger.Info("Commits list of the webhook post is truncated, matching the action regardless of its paths filters")
+		return true
+	}
+	for _, file := range webhookInfo.ChangedFiles {
+		if (len(action.Paths) == 0 || isAnyPathMatching(action.Paths, file)) &&
+			!isAnyPathMatching(action.PathsIgnore, file) {
+			return true
+		}
+	}
+	return false
+}
+
+// isActionConditionMatching evaluates the action's `if` condition. The result
+// is logged, so it's possible to see why an action didn't run.
+func isActionConditionMatching(logger *slog.Logger, action config.Action, condVars *conditionVars) bool {
+	condition, err := action.Condition()
+	if err != nil {
+		logger.Error("Malformed action condition", slog.String("if", action.If), slog.Any("error", err))
+		return false
+	}
+	if condition == nil {
+		return true
+	}
+	result, err := condition.EvalBool(condVars.get(logger))
+	if err != nil {
+		logger.Warn("Error evaluating the action condition, action is skipped", slog.String("if", action.If), slog.Any("error", err))
+		return false
+	}
+	logger.Info("Evaluated the action condition", slog.String("if", action.If), slog.Bool("result", result))
+	return result
+}
+
+func isAnyPathMatching(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		// patterns are validated on config load
+		if matched, _ := pathglob.Match(pattern, file); matched {
+			return true
+		}
+	}
+	return false
+}
+
 type ActionOutput struct {
 	actionrunner.ActionIdentifier
 	Links *ActionLinks `json:"links,omitempty"`
+	// skip marker found in the commit messages; skipped actions have no pipeId
+	Skipped string `json:"skipped,omitempty"`
 }
 
-func actionsToOutput(cfg config.Config, actions []actionrunner.ActionDescriptor) []ActionOutput {
-	output := make([]ActionOutput, len(actions))
-	for idx, action := range actions {
-		output[idx] = ActionOutput{
+func actionsToOutput(cfg config.Config, actions []actionrunner.ActionDescriptor, skipped []skippedAction) []ActionOutput {
+	output := make([]ActionOutput, 0, len(actions)+len(skipped))
+	for _, action := range actions {
+		output = append(output, ActionOutput{
 			ActionIdentifier: action.ActionIdentifier,
 			Links:            generateLinks(determineLinksType(cfg), cfg.PublicURL, action.PipeID),
-		}
+		})
+	}
+	for _, action := range skipped {
+		identifier := action.ActionIdentifier
+		identifier.PipeID = ""
+		output = append(output, ActionOutput{
+			ActionIdentifier: identifier,
+			Skipped:          action.Marker,
+		})
 	}
 	return output
 }
@@ -1,16 +1,23 @@
 package webhook_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/religiosa1/git-webhook-receiver/internal/actionrunner"
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
 	"github.com/religiosa1/git-webhook-receiver/internal/config"
 	"github.com/religiosa1/git-webhook-receiver/internal/http/webhook"
 	"github.com/religiosa1/git-webhook-receiver/internal/requestmock"
@@ -25,18 +32,18 @@ type ResponseStats struct {
 
 func makeActionsList(actions ...config.Action) []config.Action {
 	defaultAction := config.Action{
-		On:     "push",
-		Branch: "master",
+		On:     config.PatternList{"push"},
+		Branch: config.PatternList{"master"},
 		Run:    []string{"go", "version"},
 	}
 
 	mergedActions := make([]config.Action, len(actions))
 	for i, a := range actions {
 		mergedAction := a
-		if mergedAction.On == "" {
+		if len(mergedAction.On) == 0 {
 			mergedAction.On = defaultAction.On
 		}
-		if mergedAction.Branch == "" {
+		if len(mergedAction.Branch) == 0 {
 			mergedAction.Branch = defaultAction.Branch
 		}
 		if mergedAction.Run == nil {
@@ -65,7 +72,7 @@ type testHandler struct {
 func newTestHandler(cfg config.Config, prj config.Project) testHandler {
 	rcvr := whreceiver.New(prj)
 	h := webhook.Webhook{
-		ActionsCh:   make(chan actionrunner.ActionArgs, 10),
+		Queue:       actionrunner.NewMemoryQueue(10),
 		Config:      cfg,
 		ProjectName: projectName,
 		Project:     prj,
@@ -74,6 +81,19 @@ func newTestHandler(cfg config.Config, prj config.Project) testHandler {
 	return testHandler{h}
 }
 
+// popQueued returns the next queued action, failing the test if nothing was
+// queued.
+func popQueued(t *testing.T, queue actionrunner.Queue) actionrunner.ActionArgs {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	args, ok := queue.Pop(ctx, actionrunner.Oldest)
+	if !ok {
+		t.Fatal("expected an action to be queued")
+	}
+	return args
+}
+
 func (h testHandler) doRequestAndGetAction(t *testing.T, req *http.Request) webhook.ActionOutput {
 	t.Helper()
 	response := httptest.NewRecorder()
@@ -120,7 +140,7 @@ func TestProjectMatching(t *testing.T) {
 
 	t.Run("returns 204, if no action matches", func(t *testing.T) {
 		prj2 := prj
-		prj2.Actions = makeActionsList(config.Action{Branch: "badbranch"})
+		prj2.Actions = makeActionsList(config.Action{Branch: config.PatternList{"badbranch"}})
 
 		request := requestDump.ToHTTPRequest(projectEndPoint)
 		response := httptest.NewRecorder()
@@ -141,15 +161,19 @@ func TestProjectMatching(t *testing.T) {
 
 	secretAndAuthStatusTests := []struct {
 		name       string
-		auth       string
-		secret     string
+		auth       config.SecretList
+		secret     config.SecretList
 		wantStatus int
 	}{
-		{"correct signature", "", secret, 201},
-		{"bad signature", "", "bad key", 403},
-		{"correct auth", authToken, "", 201},
-		{"bad auth", "bad pass", "", 401},
-		{"bad auth precedes bad sign", "bad pass", "bad key", 401},
+		{"correct signature", nil, config.SecretList{config.Secret(secret)}, 201},
+		{"bad signature", nil, config.SecretList{"bad key"}, 403},
+		{"correct auth", config.SecretList{config.Secret(authToken)}, nil, 201},
+		{"bad auth", config.SecretList{"bad pass"}, nil, 401},
+		{"bad auth precedes bad sign", config.SecretList{"bad pass"}, config.SecretList{"bad key"}, 401},
+		{"any of the secrets", nil, config.SecretList{"old key", config.Secret(secret)}, 201},
+		{"none of the secrets", nil, config.SecretList{"old key", "bad key"}, 403},
+		{"any of the auths", config.SecretList{"old pass", config.Secret(authToken)}, nil, 201},
+		{"none of the auths", config.SecretList{"old pass", "bad pass"}, nil, 401},
 	}
 
 	for _, tt := range secretAndAuthStatusTests {
@@ -157,8 +181,8 @@ func TestProjectMatching(t *testing.T) {
 			actn := config.Action{}
 
 			prj2 := prj
-			prj2.Authorization = config.Secret(tt.auth)
-			prj2.Secret = config.Secret(tt.secret)
+			prj2.Authorization = tt.auth
+			prj2.Secret = tt.secret
 			prj2.Actions = makeActionsList(actn)
 
 			request := requestDump.ToHTTPRequest(projectEndPoint)
@@ -205,7 +229,7 @@ func TestActionMatching(t *testing.T) {
 		}
 		for _, tt := range cases {
 			t.Run(tt.name, func(t *testing.T) {
-				got := runHandler(t, []config.Action{{On: "push", Branch: tt.branch, Run: []string{"go", "version"}}})
+				got := runHandler(t, []config.Action{{On: config.PatternList{"push"}, Branch: config.PatternList{tt.branch}, Run: []string{"go", "version"}}})
 				if got != tt.wantStatus {
 					t.Errorf("got %d, want %d", got, tt.wantStatus)
 				}
@@ -214,12 +238,34 @@ func TestActionMatching(t *testing.T) {
 	})
 
 	t.Run("branch wildcard matches any branch", func(t *testing.T) {
-		got := runHandler(t, []config.Action{{On: "push", Branch: "*", Run: []string{"go", "version"}}})
+		got := runHandler(t, []config.Action{{On: config.PatternList{"push"}, Branch: config.PatternList{"*"}, Run: []string{"go", "version"}}})
 		if got != 201 {
 			t.Errorf("got %d, want 201", got)
 		}
 	})
 
+	t.Run("branch patterns", func(t *testing.T) {
+		cases := []struct {
+			name       string
+			branch     config.PatternList
+			wantStatus int
+		}{
+			{"any of the list", config.PatternList{"main", "master"}, 201},
+			{"glob", config.PatternList{"mas*"}, 201},
+			{"regex", config.PatternList{"regex:^(main|master)$"}, 201},
+			{"excluded", config.PatternList{"*", "!master"}, 204},
+			{"not excluded", config.PatternList{"!wip/*"}, 201},
+		}
+		for _, tt := range cases {
+			t.Run(tt.name, func(t *testing.T) {
+				got := runHandler(t, []config.Action{{On: config.PatternList{"push"}, Branch: tt.branch, Run: []string{"go", "version"}}})
+				if got != tt.wantStatus {
+					t.Errorf("got %d, want %d", got, tt.wantStatus)
+				}
+			})
+		}
+	})
+
 	t.Run("event matching", func(t *testing.T) {
 		cases := []struct {
 			name       string
@@ -231,7 +277,7 @@ func TestActionMatching(t *testing.T) {
 		}
 		for _, tt := range cases {
 			t.Run(tt.name, func(t *testing.T) {
-				got := runHandler(t, []config.Action{{On: tt.event, Branch: "master", Run: []string{"go", "version"}}})
+				got := runHandler(t, []config.Action{{On: config.PatternList{tt.event}, Branch: config.PatternList{"master"}, Run: []string{"go", "version"}}})
 				if got != tt.wantStatus {
 					t.Errorf("got %d, want %d", got, tt.wantStatus)
 				}
@@ -240,11 +286,272 @@ func TestActionMatching(t *testing.T) {
 	})
 
 	t.Run("event wildcard matches any event", func(t *testing.T) {
-		got := runHandler(t, []config.Action{{On: "*", Branch: "master", Run: []string{"go", "version"}}})
+		got := runHandler(t, []config.Action{{On: config.PatternList{"*"}, Branch: config.PatternList{"master"}, Run: []string{"go", "version"}}})
 		if got != 201 {
 			t.Errorf("got %d, want 201", got)
 		}
 	})
+
+	t.Run("tag matching", func(t *testing.T) {
+		tagRequestDump := requestDump
+		tagRequestDump.Body = strings.Replace(requestDump.Body, `"ref": "refs/heads/master"`, `"ref": "refs/tags/v1.2.0"`, 1)
+
+		cases := []struct {
+			name       string
+			request    requestmock.RequestMock
+			tag        string
+			wantStatus int
+		}{
+			{"exact match", tagRequestDump, "v1.2.0", 201},
+			{"pattern match", tagRequestDump, "v*", 201},
+			{"no match", tagRequestDump, "release-*", 204},
+			{"branch push doesn't match tag actions", requestDump, "*", 204},
+		}
+		for _, tt := range cases {
+			t.Run(tt.name, func(t *testing.T) {
+				prj := baseProject
+				prj.Actions = []config.Action{{On: config.PatternList{"push"}, Tag: tt.tag, Run: []string{"go", "version"}}}
+				response := httptest.NewRecorder()
+				newTestHandler(cfg, prj).ServeHTTP(response, tt.request.ToHTTPRequest(projectEndPoint))
+				if got := response.Result().StatusCode; got != tt.wantStatus {
+					t.Errorf("got %d, want %d", got, tt.wantStatus)
+				}
+			})
+		}
+	})
+
+	t.Run("event action matching", func(t *testing.T) {
+		prRequestDump := requestmock.LoadRequestMock(t, "../../requestmock/captured-requests/gitea-pull-request.json")
+		cases := []struct {
+			name       string
+			on         string
+			wantStatus int
+		}{
+			{"any action of the event", "pull_request", 201},
+			{"exact action", "pull_request.synchronized", 201},
+			{"other action", "pull_request.closed", 204},
+			{"other event", "push", 204},
+			{"catch-all action", "*", 204},
+			{"catch-all action with the event", "*.synchronized", 201},
+		}
+		for _, tt := range cases {
+			t.Run(tt.name, func(t *testing.T) {
+				prj := baseProject
+				prj.Actions = []config.Action{{On: config.PatternList{tt.on}, Branch: config.PatternList{"master"}, Run: []string{"go", "version"}}}
+				response := httptest.NewRecorder()
+				newTestHandler(cfg, prj).ServeHTTP(response, prRequestDump.ToHTTPRequest(projectEndPoint))
+				if got := response.Result().StatusCode; got != tt.wantStatus {
+					t.Errorf("got %d, want %d", got, tt.wantStatus)
+				}
+			})
+		}
+	})
+
+	t.Run("ref creation and deletion", func(t *testing.T) {
+		const zeroHash = `"0000000000000000000000000000000000000000"`
+		createdDump := requestDump
+		createdDump.Body = strings.Replace(requestDump.Body, `"before": "323b2c0d7778db8aa4164db5aacea772c4c4feaf"`, `"before": `+zeroHash, 1)
+		deletedDump := requestDump
+		deletedDump.Body = strings.Replace(requestDump.Body, `"after": "323b2c0d7778db8aa4164db5aacea772c4c4feaf"`, `"after": `+zeroHash, 1)
+
+		cases := []struct {
+			name       string
+			dump       requestmock.RequestMock
+			on         string
+			wantStatus int
+		}{
+			{"created branch matches push", createdDump, "push", 201},
+			{"created branch matches branch_create", createdDump, "branch_create", 201},
+			{"created branch doesn't match branch_delete", createdDump, "branch_delete", 204},
+			{"updated branch doesn't match branch_create", requestDump, "branch_create", 204},
+			{"deleted branch doesn't match push", deletedDump, "push", 204},
+			{"deleted branch matches branch_delete", deletedDump, "branch_delete", 201},
+		}
+		for _, tt := range cases {
+			t.Run(tt.name, func(t *testing.T) {
+				prj := baseProject
+				prj.Actions = []config.Action{{On: config.PatternList{tt.on}, Branch: config.PatternList{"master"}, Run: []string{"go", "version"}}}
+				response := httptest.NewRecorder()
+				newTestHandler(cfg, prj).ServeHTTP(response, tt.dump.ToHTTPRequest(projectEndPoint))
+				if got := response.Result().StatusCode; got != tt.wantStatus {
+					t.Errorf("got %d, want %d", got, tt.wantStatus)
+				}
+			})
+		}
+	})
+
+	t.Run("event patterns", func(t *testing.T) {
+		prRequestDump := requestmock.LoadRequestMock(t, "../../requestmock/captured-requests/gitea-pull-request.json")
+		cases := []struct {
+			name       string
+			on         config.PatternList
+			wantStatus int
+		}{
+			{"any of the list", config.PatternList{"push", "pull_request"}, 201},
+			{"action glob", config.PatternList{"pull_request.*"}, 201},
+			{"action regex", config.PatternList{"regex:^pull_request\\.(opened|synchronized)$"}, 201},
+			{"excluded action", config.PatternList{"pull_request", "!pull_request.synchronized"}, 204},
+			{"not excluded action", config.PatternList{"pull_request", "!pull_request.closed"}, 201},
+		}
+		for _, tt := range cases {
+			t.Run(tt.name, func(t *testing.T) {
+				prj := baseProject
+				prj.Actions = []config.Action{{On: tt.on, Branch: config.PatternList{"master"}, Run: []string{"go", "version"}}}
+				response := httptest.NewRecorder()
+				newTestHandler(cfg, prj).ServeHTTP(response, prRequestDump.ToHTTPRequest(projectEndPoint))
+				if got := response.Result().StatusCode; got != tt.wantStatus {
+					t.Errorf("got %d, want %d", got, tt.wantStatus)
+				}
+			})
+		}
+	})
+
+	t.Run("gitlab events match both old and new names", func(t *testing.T) {
+		mrRequestDump := requestmock.LoadRequestMock(t, "../../requestmock/captured-requests/gitlab-merge-request.json")
+		cases := []struct {
+			name       string
+			on         config.PatternList
+			wantStatus int
+		}{
+			{"new name", config.PatternList{"merge_request"}, 201},
+			{"old name", config.PatternList{"merge request"}, 201},
+			{"old name with action", config.PatternList{"merge request.close"}, 201},
+			{"excluded old name", config.PatternList{"*", "!merge request"}, 204},
+		}
+		for _, tt := range cases {
+			t.Run(tt.name, func(t *testing.T) {
+				prj := config.Project{
+					GitProvider: "gitlab",
+					Repo:        "root/test",
+					Actions:     []config.Action{{On: tt.on, Branch: config.PatternList{"main"}, Run: []string{"go", "version"}}},
+				}
+				response := httptest.NewRecorder()
+				newTestHandler(cfg, prj).ServeHTTP(response, mrRequestDump.ToHTTPRequest(projectEndPoint))
+				if got := response.Result().StatusCode; got != tt.wantStatus {
+					t.Errorf("got %d, want %d", got, tt.wantStatus)
+				}
+			})
+		}
+	})
+
+	t.Run("release type matching", func(t *testing.T) {
+		releaseRequestDump := requestmock.LoadRequestMock(t, "../../requestmock/captured-requests/gitea-release.json")
+		prereleaseRequestDump := releaseRequestDump
+		prereleaseRequestDump.Body = strings.Replace(releaseRequestDump.Body, `"prerelease": false`, `"prerelease": true`, 1)
+		draftRequestDump := releaseRequestDump
+		draftRequestDump.Body = strings.Replace(prereleaseRequestDump.Body, `"draft": false`, `"draft": true`, 1)
+
+		cases := []struct {
+			name        string
+			request     requestmock.RequestMock
+			releaseType string
+			wantStatus  int
+		}{
+			{"any release", releaseRequestDump, "", 201},
+			{"stable release", releaseRequestDump, "stable", 201},
+			{"stable doesn't match prerelease", prereleaseRequestDump, "stable", 204},
+			{"prerelease", prereleaseRequestDump, "prerelease", 201},
+			{"prerelease doesn't match stable", releaseRequestDump, "prerelease", 204},
+			{"prerelease doesn't match draft", draftRequestDump, "prerelease", 204},
+			{"draft", draftRequestDump, "draft", 201},
+			{"release type doesn't match push", requestDump, "stable", 204},
+		}
+		for _, tt := range cases {
+			t.Run(tt.name, func(t *testing.T) {
+				prj := baseProject
+				prj.Actions = []config.Action{{On: config.PatternList{"*"}, ReleaseType: tt.releaseType, Run: []string{"go", "version"}}}
+				response := httptest.NewRecorder()
+				newTestHandler(cfg, prj).ServeHTTP(response, tt.request.ToHTTPRequest(projectEndPoint))
+				if got := response.Result().StatusCode; got != tt.wantStatus {
+					t.Errorf("got %d, want %d", got, tt.wantStatus)
+				}
+			})
+		}
+	})
+
+	t.Run("paths matching", func(t *testing.T) {
+		withChangedFiles := func(totalCommits int) requestmock.RequestMock {
+			dump := requestDump
+			dump.Body = strings.Replace(
+				requestDump.Body,
+				`"added": null,
+      "removed": null,
+      "modified": null`,
+				`"added": ["docs/index.md"],
+      "removed": [],
+      "modified": ["backend/main.go"]`,
+				1,
+			)
+			dump.Body = strings.Replace(dump.Body, `"total_commits": 1`, fmt.Sprintf(`"total_commits": %d`, totalCommits), 1)
+			return dump
+		}
+		changedRequestDump := withChangedFiles(1)
+		truncatedRequestDump := withChangedFiles(30)
+
+		cases := []struct {
+			name        string
+			request     requestmock.RequestMock
+			paths       []string
+			pathsIgnore []string
+			wantStatus  int
+		}{
+			{"no filters", changedRequestDump, nil, nil, 201},
+			{"paths match", changedRequestDump, []string{"backend/**"}, nil, 201},
+			{"paths don't match", changedRequestDump, []string{"frontend/**"}, nil, 204},
+			{"some files aren't ignored", changedRequestDump, nil, []string{"docs/**"}, 201},
+			{"all files are ignored", changedRequestDump, nil, []string{"docs/**", "**/*.go"}, 204},
+			{"matched file is ignored", changedRequestDump, []string{"backend/**"}, []string{"**/*.go"}, 204},
+			{"truncated commits list matches", truncatedRequestDump, []string{"frontend/**"}, nil, 201},
+		}
+		for _, tt := range cases {
+			t.Run(tt.name, func(t *testing.T) {
+				prj := baseProject
+				prj.Actions = makeActionsList(config.Action{Paths: tt.paths, PathsIgnore: tt.pathsIgnore})
+				response := httptest.NewRecorder()
+				newTestHandler(cfg, prj).ServeHTTP(response, tt.request.ToHTTPRequest(projectEndPoint))
+				if got := response.Result().StatusCode; got != tt.wantStatus {
+					t.Errorf("got %d, want %d", got, tt.wantStatus)
+				}
+			})
+		}
+	})
+
+	t.Run("condition matching", func(t *testing.T) {
+		cases := []struct {
+			name       string
+			condition  string
+			wantStatus int
+		}{
+			{"no condition", "", 201},
+			{"payload field", "payload.repository.private == true", 201},
+			{"payload field mismatch", "!payload.repository.private", 204},
+			{"pusher in a list", "contains(['religiosa', 'octocat'], payload.pusher.login)", 201},
+			{"pusher not in a list", "contains(['octocat'], payload.pusher.login)", 204},
+			{"header", "headers['x-gitea-event'] == 'push'", 201},
+			{"webhook info", "webhook.branch == 'master' && webhook.push.commitCount == 1", 201},
+			{"evaluation error skips the action", "payload.repository > 1", 204},
+		}
+		for _, tt := range cases {
+			t.Run(tt.name, func(t *testing.T) {
+				got := runHandler(t, makeActionsList(config.Action{If: tt.condition}))
+				if got != tt.wantStatus {
+					t.Errorf("got %d, want %d", got, tt.wantStatus)
+				}
+			})
+		}
+	})
+
+	t.Run("branch action doesn't match tag pushes", func(t *testing.T) {
+		tagRequestDump := requestDump
+		tagRequestDump.Body = strings.Replace(requestDump.Body, `"ref": "refs/heads/master"`, `"ref": "refs/tags/v1.2.0"`, 1)
+		prj := baseProject
+		prj.Actions = []config.Action{{On: config.PatternList{"push"}, Branch: config.PatternList{"master"}, Run: []string{"go", "version"}}}
+		response := httptest.NewRecorder()
+		newTestHandler(cfg, prj).ServeHTTP(response, tagRequestDump.ToHTTPRequest(projectEndPoint))
+		if got := response.Result().StatusCode; got != 204 {
+			t.Errorf("got %d, want 204", got)
+		}
+	})
 }
 
 func TestResponseBody(t *testing.T) {
@@ -253,13 +560,13 @@ func TestResponseBody(t *testing.T) {
 		Repo:        "religiosa/staticus",
 		Actions: []config.Action{
 			{
-				On:     "push",
-				Branch: "non-existing",
+				On:     config.PatternList{"push"},
+				Branch: config.PatternList{"non-existing"},
 				Run:    []string{"go", "version"},
 			},
 			{
-				On:     "push",
-				Branch: "master",
+				On:     config.PatternList{"push"},
+				Branch: config.PatternList{"master"},
 				Run:    []string{"go", "version"},
 			},
 		},
@@ -284,6 +591,312 @@ func TestResponseBody(t *testing.T) {
 	})
 }
 
+func TestSkipMarkers(t *testing.T) {
+	requestDump := loadMockRequest(t) // head commit message: "Submit incident button missed"
+	prj := config.Project{
+		GitProvider: "gitea",
+		Repo:        "religiosa/staticus",
+	}
+
+	runHandler := func(t *testing.T, actions []config.Action) (int, []webhook.ActionOutput) {
+		t.Helper()
+		prj := prj
+		prj.Actions = actions
+		response := httptest.NewRecorder()
+		newTestHandler(config.Config{}, prj).ServeHTTP(response, requestDump.ToHTTPRequest(projectEndPoint))
+		output := make([]webhook.ActionOutput, 0)
+		if err := json.NewDecoder(response.Result().Body).Decode(&output); err != nil {
+			t.Fatal(err)
+		}
+		return response.Result().StatusCode, output
+	}
+
+	cases := []struct {
+		name        string
+		markers     []string
+		in          string
+		wantStatus  int
+		wantSkipped string
+	}{
+		{"no markers", nil, "", 201, ""},
+		{"marker not in the message", []string{"[skip ci]"}, "", 201, ""},
+		{"marker in the head commit", []string{"[skip ci]", "INCIDENT"}, config.SkipMarkersInHead, 200, "INCIDENT"},
+		{"marker in all commits", []string{"button"}, config.SkipMarkersInAll, 200, "button"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			status, output := runHandler(t, makeActionsList(config.Action{SkipMarkers: tt.markers, SkipMarkersIn: tt.in}))
+			if status != tt.wantStatus {
+				t.Errorf("got status %d, want %d", status, tt.wantStatus)
+			}
+			if len(output) != 1 {
+				t.Fatalf("unexpected length of actions in the response, want 1, got %d", len(output))
+			}
+			if got := output[0].Skipped; got != tt.wantSkipped {
+				t.Errorf("unexpected skipped value, want %q, got %q", tt.wantSkipped, got)
+			}
+			if skipped := tt.wantSkipped != ""; skipped != (output[0].PipeID == "") {
+				t.Errorf("skipped actions must have no pipeId, and launched ones must have it, got %q", output[0].PipeID)
+			}
+		})
+	}
+
+	t.Run("markers are per action", func(t *testing.T) {
+		status, output := runHandler(t, makeActionsList(
+			config.Action{SkipMarkers: []string{"incident"}},
+			config.Action{},
+		))
+		if status != 201 {
+			t.Errorf("got status %d, want 201", status)
+		}
+		if len(output) != 2 {
+			t.Fatalf("unexpected length of actions in the response, want 2, got %d", len(output))
+		}
+		if output[0].Index != 1 || output[0].Skipped != "" {
+			t.Errorf("expected the second action to run, got %+v", output[0])
+		}
+		if output[1].Index != 0 || output[1].Skipped != "incident" {
+			t.Errorf("expected the first action to be skipped, got %+v", output[1])
+		}
+	})
+}
+
+func TestDuplicateDeliveries(t *testing.T) {
+	requestDump := loadMockRequest(t)
+	const redeliveryToken = "redeliver-32167"
+	cfg := config.Config{RedeliveryToken: redeliveryToken}
+	prj := config.Project{
+		GitProvider: "gitea",
+		Repo:        "religiosa/staticus",
+		Secret:      config.SecretList{config.Secret(secret)},
+		Actions:     makeActionsList(config.Action{}),
+	}
+
+	newHandler := func() testHandler {
+		h := newTestHandler(cfg, prj)
+		h.Deliveries = webhook.NewMemoryDeliveryStore(10)
+		return h
+	}
+	doRequest := func(t *testing.T, h testHandler, token string) int {
+		t.Helper()
+		request := requestDump.ToHTTPRequest(projectEndPoint)
+		if token != "" {
+			request.Header.Set(webhook.RedeliveryTokenHeader, token)
+		}
+		response := httptest.NewRecorder()
+		h.ServeHTTP(response, request)
+		return response.Result().StatusCode
+	}
+
+	t.Run("rejects duplicate deliveries", func(t *testing.T) {
+		h := newHandler()
+		if got := doRequest(t, h, ""); got != 201 {
+			t.Errorf("got %d, want 201", got)
+		}
+		if got := doRequest(t, h, ""); got != 409 {
+			t.Errorf("got %d, want 409", got)
+		}
+	})
+
+	t.Run("rejects replays with a fresh delivery id", func(t *testing.T) {
+		h := newHandler()
+		doRequest(t, h, "")
+		replayDump := requestDump
+		replayDump.Headers = maps.Clone(requestDump.Headers)
+		replayDump.Headers["x-gitea-delivery"] = "00000000-0000-0000-0000-000000000000"
+		response := httptest.NewRecorder()
+		h.ServeHTTP(response, replayDump.ToHTTPRequest(projectEndPoint))
+		if got := response.Result().StatusCode; got != 409 {
+			t.Errorf("got %d, want 409", got)
+		}
+	})
+
+	t.Run("accepts redeliveries with the token", func(t *testing.T) {
+		h := newHandler()
+		doRequest(t, h, "")
+		if got := doRequest(t, h, redeliveryToken); got != 201 {
+			t.Errorf("got %d, want 201", got)
+		}
+	})
+
+	t.Run("ignores invalid redelivery token", func(t *testing.T) {
+		h := newHandler()
+		doRequest(t, h, "")
+		if got := doRequest(t, h, "bad token"); got != 409 {
+			t.Errorf("got %d, want 409", got)
+		}
+	})
+
+	t.Run("ignores redelivery token, if it's not configured", func(t *testing.T) {
+		h := newHandler()
+		h.Config.RedeliveryToken = ""
+		doRequest(t, h, "")
+		if got := doRequest(t, h, redeliveryToken); got != 409 {
+			t.Errorf("got %d, want 409", got)
+		}
+	})
+
+	t.Run("forged deliveries aren't marked as seen", func(t *testing.T) {
+		h := newHandler()
+		forgedDump := requestDump
+		forgedDump.Headers = maps.Clone(requestDump.Headers)
+		forgedDump.Headers["x-gitea-signature"] = "bad signature"
+		response := httptest.NewRecorder()
+		h.ServeHTTP(response, forgedDump.ToHTTPRequest(projectEndPoint))
+		if got := response.Result().StatusCode; got != 403 {
+			t.Fatalf("got %d, want 403", got)
+		}
+		if got := doRequest(t, h, ""); got != 201 {
+			t.Errorf("got %d, want 201", got)
+		}
+	})
+
+	t.Run("delivery can be retried if actions queue is full", func(t *testing.T) {
+		h := newHandler()
+		h.Queue = actionrunner.NewMemoryQueue(0)
+		if got := doRequest(t, h, ""); got != 429 {
+			t.Fatalf("got %d, want 429", got)
+		}
+		if got := doRequest(t, h, ""); got != 429 {
+			t.Errorf("got %d, want 429", got)
+		}
+	})
+}
+
+func TestDurableQueue(t *testing.T) {
+	requestDump := loadMockRequest(t)
+	prj := config.Project{
+		GitProvider: "gitea",
+		Repo:        "religiosa/staticus",
+		Secret:      config.SecretList{config.Secret(secret)},
+		Actions:     makeActionsList(config.Action{}),
+	}
+	cfg := config.Config{
+		MaxQueuedActions: 1,
+		Projects:         map[string]config.Project{projectName: prj},
+	}
+	db, err := actionsdb.New(filepath.Join(t.TempDir(), "actions.sqlite3"), 100)
+	if err != nil {
+		t.Fatalf("Unable to create a db: %s", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	h := newTestHandler(cfg, prj)
+	h.Queue = actionrunner.NewDBQueue(db, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	output := h.doRequestAndGetAction(t, requestDump.ToHTTPRequest(projectEndPoint))
+	record, err := db.GetPipelineRecord(output.PipeID)
+	if err != nil {
+		t.Fatalf("Expected the pipeline record to be created, got %s", err)
+	}
+	if got := record.Status(); got != actionsdb.PipeStatusQueued {
+		t.Errorf("status: want %q, got %q", actionsdb.PipeStatusQueued, got)
+	}
+
+	response := httptest.NewRecorder()
+	h.ServeHTTP(response, requestDump.ToHTTPRequest(projectEndPoint))
+	if got := response.Result().StatusCode; got != http.StatusTooManyRequests {
+		t.Errorf("status code above the max queue depth: want 429, got %d", got)
+	}
+}
+
+type recordedDeliveries []actionsdb.DeliveryRecord
+
+func (r *recordedDeliveries) Record(record actionsdb.DeliveryRecord) error {
+	*r = append(*r, record)
+	return nil
+}
+
+func TestDeliveryRecording(t *testing.T) {
+	requestDump := loadMockRequest(t)
+	prj := config.Project{
+		GitProvider: "gitea",
+		Repo:        "religiosa/staticus",
+		Secret:      config.SecretList{config.Secret(secret)},
+		Actions: makeActionsList(
+			config.Action{},
+			config.Action{SkipMarkers: []string{"incident"}, SkipMarkersIn: config.SkipMarkersInHead},
+		),
+	}
+	newHandler := func(cfg config.Config) (testHandler, *recordedDeliveries) {
+		h := newTestHandler(cfg, prj)
+		recorded := &recordedDeliveries{}
+		h.Recorder = recorded
+		return h, recorded
+	}
+
+	t.Run("records the delivery with its outcome", func(t *testing.T) {
+		h, recorded := newHandler(config.Config{})
+		queue := actionrunner.NewMemoryQueue(10)
+		h.Queue = queue
+		response := httptest.NewRecorder()
+		h.ServeHTTP(response, requestDump.ToHTTPRequest(projectEndPoint))
+		if len(*recorded) != 1 {
+			t.Fatalf("want 1 recorded delivery, got %d", len(*recorded))
+		}
+		record := (*recorded)[0]
+		if record.RecordID == "" {
+			t.Error("expected record id to be generated")
+		}
+		if record.StatusCode != response.Result().StatusCode || record.StatusCode != 201 {
+			t.Errorf("status code: want 201, got %d", record.StatusCode)
+		}
+		if record.Verification != actionsdb.DeliveryVerificationPassed {
+			t.Errorf("verification: want %q, got %q", actionsdb.DeliveryVerificationPassed, record.Verification)
+		}
+		if record.Event != "push" || record.DeliveryID == "" {
+			t.Errorf("unexpected event %q or delivery id %q", record.Event, record.DeliveryID)
+		}
+		if got := record.Headers.Get("X-Gitea-Signature"); got != "********" {
+			t.Errorf("signature header: want it masked, got %q", got)
+		}
+		if got := record.Headers.Get("X-Gitea-Event"); got != "push" {
+			t.Errorf("event header: want %q, got %q", "push", got)
+		}
+		if string(record.Payload) != requestDump.Body || record.PayloadSize != len(requestDump.Body) {
+			t.Errorf("payload: want the full request body, got %d bytes out of %d", len(record.Payload), record.PayloadSize)
+		}
+
+		args := popQueued(t, queue)
+		want := []actionsdb.DeliveryAction{
+			{Index: 0, PipeID: args.ActionDesc.PipeID},
+			{Index: 1, Skipped: "incident"},
+		}
+		if !reflect.DeepEqual(want, record.MatchedActions) {
+			t.Errorf("matched actions: want %+v, got %+v", want, record.MatchedActions)
+		}
+		if args.DeliveryRecordID != record.RecordID {
+			t.Errorf("pipeline delivery record id: want %q, got %q", record.RecordID, args.DeliveryRecordID)
+		}
+	})
+
+	t.Run("caps the stored payload", func(t *testing.T) {
+		h, recorded := newHandler(config.Config{MaxDeliveryPayloadBytes: 10})
+		h.ServeHTTP(httptest.NewRecorder(), requestDump.ToHTTPRequest(projectEndPoint))
+		record := (*recorded)[0]
+		if len(record.Payload) != 10 || record.PayloadSize != len(requestDump.Body) {
+			t.Errorf("payload: want 10 bytes out of %d, got %d out of %d", len(requestDump.Body), len(record.Payload), record.PayloadSize)
+		}
+	})
+
+	t.Run("records rejected deliveries", func(t *testing.T) {
+		h, recorded := newHandler(config.Config{})
+		forgedDump := requestDump
+		forgedDump.Headers = maps.Clone(requestDump.Headers)
+		forgedDump.Headers["x-gitea-signature"] = "bad signature"
+		h.ServeHTTP(httptest.NewRecorder(), forgedDump.ToHTTPRequest(projectEndPoint))
+		record := (*recorded)[0]
+		if record.StatusCode != 403 {
+			t.Errorf("status code: want 403, got %d", record.StatusCode)
+		}
+		if record.Verification != actionsdb.DeliveryVerificationSignatureFailed {
+			t.Errorf("verification: want %q, got %q", actionsdb.DeliveryVerificationSignatureFailed, record.Verification)
+		}
+		if len(record.MatchedActions) != 0 {
+			t.Errorf("matched actions: want none, got %+v", record.MatchedActions)
+		}
+	})
+}
+
 func TestPublicUrl(t *testing.T) {
 	requestDump := loadMockRequest(t)
 	prj := config.Project{
@@ -291,8 +904,8 @@ func TestPublicUrl(t *testing.T) {
 		Repo:        "religiosa/staticus",
 		Actions: []config.Action{
 			{
-				On:     "push",
-				Branch: "master",
+				On:     config.PatternList{"push"},
+				Branch: config.PatternList{"master"},
 				Run:    []string{"go", "version"},
 			},
 		},
@@ -0,0 +1,162 @@
+// Package jsonpointer implements RFC 6901 JSON pointers, resolved against the
+// generic representation of a JSON document, as produced by [json.Unmarshal]
+// into an `any` value.
+//
+// @see https://www.rfc-editor.org/rfc/rfc6901
+package jsonpointer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrBadPointer = errors.New("bad json pointer")
+
+// Pointer is a parsed JSON pointer, a list of unescaped reference tokens.
+// Zero value points to the whole document.
+type Pointer []string
+
+// Parse parses a JSON pointer string, e.g. "/repository/full_name".
+// Empty string is a valid pointer to the whole document.
+func Parse(s string) (Pointer, error) {
+	if s == "" {
+		return Pointer{}, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("%w %q: it must start with '/'", ErrBadPointer, s)
+	}
+	parts := strings.Split(s[1:], "/")
+	for i, part := range parts {
+		token, err := unescape(part)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrBadPointer, s, err)
+		}
+		parts[i] = token
+	}
+	return Pointer(parts), nil
+}
+
+func unescape(token string) (string, error) {
+	if !strings.Contains(token, "~") {
+		return token, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(token); i++ {
+		if token[i] != '~' {
+			sb.WriteByte(token[i])
+			continue
+		}
+		if i+1 >= len(token) {
+			return "", fmt.Errorf("dangling '~' in %q", token)
+		}
+		i++
+		switch token[i] {
+		case '0':
+			sb.WriteByte('~')
+		case '1':
+			sb.WriteByte('/')
+		default:
+			return "", fmt.Errorf("bad escape sequence '~%c' in %q", token[i], token)
+		}
+	}
+	return sb.String(), nil
+}
+
+func (p Pointer) String() string {
+	var sb strings.Builder
+	for _, token := range p {
+		sb.WriteByte('/')
+		token = strings.ReplaceAll(token, "~", "~0")
+		token = strings.ReplaceAll(token, "/", "~1")
+		sb.WriteString(token)
+	}
+	return sb.String()
+}
+
+// Get resolves the pointer against the document, returning false if the
+// referenced value doesn't exist.
+func (p Pointer) Get(doc any) (any, bool) {
+	value := doc
+	for _, token := range p {
+		switch v := value.(type) {
+		case map[string]any:
+			var ok bool
+			if value, ok = v[token]; !ok {
+				return nil, false
+			}
+		case []any:
+			idx, ok := parseArrayIndex(token)
+			if !ok || idx >= len(v) {
+				return nil, false
+			}
+			value = v[idx]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// GetString resolves the pointer and converts the referenced value to
+// string: strings are returned as-is, nulls as an empty string, and anything
+// else is returned in its JSON representation.
+func (p Pointer) GetString(doc any) (string, bool) {
+	value, ok := p.Get(doc)
+	if !ok {
+		return "", false
+	}
+	return Stringify(value), true
+}
+
+// Stringify converts a generic JSON value to string, see [Pointer.GetString].
+func Stringify(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		out, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(out)
+	}
+}
+
+// parseArrayIndex only accepts decimal indices without leading zeros, as per
+// RFC 6901 section 4.
+func parseArrayIndex(token string) (int, bool) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, false
+	}
+	for _, r := range token {
+		if r < '0' || r > '9' {
+			return 0, false
+		}
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, false
+	}
+	return idx, true
+}
+
+// Unmarshal decodes a JSON payload into its generic representation suitable
+// for [Pointer.Get], keeping numbers as [json.Number] so they're stringified
+// without losing precision.
+func Unmarshal(payload []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,104 @@
+package jsonpointer_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/jsonpointer"
+)
+
+// Example document from RFC 6901 section 5
+const rfcDocument = `{
+	"foo": ["bar", "baz"],
+	"": 0,
+	"a/b": 1,
+	"c%d": 2,
+	"e^f": 3,
+	"g|h": 4,
+	"i\\j": 5,
+	"k\"l": 6,
+	" ": 7,
+	"m~n": 8
+}`
+
+func TestPointerGet(t *testing.T) {
+	doc, err := jsonpointer.Unmarshal([]byte(rfcDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pointer string
+		want    string
+	}{
+		{"/foo", `["bar","baz"]`},
+		{"/foo/0", "bar"},
+		{"/", "0"},
+		{"/a~1b", "1"},
+		{"/c%d", "2"},
+		{"/e^f", "3"},
+		{"/g|h", "4"},
+		{"/i\\j", "5"},
+		{"/k\"l", "6"},
+		{"/ ", "7"},
+		{"/m~0n", "8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			p, err := jsonpointer.Parse(tt.pointer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := p.GetString(doc)
+			if !ok {
+				t.Fatalf("value not found for pointer %q", tt.pointer)
+			}
+			if got != tt.want {
+				t.Errorf("unexpected value, want %q, got %q", tt.want, got)
+			}
+			if s := p.String(); s != tt.pointer {
+				t.Errorf("pointer string round trip failed, want %q, got %q", tt.pointer, s)
+			}
+		})
+	}
+}
+
+func TestPointerGetMissing(t *testing.T) {
+	doc, err := jsonpointer.Unmarshal([]byte(rfcDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pointer := range []string{"/nope", "/foo/2", "/foo/01", "/foo/-", "/foo/0/bar", "/m~0n/x"} {
+		t.Run(pointer, func(t *testing.T) {
+			p, err := jsonpointer.Parse(pointer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, ok := p.Get(doc); ok {
+				t.Errorf("expected value to be missing, got %v", got)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, pointer := range []string{"foo", "/foo~", "/foo~2"} {
+		t.Run(pointer, func(t *testing.T) {
+			_, err := jsonpointer.Parse(pointer)
+			if !errors.Is(err, jsonpointer.ErrBadPointer) {
+				t.Errorf("expected ErrBadPointer, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEmptyPointerIsWholeDocument(t *testing.T) {
+	p, err := jsonpointer.Parse("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, _ := jsonpointer.Unmarshal([]byte(`"foo"`))
+	if got, _ := p.GetString(doc); got != "foo" {
+		t.Errorf("unexpected value, want %q, got %q", "foo", got)
+	}
+}
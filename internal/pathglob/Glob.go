@@ -0,0 +1,56 @@
+// Package pathglob matches slash separated paths against glob patterns.
+//
+// Each pattern segment follows the [path.Match] syntax, so '*' never crosses
+// a '/', with the addition of the "**" segment, which matches zero or more
+// whole path segments, e.g. "docs/**" matches any file under docs/ and
+// "**/*.md" matches markdown files in any directory.
+package pathglob
+
+import (
+	"path"
+	"strings"
+)
+
+const anySegments = "**"
+
+// Match reports whether name matches the glob pattern. The only possible
+// error is [path.ErrBadPattern].
+func Match(pattern, name string) (bool, error) {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// Validate checks the pattern syntax, so it can be reported at config load.
+func Validate(pattern string) error {
+	for segment := range strings.SplitSeq(pattern, "/") {
+		if segment == anySegments {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == anySegments {
+			for i := 0; i <= len(name); i++ {
+				matched, err := matchSegments(pattern[1:], name[i:])
+				if matched || err != nil {
+					return matched, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		matched, err := path.Match(pattern[0], name[0])
+		if !matched || err != nil {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
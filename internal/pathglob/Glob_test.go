@@ -0,0 +1,60 @@
+package pathglob_test
+
+import (
+	"errors"
+	"path"
+	"testing"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/pathglob"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"README.md", "README.md", true},
+		{"*.md", "README.md", true},
+		{"*.md", "docs/README.md", false},
+		{"docs/*", "docs/index.md", true},
+		{"docs/*", "docs/api/index.md", false},
+		{"docs/**", "docs/index.md", true},
+		{"docs/**", "docs/api/index.md", true},
+		{"docs/**", "docs", true},
+		{"docs/**", "src/docs/index.md", false},
+		{"**/*.md", "README.md", true},
+		{"**/*.md", "docs/api/index.md", true},
+		{"**/*.md", "docs/api/index.go", false},
+		{"src/**/test/*.go", "src/test/a.go", true},
+		{"src/**/test/*.go", "src/a/b/test/a.go", true},
+		{"src/**/test/*.go", "src/a/b/a.go", false},
+		{"**", "any/path/at/all", true},
+		{"cmd/[a-m]*/main.go", "cmd/api/main.go", true},
+		{"cmd/[a-m]*/main.go", "cmd/web/main.go", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			got, err := pathglob.Match(tt.pattern, tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Match(%q, %q) = %t, want %t", tt.pattern, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, pattern := range []string{"docs/**", "**/*.md", "cmd/[a-z]*/main.go", ""} {
+		if err := pathglob.Validate(pattern); err != nil {
+			t.Errorf("unexpected error for pattern %q: %v", pattern, err)
+		}
+	}
+	for _, pattern := range []string{"docs/[", "**/[a-"} {
+		if err := pathglob.Validate(pattern); !errors.Is(err, path.ErrBadPattern) {
+			t.Errorf("expected ErrBadPattern for pattern %q, got %v", pattern, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package serialization
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/actionsdb"
+	"github.com/religiosa1/git-webhook-receiver/internal/models"
+)
+
+type PrettyDeliveryRecord struct {
+	ID             string                     `json:"id"`
+	Project        string                     `json:"project"`
+	DeliveryID     string                     `json:"deliveryId"`
+	Event          string                     `json:"event"`
+	Headers        http.Header                `json:"headers"`
+	Verification   string                     `json:"verification"`
+	MatchedActions []actionsdb.DeliveryAction `json:"matchedActions"`
+	StatusCode     int                        `json:"statusCode"`
+	CreatedAt      time.Time                  `json:"createdAt"`
+	PayloadSize    int                        `json:"payloadSize"`
+	// payload is only returned for a single delivery
+	Payload          *string `json:"payload,omitempty"`
+	PayloadTruncated *bool   `json:"payloadTruncated,omitempty"`
+}
+
+func DeliveryRecord(r actionsdb.DeliveryRecord) PrettyDeliveryRecord {
+	matchedActions := r.MatchedActions
+	if matchedActions == nil {
+		matchedActions = []actionsdb.DeliveryAction{}
+	}
+	return PrettyDeliveryRecord{
+		ID:             r.RecordID,
+		Project:        r.Project,
+		DeliveryID:     r.DeliveryID,
+		Event:          r.Event,
+		Headers:        r.Headers,
+		Verification:   r.Verification,
+		MatchedActions: matchedActions,
+		StatusCode:     r.StatusCode,
+		CreatedAt:      r.CreatedAt,
+		PayloadSize:    r.PayloadSize,
+	}
+}
+
+// DeliveryRecordWithPayload serializes the delivery with its stored payload.
+func DeliveryRecordWithPayload(r actionsdb.DeliveryRecord) PrettyDeliveryRecord {
+	record := DeliveryRecord(r)
+	payload := string(r.Payload)
+	truncated := r.IsPayloadTruncated()
+	record.Payload = &payload
+	record.PayloadTruncated = &truncated
+	return record
+}
+
+func DeliveryRecords(rs []actionsdb.DeliveryRecord) []PrettyDeliveryRecord {
+	records := make([]PrettyDeliveryRecord, len(rs))
+	for i, r := range rs {
+		records[i] = DeliveryRecord(r)
+	}
+	return records
+}
+
+func DeliveryPage(pagedDeliveryRecords models.PagedDB[actionsdb.DeliveryRecord]) models.Paged[PrettyDeliveryRecord] {
+	var result models.Paged[PrettyDeliveryRecord]
+	result.TotalCount = pagedDeliveryRecords.TotalCount
+	result.Items = DeliveryRecords(pagedDeliveryRecords.Items)
+	return result
+}
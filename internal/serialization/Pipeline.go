@@ -8,38 +8,79 @@ import (
 )
 
 type PrettyPipelineRecord struct {
-	PipeID     string     `json:"pipeId"`
-	Project    string     `json:"project"`
-	DeliveryID string     `json:"deliveryId"`
-	Hash       *string    `json:"hash"`
-	Config     JSONData   `json:"config"`
-	Error      *string    `json:"error"`
-	CreatedAt  time.Time  `json:"createdAt"`
-	EndedAt    *time.Time `json:"endedAt"`
+	PipeID     string `json:"pipeId"`
+	Project    string `json:"project"`
+	DeliveryID string `json:"deliveryId"`
+	// RecordID of the stored delivery, nil if it wasn't recorded
+	DeliveryRecordID *string `json:"deliveryRecordId"`
+	// set, if the pipeline was started by a redelivery
+	Replay bool     `json:"replay"`
+	Hash   *string  `json:"hash"`
+	Config JSONData `json:"config"`
+	Error  *string  `json:"error"`
+	// one of "ok", "error", "pending" or "queued"
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"createdAt"`
+	StartedAt *time.Time `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt"`
+	// nil, unless the pipeline was triggered by a push event
+	Push *PrettyPushInfo `json:"push"`
+}
+
+type PrettyPushInfo struct {
+	Pusher        string `json:"pusher"`
+	Before        string `json:"before"`
+	CommitMessage string `json:"commitMessage"`
+	CommitCount   int    `json:"commitCount"`
+	CompareURL    string `json:"compareUrl"`
+	Created       bool   `json:"created"`
+	Deleted       bool   `json:"deleted"`
+	Forced        bool   `json:"forced"`
 }
 
 func PipelineRecord(r actionsdb.PipeLineRecord) PrettyPipelineRecord {
 	config, _ := NewJSONData(r.Config)
 
-	var hash *string
+	var hash, deliveryRecordID *string
 	if r.Hash != "" {
 		hash = &r.Hash
 	}
+	if r.DeliveryRecordID != "" {
+		deliveryRecordID = &r.DeliveryRecordID
+	}
 	var errStr *string
 	if r.Error != nil {
 		s := r.Error.Error()
 		errStr = &s
 	}
+	var push *PrettyPushInfo
+	if r.Push != nil {
+		push = &PrettyPushInfo{
+			Pusher:        r.Push.Pusher,
+			Before:        r.Push.Before,
+			CommitMessage: r.Push.CommitMessage,
+			CommitCount:   r.Push.CommitCount,
+			CompareURL:    r.Push.CompareURL,
+			Created:       r.Push.Created,
+			Deleted:       r.Push.Deleted,
+			Forced:        r.Push.Forced,
+		}
+	}
 
 	return PrettyPipelineRecord{
-		PipeID:     r.PipeID,
-		Project:    r.Project,
-		DeliveryID: r.DeliveryID,
-		Hash:       hash,
-		Config:     config,
-		Error:      errStr,
-		CreatedAt:  r.CreatedAt,
-		EndedAt:    r.EndedAt,
+		PipeID:           r.PipeID,
+		Project:          r.Project,
+		DeliveryID:       r.DeliveryID,
+		DeliveryRecordID: deliveryRecordID,
+		Replay:           r.Replay,
+		Hash:             hash,
+		Config:           config,
+		Error:            errStr,
+		Status:           r.Status().String(),
+		CreatedAt:        r.CreatedAt,
+		StartedAt:        r.StartedAt,
+		EndedAt:          r.EndedAt,
+		Push:             push,
 	}
 }
 
@@ -13,9 +13,11 @@ import (
 type baseViewModel struct {
 	HasLogsPages     bool
 	HasPipelinePages bool
-	PublicURL        string
-	CurrentPath      string
-	Version          string
+	// redeliver endpoints are only registered with the basic auth
+	CanRedeliver bool
+	PublicURL    string
+	CurrentPath  string
+	Version      string
 }
 
 type viewModelContextKey string
@@ -52,6 +54,7 @@ func WithBaseViewModel(cfg config.Config) func(http.Handler) http.Handler {
 			model := baseViewModel{
 				HasLogsPages:     cfg.LogsDBFile != "",
 				HasPipelinePages: cfg.ActionsDBFile != "",
+				CanRedeliver:     cfg.ActionsDBFile != "" && cfg.HasBasicAuth(),
 				PublicURL:        cfg.PublicURL,
 				CurrentPath:      currentPath,
 				Version:          version.String(),
@@ -1,6 +1,7 @@
 package whreceiver
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -14,10 +15,78 @@ type WebhookPostRequest struct {
 
 type WebhookPostInfo struct {
 	DeliveryID string
-	Branch     string
-	Event      string
+	// ID of the delivery, covered by the payload signature, i.e. the Standard
+	// Webhooks webhook-id; empty if the provider doesn't sign it
+	SignedDeliveryID string
+	Branch           string
+	// tag name, if the event is for a tag ref; Branch is empty in this case
+	Tag   string
+	Event string
+	// other names of the event, still matched against the action's `on`, e.g.
+	// the old space separated names of gitlab events
+	EventAliases []string
+	// event subtype, e.g. "opened" for pull_request event; only populated by
+	// providers sending it in the payload
+	Action string
 	// commit hash-id after applying the event ("after" field of payload)
 	Hash string
+	// nil, unless it's a pull/merge request event
+	PullRequest *PullRequestInfo
+	// nil, unless it's a release event
+	Release *ReleaseInfo
+	// nil, unless it's a push event
+	Push *PushInfo
+	// paths added, modified or removed by the pushed commits; nil, unless
+	// it's a push event of a provider sending them
+	ChangedFiles []string
+	// messages of the pushed commits, oldest first; nil, unless it's a push
+	// event of a provider sending them
+	CommitMessages []string
+	// provider capped the pushed commits list, so ChangedFiles and
+	// CommitMessages are incomplete
+	ChangedFilesTruncated bool
+}
+
+// PushInfo is the extra context of a push event.
+type PushInfo struct {
+	// login of the user who pushed
+	Pusher string
+	// commit hash-id before the push ("before" field of payload)
+	Before string
+	// message of the head commit
+	CommitMessage string
+	// total number of pushed commits
+	CommitCount int
+	// URL of the before...after diff, if provider sends it
+	CompareURL string
+	// the push created the ref, i.e. before is the zero hash
+	Created bool
+	// the push deleted the ref, i.e. after is the zero hash
+	Deleted bool
+	// the push was forced; only reported by github and bitbucket
+	Forced bool
+}
+
+// PullRequestInfo is the pull request (merge request in gitlab) data of
+// a pull request event. For such events WebhookPostInfo Branch and Hash are
+// empty, so the actions filtered by branch don't run on the pull requests,
+// which head commit may come from an untrusted fork.
+type PullRequestInfo struct {
+	Number     int
+	Title      string
+	Author     string
+	HeadBranch string
+	BaseBranch string
+	HeadHash   string
+}
+
+// ReleaseInfo is the release data of a release event. For such events
+// WebhookPostInfo Tag is the release tag.
+type ReleaseInfo struct {
+	Tag        string
+	Name       string
+	Prerelease bool
+	Draft      bool
 }
 
 type ReceiverCapabilities struct {
@@ -42,10 +111,22 @@ func New(project config.Project) Receiver {
 	switch project.GitProvider {
 	case "gitea":
 		receiver = GiteaReceiver{project}
+	case "forgejo":
+		receiver = ForgejoReceiver{project}
+	case "gogs":
+		receiver = GogsReceiver{project}
 	case "github":
 		receiver = GithubReceiver{project}
 	case "gitlab":
 		receiver = GitlabReceiver{project}
+	case "bitbucket":
+		receiver = BitbucketReceiver{project}
+	case "bitbucket-server":
+		receiver = BitbucketServerReceiver{project}
+	case "azure-devops":
+		receiver = AzureDevopsReceiver{project}
+	case config.CustomGitProvider:
+		receiver = CustomReceiver{project}
 	default:
 		panic(fmt.Sprintf("unknown receiver provided: %q", project.GitProvider))
 	}
@@ -60,11 +141,60 @@ func Capabilities(project config.Project) ReceiverCapabilities {
 	switch project.GitProvider {
 	case "gitea":
 		return GiteaReceiver{project}.GetCapabilities()
+	case "forgejo":
+		return ForgejoReceiver{project}.GetCapabilities()
+	case "gogs":
+		return GogsReceiver{project}.GetCapabilities()
 	case "github":
 		return GithubReceiver{project}.GetCapabilities()
 	case "gitlab":
 		return GitlabReceiver{project}.GetCapabilities()
+	case "bitbucket":
+		return BitbucketReceiver{project}.GetCapabilities()
+	case "bitbucket-server":
+		return BitbucketServerReceiver{project}.GetCapabilities()
+	case "azure-devops":
+		return AzureDevopsReceiver{project}.GetCapabilities()
+	case config.CustomGitProvider:
+		return CustomReceiver{project}.GetCapabilities()
 	default:
 		return ReceiverCapabilities{}
 	}
 }
+
+// AuthorizeAny checks the request against every accepted authorization value,
+// returning the index of the first one that matched, or -1 if none did.
+// All of the values are checked, so the response time doesn't depend on
+// which one matched.
+func AuthorizeAny(rcvr Receiver, req WebhookPostRequest, auths []string) (int, error) {
+	return matchAny(auths, func(auth string) (bool, error) {
+		return rcvr.Authorize(req, auth)
+	})
+}
+
+// VerifySignatureAny is the same as [AuthorizeAny], but for the payload
+// signature secrets.
+func VerifySignatureAny(rcvr Receiver, req WebhookPostRequest, secrets []string) (int, error) {
+	return matchAny(secrets, func(secret string) (bool, error) {
+		return rcvr.VerifySignature(req, secret)
+	})
+}
+
+func matchAny(values []string, match func(value string) (bool, error)) (int, error) {
+	matched := -1
+	var errs []error
+	for i, value := range values {
+		ok, err := match(value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok && matched == -1 {
+			matched = i
+		}
+	}
+	if matched == -1 {
+		return matched, errors.Join(errs...)
+	}
+	return matched, nil
+}
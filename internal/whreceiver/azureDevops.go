@@ -0,0 +1,109 @@
+package whreceiver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/cryptoutils"
+)
+
+var _ Receiver = (*AzureDevopsReceiver)(nil)
+
+// AzureDevopsReceiver handles Azure DevOps Repos service hooks ("Web Hooks"
+// consumer). Azure doesn't sign its payloads, requests are authorized with
+// either basic auth credentials or a custom Authorization header instead.
+type AzureDevopsReceiver struct {
+	project config.Project
+}
+
+func (rcvr AzureDevopsReceiver) GetCapabilities() ReceiverCapabilities {
+	return ReceiverCapabilities{
+		CanAuthorize:       true,
+		CanVerifySignature: false,
+		HasPing:            false,
+	}
+}
+
+// Authorize accepts either the verbatim Authorization header contents (set
+// through the service hook's "HTTP headers" field), or "username:password"
+// basic auth credentials (set through the service hook's basic auth fields).
+func (rcvr AzureDevopsReceiver) Authorize(req WebhookPostRequest, auth string) (bool, error) {
+	authorizationHeader := req.Headers.Get("Authorization")
+	basicAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
+	// evaluating both, so we don't leak which form was used through timing
+	rawMatch := cryptoutils.NewConstantTimeComparer(auth).Eq(authorizationHeader)
+	basicMatch := cryptoutils.NewConstantTimeComparer(basicAuth).Eq(authorizationHeader)
+	return rawMatch || basicMatch, nil
+}
+
+func (rcvr AzureDevopsReceiver) VerifySignature(req WebhookPostRequest, secret string) (bool, error) {
+	return false, ErrSignNotSupported
+}
+
+func (rcvr AzureDevopsReceiver) IsPingRequest(req WebhookPostRequest) bool {
+	return false
+}
+
+// azureDevopsEvents maps Azure DevOps event types onto the event names used by
+// the other receivers. Types not listed here are passed through as-is, e.g.
+// "git.pullrequest.created".
+var azureDevopsEvents = map[string]string{
+	"git.push": "push",
+}
+
+func getAzureDevopsEvent(eventType string) string {
+	if event, ok := azureDevopsEvents[eventType]; ok {
+		return event
+	}
+	return eventType
+}
+
+type azureDevopsWebhookPayload struct {
+	ID        string `json:"id"`
+	EventType string `json:"eventType"`
+	Resource  struct {
+		RefUpdates []struct {
+			Name        string `json:"name"`
+			OldObjectID string `json:"oldObjectId"`
+			NewObjectID string `json:"newObjectId"`
+		} `json:"refUpdates"`
+		Repository struct {
+			Name    string `json:"name"`
+			Project struct {
+				Name string `json:"name"`
+			} `json:"project"`
+		} `json:"repository"`
+	} `json:"resource"`
+}
+
+func (rcvr AzureDevopsReceiver) GetWebhookInfo(req WebhookPostRequest) (*WebhookPostInfo, error) {
+	var postInfo WebhookPostInfo
+	var whPayload azureDevopsWebhookPayload
+	if err := json.Unmarshal(req.Payload, &whPayload); err != nil {
+		return nil, err
+	}
+	repository := whPayload.Resource.Repository
+	repo := repository.Project.Name + "/" + repository.Name
+	if repo != rcvr.project.Repo {
+		return nil, IncorrectRepoError{Expected: rcvr.project.Repo, Actual: repo}
+	}
+
+	// Same as with Bitbucket, only reporting the first updated ref.
+	if len(whPayload.Resource.RefUpdates) > 0 {
+		refUpdate := whPayload.Resource.RefUpdates[0]
+		postInfo.Branch = getBranchFromRefName(refUpdate.Name)
+		postInfo.Tag = getTagFromRefName(refUpdate.Name)
+		postInfo.Hash = refUpdate.NewObjectID
+		postInfo.Push = &PushInfo{
+			Before:  refUpdate.OldObjectID,
+			Created: isZeroHash(refUpdate.OldObjectID),
+			Deleted: isZeroHash(refUpdate.NewObjectID),
+		}
+	}
+
+	postInfo.Event = getAzureDevopsEvent(whPayload.EventType)
+	// Azure sends no delivery header, using the notification event id instead
+	postInfo.DeliveryID = whPayload.ID
+	return &postInfo, nil
+}
@@ -0,0 +1,120 @@
+package whreceiver
+
+import (
+	"encoding/json"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+)
+
+var _ Receiver = (*BitbucketReceiver)(nil)
+
+// BitbucketReceiver handles Bitbucket Cloud webhooks.
+type BitbucketReceiver struct {
+	project config.Project
+}
+
+func (rcvr BitbucketReceiver) GetCapabilities() ReceiverCapabilities {
+	return ReceiverCapabilities{
+		CanAuthorize:       false,
+		CanVerifySignature: true,
+		HasPing:            false,
+	}
+}
+
+func (rcvr BitbucketReceiver) Authorize(req WebhookPostRequest, auth string) (bool, error) {
+	return false, ErrAuthNotSupported
+}
+
+func (rcvr BitbucketReceiver) VerifySignature(req WebhookPostRequest, secret string) (bool, error) {
+	signature := req.Headers.Get("X-Hub-Signature")
+	return verifyPrefixedPayloadSignature(req.Payload, signature, secret, "Bitbucket")
+}
+
+func (rcvr BitbucketReceiver) IsPingRequest(req WebhookPostRequest) bool {
+	return false
+}
+
+// bitbucketEvents maps Bitbucket event keys onto the event names used by the
+// other receivers, so the same `on:` values work across providers. Keys not
+// listed here are passed through as-is, e.g. "pullrequest:created".
+var bitbucketEvents = map[string]string{
+	"repo:push": "push",
+}
+
+func getBitbucketEvent(eventKey string) string {
+	if event, ok := bitbucketEvents[eventKey]; ok {
+		return event
+	}
+	return eventKey
+}
+
+type bitbucketRef struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Target struct {
+		Hash    string `json:"hash"`
+		Message string `json:"message"`
+	} `json:"target"`
+}
+
+type bitbucketWebhookPayload struct {
+	Push struct {
+		Changes []struct {
+			Old     *bitbucketRef `json:"old"`
+			New     *bitbucketRef `json:"new"`
+			Created bool          `json:"created"`
+			Closed  bool          `json:"closed"` // the ref was deleted
+			Forced  bool          `json:"forced"`
+		} `json:"changes"`
+	} `json:"push"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+func (rcvr BitbucketReceiver) GetWebhookInfo(req WebhookPostRequest) (*WebhookPostInfo, error) {
+	var postInfo WebhookPostInfo
+	var whPayload bitbucketWebhookPayload
+	if err := json.Unmarshal(req.Payload, &whPayload); err != nil {
+		return nil, err
+	}
+	repo := whPayload.Repository.FullName
+	if repo != rcvr.project.Repo {
+		return nil, IncorrectRepoError{Expected: rcvr.project.Repo, Actual: repo}
+	}
+
+	// A single push may update several refs, but the other receivers only
+	// report one, so we're sticking with the first change here.
+	if len(whPayload.Push.Changes) > 0 {
+		change := whPayload.Push.Changes[0]
+		// new is null when the branch was removed
+		ref := change.New
+		if ref == nil {
+			ref = change.Old
+		}
+		if ref != nil {
+			switch ref.Type {
+			case "branch":
+				postInfo.Branch = ref.Name
+			case "tag":
+				postInfo.Tag = ref.Name
+			}
+		}
+		postInfo.Push = &PushInfo{
+			Created: change.Created,
+			Deleted: change.Closed,
+			Forced:  change.Forced,
+		}
+		if change.New != nil {
+			postInfo.Hash = change.New.Target.Hash
+			postInfo.Push.CommitMessage = change.New.Target.Message
+		}
+		if change.Old != nil {
+			postInfo.Push.Before = change.Old.Target.Hash
+		}
+	}
+
+	postInfo.Event = getBitbucketEvent(req.Headers.Get("X-Event-Key"))
+	postInfo.DeliveryID = req.Headers.Get("X-Request-UUID")
+	return &postInfo, nil
+}
@@ -0,0 +1,111 @@
+package whreceiver
+
+import (
+	"encoding/json"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+)
+
+var _ Receiver = (*BitbucketServerReceiver)(nil)
+
+// BitbucketServerReceiver handles Bitbucket Data Center (formerly Server)
+// webhooks, which differ from the Bitbucket Cloud ones both in headers and in
+// the payload shape.
+type BitbucketServerReceiver struct {
+	project config.Project
+}
+
+func (rcvr BitbucketServerReceiver) GetCapabilities() ReceiverCapabilities {
+	return ReceiverCapabilities{
+		CanAuthorize:       false,
+		CanVerifySignature: true,
+		HasPing:            true,
+	}
+}
+
+func (rcvr BitbucketServerReceiver) Authorize(req WebhookPostRequest, auth string) (bool, error) {
+	return false, ErrAuthNotSupported
+}
+
+func (rcvr BitbucketServerReceiver) VerifySignature(req WebhookPostRequest, secret string) (bool, error) {
+	signature := req.Headers.Get("X-Hub-Signature")
+	return verifyPrefixedPayloadSignature(req.Payload, signature, secret, "Bitbucket")
+}
+
+const bitbucketServerPingEvent = "diagnostics:ping"
+
+func (rcvr BitbucketServerReceiver) IsPingRequest(req WebhookPostRequest) bool {
+	return req.Headers.Get("X-Event-Key") == bitbucketServerPingEvent
+}
+
+// bitbucketServerEvents maps Bitbucket Data Center event keys onto the event
+// names used by the other receivers. Keys not listed here are passed through
+// as-is, e.g. "pr:opened".
+var bitbucketServerEvents = map[string]string{
+	"repo:refs_changed":      "push",
+	bitbucketServerPingEvent: "ping",
+}
+
+func getBitbucketServerEvent(eventKey string) string {
+	if event, ok := bitbucketServerEvents[eventKey]; ok {
+		return event
+	}
+	return eventKey
+}
+
+type bitbucketServerWebhookPayload struct {
+	Repository struct {
+		Slug    string `json:"slug"`
+		Project struct {
+			Key string `json:"key"`
+		} `json:"project"`
+	} `json:"repository"`
+	Changes []struct {
+		Ref struct {
+			DisplayID string `json:"displayId"`
+			Type      string `json:"type"`
+		} `json:"ref"`
+		FromHash string `json:"fromHash"`
+		ToHash   string `json:"toHash"`
+		// "ADD" | "DELETE" | "UPDATE"
+		Type string `json:"type"`
+	} `json:"changes"`
+}
+
+func (rcvr BitbucketServerReceiver) GetWebhookInfo(req WebhookPostRequest) (*WebhookPostInfo, error) {
+	var postInfo WebhookPostInfo
+	postInfo.Event = getBitbucketServerEvent(req.Headers.Get("X-Event-Key"))
+	postInfo.DeliveryID = req.Headers.Get("X-Request-Id")
+
+	// "Test connection" ping payload has no repository info in it
+	if rcvr.IsPingRequest(req) {
+		return &postInfo, nil
+	}
+
+	var whPayload bitbucketServerWebhookPayload
+	if err := json.Unmarshal(req.Payload, &whPayload); err != nil {
+		return nil, err
+	}
+	repo := whPayload.Repository.Project.Key + "/" + whPayload.Repository.Slug
+	if repo != rcvr.project.Repo {
+		return nil, IncorrectRepoError{Expected: rcvr.project.Repo, Actual: repo}
+	}
+
+	// Same as with Bitbucket Cloud, only reporting the first changed ref.
+	if len(whPayload.Changes) > 0 {
+		change := whPayload.Changes[0]
+		switch change.Ref.Type {
+		case "BRANCH":
+			postInfo.Branch = change.Ref.DisplayID
+		case "TAG":
+			postInfo.Tag = change.Ref.DisplayID
+		}
+		postInfo.Hash = change.ToHash
+		postInfo.Push = &PushInfo{
+			Before:  change.FromHash,
+			Created: change.Type == "ADD",
+			Deleted: change.Type == "DELETE",
+		}
+	}
+	return &postInfo, nil
+}
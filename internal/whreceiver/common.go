@@ -1,11 +1,15 @@
 package whreceiver
 
 import (
+	"cmp"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
+	"net/url"
 	"strings"
 
 	"github.com/religiosa1/git-webhook-receiver/internal/cryptoutils"
@@ -13,9 +17,159 @@ import (
 
 // CommonWebhookPayload is common significant payload for push events for Gitea and Github
 type CommonWebhookPayload struct {
-	Ref        string            `json:"ref"`
-	After      string            `json:"after"`
-	Repository CommonWebhookRepo `json:"repository"`
+	Ref          string                `json:"ref"`
+	Before       string                `json:"before"`
+	After        string                `json:"after"`
+	Compare      string                `json:"compare"`       // github
+	CompareURL   string                `json:"compare_url"`   // gitea
+	TotalCommits int                   `json:"total_commits"` // gitea
+	Created      bool                  `json:"created"`       // github
+	Deleted      bool                  `json:"deleted"`       // github
+	Forced       bool                  `json:"forced"`        // github
+	Commits      []CommonWebhookCommit `json:"commits"`
+	HeadCommit   *CommonWebhookCommit  `json:"head_commit"`
+	Pusher       struct {
+		Login string `json:"login"`
+		// github only sends name and email of the pusher, name being the login
+		Name string `json:"name"`
+	} `json:"pusher"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+	Action      string                    `json:"action"`
+	PullRequest *CommonWebhookPullRequest `json:"pull_request"`
+	Release     *CommonWebhookRelease     `json:"release"`
+	Repository  CommonWebhookRepo         `json:"repository"`
+}
+
+// CommonWebhookPullRequest is pull_request field of pull request events for Gitea and Github
+type CommonWebhookPullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	User   struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Head struct {
+		Ref string `json:"ref"`
+		Sha string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+// CommonWebhookRelease is release field of release events for Gitea and Github
+type CommonWebhookRelease struct {
+	TagName    string `json:"tag_name"`
+	Name       string `json:"name"`
+	Prerelease bool   `json:"prerelease"`
+	Draft      bool   `json:"draft"`
+}
+
+func (r CommonWebhookRelease) toReleaseInfo() *ReleaseInfo {
+	return &ReleaseInfo{
+		Tag:        r.TagName,
+		Name:       r.Name,
+		Prerelease: r.Prerelease,
+		Draft:      r.Draft,
+	}
+}
+
+type CommonWebhookCommit struct {
+	ID       string   `json:"id"`
+	Message  string   `json:"message"`
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+// githubMaxCommits is the cap of github push payload commits list; github
+// doesn't send the total count, so reaching the cap means it's truncated.
+const githubMaxCommits = 2048
+
+// getChangedFiles returns the changed files of a push event and whether the
+// commits list was truncated. Files are nil if it's not a push event.
+func (p CommonWebhookPayload) getChangedFiles() (files []string, truncated bool) {
+	if p.Before == "" && p.After == "" {
+		return nil, false
+	}
+	truncated = p.TotalCommits > len(p.Commits) || len(p.Commits) >= githubMaxCommits
+	return collectChangedFiles(p.Commits), truncated
+}
+
+// getCommitMessages returns the messages of the pushed commits, or nil if
+// it's not a push event.
+func (p CommonWebhookPayload) getCommitMessages() []string {
+	if p.Before == "" && p.After == "" {
+		return nil
+	}
+	return collectCommitMessages(p.Commits)
+}
+
+// collectChangedFiles returns a deduplicated list of the files touched by
+// the commits. The list is non-nil even if there are no commits.
+func collectChangedFiles(commits []CommonWebhookCommit) []string {
+	files := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, commit := range commits {
+		for _, list := range [][]string{commit.Added, commit.Removed, commit.Modified} {
+			for _, file := range list {
+				if _, ok := seen[file]; !ok {
+					seen[file] = struct{}{}
+					files = append(files, file)
+				}
+			}
+		}
+	}
+	return files
+}
+
+// isZeroHash checks if the commit hash is all zeros, which is sent as the
+// before hash of created refs and after hash of deleted ones.
+func isZeroHash(hash string) bool {
+	return hash != "" && strings.Trim(hash, "0") == ""
+}
+
+// collectCommitMessages returns the messages of the commits. The list is
+// non-nil even if there are no commits.
+func collectCommitMessages(commits []CommonWebhookCommit) []string {
+	messages := make([]string, len(commits))
+	for i, commit := range commits {
+		messages[i] = commit.Message
+	}
+	return messages
+}
+
+// getPushInfo returns push event context, or nil if it's not a push event.
+func (p CommonWebhookPayload) getPushInfo() *PushInfo {
+	if p.Before == "" && p.After == "" {
+		return nil
+	}
+	push := PushInfo{
+		Pusher:      p.Sender.Login,
+		Before:      p.Before,
+		CommitCount: p.TotalCommits,
+		CompareURL:  p.Compare,
+		// gitea and gogs don't send the flags, but zero hashes work for all
+		Created: p.Created || isZeroHash(p.Before),
+		Deleted: p.Deleted || isZeroHash(p.After),
+		Forced:  p.Forced,
+	}
+	if push.Pusher == "" {
+		push.Pusher = cmp.Or(p.Pusher.Login, p.Pusher.Name)
+	}
+	if push.CommitCount == 0 {
+		push.CommitCount = len(p.Commits)
+	}
+	if push.CompareURL == "" {
+		push.CompareURL = p.CompareURL
+	}
+	if p.HeadCommit != nil {
+		push.CommitMessage = p.HeadCommit.Message
+	} else if len(p.Commits) > 0 {
+		push.CommitMessage = p.Commits[len(p.Commits)-1].Message
+	}
+	return &push
 }
 
 type CommonWebhookRepo struct {
@@ -33,9 +187,64 @@ func getJSONPayloadInfo(payload []byte, repo string) (*WebhookPostInfo, error) {
 		return nil, IncorrectRepoError{Expected: repo, Actual: whPayload.Repository.FullName}
 	}
 
+	if pr := whPayload.PullRequest; pr != nil {
+		return &WebhookPostInfo{
+			Action: whPayload.Action,
+			PullRequest: &PullRequestInfo{
+				Number:     pr.Number,
+				Title:      pr.Title,
+				Author:     pr.User.Login,
+				HeadBranch: pr.Head.Ref,
+				BaseBranch: pr.Base.Ref,
+				HeadHash:   pr.Head.Sha,
+			},
+		}, nil
+	}
+
+	if release := whPayload.Release; release != nil {
+		return &WebhookPostInfo{
+			Tag:     release.TagName,
+			Action:  whPayload.Action,
+			Release: release.toReleaseInfo(),
+		}, nil
+	}
+
 	branch := getBranchFromRefName(whPayload.Ref)
+	tag := getTagFromRefName(whPayload.Ref)
 	hash := whPayload.After
-	return &WebhookPostInfo{Branch: branch, Hash: hash}, nil
+	changedFiles, truncated := whPayload.getChangedFiles()
+	return &WebhookPostInfo{
+		Branch:                branch,
+		Tag:                   tag,
+		Action:                whPayload.Action,
+		Hash:                  hash,
+		Push:                  whPayload.getPushInfo(),
+		ChangedFiles:          changedFiles,
+		CommitMessages:        whPayload.getCommitMessages(),
+		ChangedFilesTruncated: truncated,
+	}, nil
+}
+
+// JSONPayload returns the JSON payload of the request. GitHub webhooks
+// can be configured to send application/x-www-form-urlencoded body, with
+// the JSON in the "payload" form field.
+func JSONPayload(req WebhookPostRequest) ([]byte, error) {
+	contentType := req.Headers.Get("Content-Type")
+	if contentType == "" {
+		return req.Payload, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "application/x-www-form-urlencoded" {
+		return req.Payload, nil
+	}
+	form, err := url.ParseQuery(string(req.Payload))
+	if err != nil {
+		return nil, fmt.Errorf("malformed form-encoded payload: %w", err)
+	}
+	if !form.Has("payload") {
+		return nil, errors.New("form-encoded payload has no 'payload' field")
+	}
+	return []byte(form.Get("payload")), nil
 }
 
 func verifyPayloadSignature(payload []byte, signature string, secret string) (bool, error) {
@@ -49,19 +258,48 @@ func verifyPayloadSignature(payload []byte, signature string, secret string) (bo
 	return cryptoutils.NewConstantTimeComparerBytes(headSig).EqBytes((payloadSignature)), nil
 }
 
+const signaturePrefix = "sha256="
+
+// verifyPrefixedPayloadSignature verifies a "sha256=<hex>" signature header
+// value, as sent by GitHub and Bitbucket. providerName is only used in the
+// error message.
+func verifyPrefixedPayloadSignature(payload []byte, signature string, secret string, providerName string) (bool, error) {
+	if signature == "" || signature == signaturePrefix {
+		return false, nil
+	}
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false, fmt.Errorf("malformed %s signature: it must start with '"+signaturePrefix+"', got %s instead", providerName, signature)
+	}
+	signature = signature[len(signaturePrefix):]
+	return verifyPayloadSignature(payload, signature, secret)
+}
+
 func getBranchFromRefName(ref string) string {
+	refType, refName := parseRefName(ref)
+	if refType != "heads" {
+		return ""
+	}
+	return refName
+}
+
+func getTagFromRefName(ref string) string {
+	refType, refName := parseRefName(ref)
+	if refType != "tags" {
+		return ""
+	}
+	return refName
+}
+
+// parseRefName splits "refs/{type}/{name}" ref into its type and name.
+func parseRefName(ref string) (refType string, refName string) {
 	parts := strings.Split(ref, "/")
-	var refType, refName string
 	if len(parts) >= 2 {
 		refType = parts[1]
 	}
 	if len(parts) >= 3 {
 		refName = strings.Join(parts[2:], "/")
 	}
-	if refType != "heads" {
-		return ""
-	}
-	return refName
+	return refType, refName
 }
 
 func getPayloadSignature(secret string, payload []byte) []byte {
@@ -0,0 +1,152 @@
+package whreceiver
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/cryptoutils"
+	"github.com/religiosa1/git-webhook-receiver/internal/jsonpointer"
+)
+
+var _ Receiver = (*CustomReceiver)(nil)
+
+// CustomReceiver is a receiver described in the project config, see
+// [config.CustomProvider].
+type CustomReceiver struct {
+	project config.Project
+}
+
+func (rcvr CustomReceiver) GetCapabilities() ReceiverCapabilities {
+	provider := rcvr.project.CustomProvider
+	return ReceiverCapabilities{
+		CanAuthorize:       provider.AuthorizationHeader != "",
+		CanVerifySignature: provider.SignatureHeader != "",
+		HasPing:            false,
+	}
+}
+
+func (rcvr CustomReceiver) Authorize(req WebhookPostRequest, auth string) (bool, error) {
+	provider := rcvr.project.CustomProvider
+	if provider.AuthorizationHeader == "" {
+		return false, ErrAuthNotSupported
+	}
+	authorizationHeader := req.Headers.Get(provider.AuthorizationHeader)
+	return cryptoutils.NewConstantTimeComparer(auth).Eq(authorizationHeader), nil
+}
+
+func (rcvr CustomReceiver) VerifySignature(req WebhookPostRequest, secret string) (bool, error) {
+	provider := rcvr.project.CustomProvider
+	if provider.SignatureHeader == "" {
+		return false, ErrSignNotSupported
+	}
+	signature := req.Headers.Get(provider.SignatureHeader)
+	if signature == "" || signature == provider.SignaturePrefix {
+		return false, nil
+	}
+	if !strings.HasPrefix(signature, provider.SignaturePrefix) {
+		return false, fmt.Errorf("malformed signature: it must start with '%s', got %s instead", provider.SignaturePrefix, signature)
+	}
+	signature = signature[len(provider.SignaturePrefix):]
+
+	var headSig []byte
+	var err error
+	switch provider.SignatureEncoding {
+	case "base64":
+		headSig, err = base64.StdEncoding.DecodeString(signature)
+	default:
+		headSig, err = hex.DecodeString(signature)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	h := hmac.New(getCustomSignatureHash(provider.SignatureAlgorithm), []byte(secret))
+	h.Write(req.Payload)
+	payloadSignature := h.Sum(nil)
+
+	return cryptoutils.NewConstantTimeComparerBytes(headSig).EqBytes(payloadSignature), nil
+}
+
+func getCustomSignatureHash(algorithm string) func() hash.Hash {
+	switch algorithm {
+	case "sha1":
+		return sha1.New
+	case "sha512":
+		return sha512.New
+	default:
+		return sha256.New
+	}
+}
+
+func (rcvr CustomReceiver) IsPingRequest(req WebhookPostRequest) bool {
+	return false
+}
+
+func (rcvr CustomReceiver) GetWebhookInfo(req WebhookPostRequest) (*WebhookPostInfo, error) {
+	provider := rcvr.project.CustomProvider
+	var postInfo WebhookPostInfo
+
+	payload, err := jsonpointer.Unmarshal(req.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := getCustomPayloadValue(payload, provider.RepoPath)
+	if err != nil {
+		return nil, err
+	}
+	if repo != rcvr.project.Repo {
+		return nil, IncorrectRepoError{Expected: rcvr.project.Repo, Actual: repo}
+	}
+
+	ref, err := getCustomPayloadValue(payload, provider.RefPath)
+	if err != nil {
+		return nil, err
+	}
+	// Some forges send a bare branch name instead of a full ref
+	if strings.HasPrefix(ref, "refs/") {
+		postInfo.Branch = getBranchFromRefName(ref)
+		postInfo.Tag = getTagFromRefName(ref)
+	} else {
+		postInfo.Branch = ref
+	}
+
+	postInfo.Hash, err = getCustomPayloadValue(payload, provider.HashPath)
+	if err != nil {
+		return nil, err
+	}
+	// without the before hash only ref deletions can be detected
+	if isZeroHash(postInfo.Hash) {
+		postInfo.Push = &PushInfo{Deleted: true}
+	}
+
+	if provider.EventHeader != "" {
+		postInfo.Event = req.Headers.Get(provider.EventHeader)
+	}
+	if provider.DeliveryHeader != "" {
+		postInfo.DeliveryID = req.Headers.Get(provider.DeliveryHeader)
+	}
+	return &postInfo, nil
+}
+
+// getCustomPayloadValue resolves the JSON pointer path against the payload,
+// returning an empty string if path is empty or value doesn't exist.
+func getCustomPayloadValue(payload any, path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	pointer, err := jsonpointer.Parse(path)
+	if err != nil {
+		return "", err
+	}
+	value, _ := pointer.GetString(payload)
+	return value, nil
+}
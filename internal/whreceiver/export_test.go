@@ -0,0 +1,15 @@
+package whreceiver
+
+import (
+	"testing"
+	"time"
+)
+
+// SetTimeNow freezes the clock used in timestamp tolerance checks for the
+// duration of the test.
+func SetTimeNow(t testing.TB, now time.Time) {
+	t.Helper()
+	prev := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = prev })
+}
@@ -0,0 +1,63 @@
+package whreceiver
+
+import (
+	"net/http"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/cryptoutils"
+)
+
+var _ Receiver = (*ForgejoReceiver)(nil)
+
+// ForgejoReceiver handles Forgejo webhooks. Forgejo sends its own X-Forgejo-*
+// headers alongside the Gitea-compatible X-Gitea-* ones, which are planned to
+// be dropped, so we're reading the native ones first, falling back to Gitea's.
+// The payload is the same as Gitea's.
+type ForgejoReceiver struct {
+	project config.Project
+}
+
+func (rcvr ForgejoReceiver) GetCapabilities() ReceiverCapabilities {
+	return ReceiverCapabilities{
+		CanAuthorize:       true,
+		CanVerifySignature: true,
+		HasPing:            false,
+	}
+}
+
+func (rcvr ForgejoReceiver) Authorize(req WebhookPostRequest, auth string) (bool, error) {
+	authorizationHeader := req.Headers.Get("Authorization")
+	return cryptoutils.NewConstantTimeComparer(auth).Eq(authorizationHeader), nil
+}
+
+func (rcvr ForgejoReceiver) VerifySignature(req WebhookPostRequest, secret string) (bool, error) {
+	signature := getForgejoHeader(req.Headers, "Signature")
+	if signature == "" {
+		return false, nil
+	}
+	return verifyPayloadSignature(req.Payload, signature, secret)
+}
+
+func (rcvr ForgejoReceiver) GetWebhookInfo(req WebhookPostRequest) (*WebhookPostInfo, error) {
+	postInfo, err := getJSONPayloadInfo(req.Payload, rcvr.project.Repo)
+	if err != nil {
+		return nil, err
+	}
+	postInfo.Event = getForgejoHeader(req.Headers, "Event")
+	postInfo.DeliveryID = getForgejoHeader(req.Headers, "Delivery")
+
+	return postInfo, nil
+}
+
+func (rcvr ForgejoReceiver) IsPingRequest(req WebhookPostRequest) bool {
+	return false
+}
+
+// getForgejoHeader returns X-Forgejo-{name} header value, or the value of
+// its Gitea-compatible X-Gitea-{name} counterpart if it's missing.
+func getForgejoHeader(headers http.Header, name string) string {
+	if value := headers.Get("X-Forgejo-" + name); value != "" {
+		return value
+	}
+	return headers.Get("X-Gitea-" + name)
+}
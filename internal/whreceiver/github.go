@@ -1,10 +1,14 @@
 package whreceiver
 
 import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"strings"
 
 	"github.com/religiosa1/git-webhook-receiver/internal/config"
+	"github.com/religiosa1/git-webhook-receiver/internal/cryptoutils"
 )
 
 var _ Receiver = (*GithubReceiver)(nil)
@@ -25,22 +29,41 @@ func (rcvr GithubReceiver) Authorize(req WebhookPostRequest, auth string) (bool,
 	return false, ErrAuthNotSupported
 }
 
-const signaturePrefix = "sha256="
+// Older GitHub Enterprise instances only send sha1 X-Hub-Signature, it's only
+// checked if the project explicitly allows it and sha256 signature is missing.
 
 func (rcvr GithubReceiver) VerifySignature(req WebhookPostRequest, secret string) (bool, error) {
 	signature := req.Headers.Get("X-Hub-Signature-256")
-	if signature == "" || signature == signaturePrefix {
+	if signature == "" && rcvr.project.AllowSha1Signature {
+		return verifyGithubSha1Signature(req.Payload, req.Headers.Get("X-Hub-Signature"), secret)
+	}
+	return verifyPrefixedPayloadSignature(req.Payload, signature, secret, "GitHub")
+}
+
+const sha1SignaturePrefix = "sha1="
+
+func verifyGithubSha1Signature(payload []byte, signature string, secret string) (bool, error) {
+	if signature == "" || signature == sha1SignaturePrefix {
 		return false, nil
 	}
-	if !strings.HasPrefix(signature, signaturePrefix) {
-		return false, fmt.Errorf("malformed GitHub signature: it must start with '"+signaturePrefix+"', got %s instead", signature)
+	if !strings.HasPrefix(signature, sha1SignaturePrefix) {
+		return false, fmt.Errorf("malformed GitHub signature: it must start with '"+sha1SignaturePrefix+"', got %s instead", signature)
 	}
-	signature = signature[len(signaturePrefix):]
-	return verifyPayloadSignature(req.Payload, signature, secret)
+	headSig, err := hex.DecodeString(signature[len(sha1SignaturePrefix):])
+	if err != nil {
+		return false, fmt.Errorf("failed to decode signature: %w", err)
+	}
+	h := hmac.New(sha1.New, []byte(secret))
+	h.Write(payload)
+	return cryptoutils.NewConstantTimeComparerBytes(headSig).EqBytes(h.Sum(nil)), nil
 }
 
 func (rcvr GithubReceiver) GetWebhookInfo(req WebhookPostRequest) (*WebhookPostInfo, error) {
-	postInfo, err := getJSONPayloadInfo(req.Payload, rcvr.project.Repo)
+	payload, err := JSONPayload(req)
+	if err != nil {
+		return nil, err
+	}
+	postInfo, err := getJSONPayloadInfo(payload, rcvr.project.Repo)
 	if err != nil {
 		return nil, err
 	}
@@ -18,7 +18,7 @@ type GitlabReceiver struct {
 func (rcvr GitlabReceiver) GetCapabilities() ReceiverCapabilities {
 	return ReceiverCapabilities{
 		CanAuthorize:       true,
-		CanVerifySignature: false,
+		CanVerifySignature: true,
 		HasPing:            false,
 	}
 }
@@ -28,10 +28,12 @@ func (rcvr GitlabReceiver) Authorize(req WebhookPostRequest, auth string) (bool,
 	return cryptoutils.NewConstantTimeComparer(auth).Eq(authorizationHeader), nil
 }
 
+// GitLab signs the payload only if the webhook has a signing token, which
+// follows the Standard Webhooks spec.
 // https://gitlab.com/gitlab-org/gitlab/-/issues/19367
 
 func (rcvr GitlabReceiver) VerifySignature(req WebhookPostRequest, secret string) (bool, error) {
-	return false, ErrSignNotSupported
+	return verifyStandardWebhooksSignature(req, secret)
 }
 
 func (rcvr GitlabReceiver) IsPingRequest(req WebhookPostRequest) bool {
@@ -41,11 +43,39 @@ func (rcvr GitlabReceiver) IsPingRequest(req WebhookPostRequest) bool {
 var gitlabEventSuffix = " Hook"
 
 type gitlabWebhookPayload struct {
-	Ref     string `json:"ref"`
-	After   string `json:"after"`
-	Project struct {
+	ObjectKind string `json:"object_kind"`
+	// push event fields
+	Ref               string                `json:"ref"`
+	Before            string                `json:"before"`
+	After             string                `json:"after"`
+	UserUsername      string                `json:"user_username"`
+	TotalCommitsCount int                   `json:"total_commits_count"`
+	Commits           []CommonWebhookCommit `json:"commits"`
+	Project           struct {
 		PathWithNamespace string `json:"path_with_namespace"`
+		WebURL            string `json:"web_url"`
 	} `json:"project"`
+	User struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	// release event fields
+	Action string `json:"action"`
+	Tag    string `json:"tag"`
+	Name   string `json:"name"`
+	Commit struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+	// merge request event fields
+	ObjectAttributes struct {
+		IID          int    `json:"iid"`
+		Title        string `json:"title"`
+		SourceBranch string `json:"source_branch"`
+		TargetBranch string `json:"target_branch"`
+		Action       string `json:"action"`
+		LastCommit   struct {
+			ID string `json:"id"`
+		} `json:"last_commit"`
+	} `json:"object_attributes"`
 }
 
 func (rcvr GitlabReceiver) GetWebhookInfo(req WebhookPostRequest) (*WebhookPostInfo, error) {
@@ -59,15 +89,80 @@ func (rcvr GitlabReceiver) GetWebhookInfo(req WebhookPostRequest) (*WebhookPostI
 		return nil, IncorrectRepoError{Expected: rcvr.project.Repo, Actual: repo}
 	}
 
-	postInfo.Branch = getBranchFromRefName(whPayload.Ref)
+	switch whPayload.ObjectKind {
+	case "merge_request":
+		mr := whPayload.ObjectAttributes
+		postInfo.Action = mr.Action
+		postInfo.PullRequest = &PullRequestInfo{
+			Number:     mr.IID,
+			Title:      mr.Title,
+			Author:     whPayload.User.Username,
+			HeadBranch: mr.SourceBranch,
+			BaseBranch: mr.TargetBranch,
+			HeadHash:   mr.LastCommit.ID,
+		}
+	case "release":
+		// gitlab has no drafts or prereleases
+		postInfo.Tag = whPayload.Tag
+		postInfo.Action = whPayload.Action
+		postInfo.Hash = whPayload.Commit.ID
+		postInfo.Release = &ReleaseInfo{
+			Tag:  whPayload.Tag,
+			Name: whPayload.Name,
+		}
+	default:
+		postInfo.Branch = getBranchFromRefName(whPayload.Ref)
+		postInfo.Tag = getTagFromRefName(whPayload.Ref)
+		postInfo.Hash = whPayload.After
+		// pipeline, note, wiki and other events aren't pushes, they keep nil
+		// changed files, so the `paths` filters aren't applied to them
+		if whPayload.isPush() {
+			postInfo.Push = whPayload.getPushInfo()
+			// gitlab caps the commits list at 20
+			postInfo.ChangedFiles = collectChangedFiles(whPayload.Commits)
+			postInfo.CommitMessages = collectCommitMessages(whPayload.Commits)
+			postInfo.ChangedFilesTruncated = whPayload.TotalCommitsCount > len(whPayload.Commits)
+		}
+	}
 
-	postInfo.Hash = whPayload.After
 	event := req.Headers.Get("X-Gitlab-Event")
 
 	if !strings.HasSuffix(event, gitlabEventSuffix) {
 		return nil, fmt.Errorf("malformed gitlab event, must end with ' Hook', got %s", event)
 	}
-	postInfo.Event = strings.ToLower(event[:len(event)-len(gitlabEventSuffix)])
+	// "Tag Push Hook" -> "tag_push", same as the payload's object_kind; the
+	// old "tag push" name is kept as an alias, so the existing configs match
+	eventName := strings.ToLower(event[:len(event)-len(gitlabEventSuffix)])
+	postInfo.Event = strings.ReplaceAll(eventName, " ", "_")
+	if eventName != postInfo.Event {
+		postInfo.EventAliases = []string{eventName}
+	}
 	postInfo.DeliveryID = req.Headers.Get("X-Gitlab-Event-UUID")
+	postInfo.SignedDeliveryID = req.Headers.Get("Webhook-Id")
 	return &postInfo, nil
 }
+
+func (p gitlabWebhookPayload) isPush() bool {
+	return p.ObjectKind == "push" || p.ObjectKind == "tag_push"
+}
+
+func (p gitlabWebhookPayload) getPushInfo() *PushInfo {
+	push := PushInfo{
+		Pusher:      p.UserUsername,
+		Before:      p.Before,
+		CommitCount: p.TotalCommitsCount,
+		Created:     isZeroHash(p.Before),
+		Deleted:     isZeroHash(p.After),
+	}
+	// commits list is capped at 20, looking for the head one
+	for _, commit := range p.Commits {
+		if commit.ID == p.After {
+			push.CommitMessage = commit.Message
+		}
+	}
+	// gitlab doesn't send the compare URL, but it's easy to build one
+	if p.Project.WebURL != "" && !push.Created && !push.Deleted {
+		push.CompareURL = p.Project.WebURL + "/-/compare/" + p.Before + "..." + p.After
+	}
+	return &push
+}
@@ -0,0 +1,88 @@
+package whreceiver
+
+import (
+	"encoding/json"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/config"
+)
+
+var _ Receiver = (*GogsReceiver)(nil)
+
+// GogsReceiver handles Gogs webhooks. Gogs is the project Gitea was forked
+// from, so the payloads are close, but Gogs can't send a custom Authorization
+// header and its create/delete events carry a bare ref name instead of a full
+// "refs/heads/..." one.
+type GogsReceiver struct {
+	project config.Project
+}
+
+func (rcvr GogsReceiver) GetCapabilities() ReceiverCapabilities {
+	return ReceiverCapabilities{
+		CanAuthorize:       false,
+		CanVerifySignature: true,
+		HasPing:            false,
+	}
+}
+
+func (rcvr GogsReceiver) Authorize(req WebhookPostRequest, auth string) (bool, error) {
+	return false, ErrAuthNotSupported
+}
+
+func (rcvr GogsReceiver) VerifySignature(req WebhookPostRequest, secret string) (bool, error) {
+	signature := req.Headers.Get("X-Gogs-Signature")
+	if signature == "" {
+		return false, nil
+	}
+	return verifyPayloadSignature(req.Payload, signature, secret)
+}
+
+func (rcvr GogsReceiver) IsPingRequest(req WebhookPostRequest) bool {
+	return false
+}
+
+type gogsWebhookPayload struct {
+	CommonWebhookPayload
+	// RefType is only present in create and delete events
+	RefType string `json:"ref_type"`
+	// Sha is only present in create event
+	Sha string `json:"sha"`
+}
+
+func (rcvr GogsReceiver) GetWebhookInfo(req WebhookPostRequest) (*WebhookPostInfo, error) {
+	var postInfo WebhookPostInfo
+	var whPayload gogsWebhookPayload
+	if err := json.Unmarshal(req.Payload, &whPayload); err != nil {
+		return nil, err
+	}
+	repo := whPayload.Repository.FullName
+	if repo != rcvr.project.Repo {
+		return nil, IncorrectRepoError{Expected: rcvr.project.Repo, Actual: repo}
+	}
+
+	switch whPayload.RefType {
+	case "":
+		postInfo.Branch = getBranchFromRefName(whPayload.Ref)
+		postInfo.Tag = getTagFromRefName(whPayload.Ref)
+		postInfo.Hash = whPayload.After
+		postInfo.Push = whPayload.getPushInfo()
+		postInfo.ChangedFiles, postInfo.ChangedFilesTruncated = whPayload.getChangedFiles()
+		postInfo.CommitMessages = whPayload.getCommitMessages()
+	case "branch":
+		postInfo.Branch = whPayload.Ref
+		postInfo.Hash = whPayload.Sha
+	case "tag":
+		postInfo.Tag = whPayload.Ref
+		postInfo.Hash = whPayload.Sha
+	default:
+		postInfo.Hash = whPayload.Sha
+	}
+	if release := whPayload.Release; release != nil {
+		postInfo.Tag = release.TagName
+		postInfo.Action = whPayload.Action
+		postInfo.Release = release.toReleaseInfo()
+	}
+
+	postInfo.Event = req.Headers.Get("X-Gogs-Event")
+	postInfo.DeliveryID = req.Headers.Get("X-Gogs-Delivery")
+	return &postInfo, nil
+}
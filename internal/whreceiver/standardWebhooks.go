@@ -0,0 +1,80 @@
+package whreceiver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/religiosa1/git-webhook-receiver/internal/cryptoutils"
+)
+
+// StandardWebhooksTolerance is the maximum allowed difference between the
+// webhook-timestamp header and the current time. Requests outside of this
+// window are rejected, so a captured request can't be replayed later on.
+const StandardWebhooksTolerance = 5 * time.Minute
+
+var ErrTimestampOutOfTolerance = errors.New("webhook timestamp is outside of the allowed tolerance window")
+
+// timeNow is overridden in tests
+var timeNow = time.Now
+
+const standardWebhooksSecretPrefix = "whsec_"
+
+// verifyStandardWebhooksSignature verifies request signature as per
+// the Standard Webhooks spec, which is used by GitLab signing tokens.
+//
+// Secret can be either in the "whsec_<base64 key>" form, or a raw string.
+//
+// @see https://github.com/standard-webhooks/standard-webhooks/blob/main/spec/standard-webhooks.md
+func verifyStandardWebhooksSignature(req WebhookPostRequest, secret string) (bool, error) {
+	id := req.Headers.Get("Webhook-Id")
+	timestamp := req.Headers.Get("Webhook-Timestamp")
+	signatures := req.Headers.Get("Webhook-Signature")
+	if id == "" || timestamp == "" || signatures == "" {
+		return false, nil
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("malformed webhook-timestamp header %q: %w", timestamp, err)
+	}
+	diff := timeNow().Sub(time.Unix(ts, 0))
+	if diff > StandardWebhooksTolerance || diff < -StandardWebhooksTolerance {
+		return false, ErrTimestampOutOfTolerance
+	}
+
+	key := []byte(secret)
+	if encodedKey, ok := strings.CutPrefix(secret, standardWebhooksSecretPrefix); ok {
+		key, err = base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return false, fmt.Errorf("failed to decode the secret: %w", err)
+		}
+	}
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(id + "." + timestamp + "."))
+	h.Write(req.Payload)
+	comparer := cryptoutils.NewConstantTimeComparerBytes(h.Sum(nil))
+
+	// The header is a space delimited list of "version,signature" pairs, so
+	// the sender can rotate its keys. Only v1 (HMAC-SHA256) is defined.
+	verified := false
+	for entry := range strings.FieldsSeq(signatures) {
+		version, signature, found := strings.Cut(entry, ",")
+		if !found || version != "v1" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			continue
+		}
+		if comparer.EqBytes(decoded) {
+			verified = true
+		}
+	}
+	return verified, nil
+}
@@ -1,16 +1,61 @@
 package whreceiver_test
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/religiosa1/git-webhook-receiver/internal/config"
 	"github.com/religiosa1/git-webhook-receiver/internal/requestmock"
 	"github.com/religiosa1/git-webhook-receiver/internal/whreceiver"
 )
 
+// Signing token and the time signed gitlab mock request was sent at
+const (
+	gitlabSigningToken = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+	gitlabSignedAt     = 1760270400
+)
+
+var (
+	giteaPush = whreceiver.PushInfo{
+		Pusher:        "religiosa",
+		Before:        "323b2c0d7778db8aa4164db5aacea772c4c4feaf",
+		CommitMessage: "Submit incident button missed\n",
+		CommitCount:   1,
+		CompareURL:    "https://git.example.com/religiosa/staticus/compare/323b2c0d7778db8aa4164db5aacea772c4c4feaf...323b2c0d7778db8aa4164db5aacea772c4c4feaf",
+	}
+	gogsPush = whreceiver.PushInfo{
+		Pusher:        "religiosa",
+		Before:        "f3a4b1c2d5e6f7081920a1b2c3d4e5f6a7b8c9d0",
+		CommitMessage: "Bump dependencies\n",
+		CommitCount:   1,
+		CompareURL:    "https://gogs.example.com/religiosa/legacy/compare/f3a4b1c2d5...9e8d7c6b5a",
+	}
+	githubPush = whreceiver.PushInfo{
+		Pusher:        "religiosa1",
+		Before:        "2dec223d4e46cea4fd8aeb5d205d16a2e4296a1a",
+		CommitMessage: "test",
+		CommitCount:   1,
+		CompareURL:    "https://github.com/religiosa1/github-test/compare/2dec223d4e46...92bcfadb4199",
+	}
+	gitlabPush = whreceiver.PushInfo{
+		Pusher:        "root",
+		Before:        "cc95703e989bef045106f320004b70bd4b90c3e6",
+		CommitMessage: "test push\n",
+		CommitCount:   1,
+		CompareURL:    "https://gitlab.example.com/root/test/-/compare/cc95703e989bef045106f320004b70bd4b90c3e6...ecb02fa2ce0400aaa3415de7b5b9f3cb59b3d5dc",
+	}
+)
+
 func TestReceivers(t *testing.T) {
+	whreceiver.SetTimeNow(t, time.Unix(gitlabSignedAt, 0))
 	receivers := []struct {
 		name        string
 		project     config.Project
@@ -27,8 +72,54 @@ func TestReceivers(t *testing.T) {
 				Repo:        "religiosa/staticus",
 				Actions: []config.Action{
 					{
-						On:     "push",
-						Branch: "master",
+						On:     config.PatternList{"push"},
+						Branch: config.PatternList{"master"},
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/gitea.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID: "e3b2f0a4-2e9b-417a-b2e5-1a808025998e",
+				Branch:     "master",
+				Event:      "push",
+				Hash:       "323b2c0d7778db8aa4164db5aacea772c4c4feaf",
+				Push:       &giteaPush,
+			},
+			authToken: "JgHhtuPOISmw3WDCRtz4H6IrT8zWwNkS",
+			secret:    "cc7ec03e-2e09-4bb9-b2fc-388b865200d0",
+		},
+		{
+			name: "forgejo",
+			project: config.Project{
+				GitProvider: "forgejo",
+				Repo:        "religiosa/staticus",
+				Actions: []config.Action{
+					{
+						On:     config.PatternList{"push"},
+						Branch: config.PatternList{"master"},
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/forgejo.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID: "7d1c5f0e-3a2b-4c9d-8e7f-6a5b4c3d2e1f",
+				Branch:     "master",
+				Event:      "push",
+				Hash:       "323b2c0d7778db8aa4164db5aacea772c4c4feaf",
+				Push:       &giteaPush,
+			},
+			authToken: "JgHhtuPOISmw3WDCRtz4H6IrT8zWwNkS",
+			secret:    "cc7ec03e-2e09-4bb9-b2fc-388b865200d0",
+		},
+		{
+			name: "forgejo with gitea-compatible headers",
+			project: config.Project{
+				GitProvider: "forgejo",
+				Repo:        "religiosa/staticus",
+				Actions: []config.Action{
+					{
+						On:     config.PatternList{"push"},
+						Branch: config.PatternList{"master"},
 					},
 				},
 			},
@@ -38,10 +129,35 @@ func TestReceivers(t *testing.T) {
 				Branch:     "master",
 				Event:      "push",
 				Hash:       "323b2c0d7778db8aa4164db5aacea772c4c4feaf",
+				Push:       &giteaPush,
 			},
 			authToken: "JgHhtuPOISmw3WDCRtz4H6IrT8zWwNkS",
 			secret:    "cc7ec03e-2e09-4bb9-b2fc-388b865200d0",
 		},
+		{
+			name: "gogs",
+			project: config.Project{
+				GitProvider: "gogs",
+				Repo:        "religiosa/legacy",
+				Actions: []config.Action{
+					{
+						On:     config.PatternList{"push"},
+						Branch: config.PatternList{"master"},
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/gogs.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID:   "5f4e3d2c-1b0a-4f9e-8d7c-6b5a4f3e2d1c",
+				Branch:       "master",
+				Event:        "push",
+				Hash:         "9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d",
+				Push:         &gogsPush,
+				ChangedFiles: []string{"composer.json", "composer.lock"},
+			},
+			authToken: "",
+			secret:    "gogs-3216732167",
+		},
 		{
 			name: "github",
 			project: config.Project{
@@ -49,17 +165,48 @@ func TestReceivers(t *testing.T) {
 				Repo:        "religiosa1/github-test",
 				Actions: []config.Action{
 					{
-						On:     "push",
-						Branch: "main",
+						On:     config.PatternList{"push"},
+						Branch: config.PatternList{"main"},
 					},
 				},
 			},
 			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/github.json"),
 			postInfo: whreceiver.WebhookPostInfo{
-				DeliveryID: "98105a58-6936-11ef-95df-08616739eae5",
-				Branch:     "main",
-				Event:      "push",
-				Hash:       "92bcfadb4199556415be69b9c31c0dc72343fea2",
+				DeliveryID:   "98105a58-6936-11ef-95df-08616739eae5",
+				Branch:       "main",
+				Event:        "push",
+				Hash:         "92bcfadb4199556415be69b9c31c0dc72343fea2",
+				Push:         &githubPush,
+				ChangedFiles: []string{"test"},
+			},
+			authToken: "",
+			secret:    "3216732167",
+			pingRequest: &whreceiver.WebhookPostRequest{
+				Headers: http.Header{"X-Github-Event": []string{"ping"}},
+				Payload: []byte(`{}`),
+			},
+		},
+		{
+			name: "github form-encoded with sha1 signature",
+			project: config.Project{
+				GitProvider:        "github",
+				Repo:               "religiosa1/github-test",
+				AllowSha1Signature: true,
+				Actions: []config.Action{
+					{
+						On:     config.PatternList{"push"},
+						Branch: config.PatternList{"main"},
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/github-form-sha1.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID:   "4c1f8e2a-7a3b-11ef-8d2e-0242ac120002",
+				Branch:       "main",
+				Event:        "push",
+				Hash:         "92bcfadb4199556415be69b9c31c0dc72343fea2",
+				Push:         &githubPush,
+				ChangedFiles: []string{"test"},
 			},
 			authToken: "",
 			secret:    "3216732167",
@@ -75,8 +222,296 @@ func TestReceivers(t *testing.T) {
 				Repo:        "root/test",
 				Actions: []config.Action{
 					{
-						On:     "push",
-						Branch: "main",
+						On:     config.PatternList{"push"},
+						Branch: config.PatternList{"main"},
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/gitlab-signed.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID:   "53c7c667-1a4b-49f2-94e1-8246ab64c776",
+				Branch:       "main",
+				Event:        "push",
+				Hash:         "ecb02fa2ce0400aaa3415de7b5b9f3cb59b3d5dc",
+				Push:         &gitlabPush,
+				ChangedFiles: []string{"test"},
+			},
+			authToken: "32167",
+			secret:    gitlabSigningToken,
+		},
+		{
+			name: "github pull request",
+			project: config.Project{
+				GitProvider: "github",
+				Repo:        "religiosa1/github-test",
+				Actions: []config.Action{
+					{
+						On:     config.PatternList{"pull_request.opened"},
+						Branch: config.PatternList{"main"},
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/github-pull-request.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID: "d1e5a2c0-6b7f-11ef-8a3b-5c1a7e9d3f20",
+				Event:      "pull_request",
+				Action:     "opened",
+				PullRequest: &whreceiver.PullRequestInfo{
+					Number:     2,
+					Title:      "Add build status badge",
+					Author:     "octocat-dev",
+					HeadBranch: "feature/badge",
+					BaseBranch: "main",
+					HeadHash:   "5e1f9a2b3c4d5e6f7a8b9c0d1e2f3a4b5c6d7e8f",
+				},
+			},
+			authToken: "",
+			secret:    "3216732167",
+			pingRequest: &whreceiver.WebhookPostRequest{
+				Headers: http.Header{"X-Github-Event": []string{"ping"}},
+				Payload: []byte(`{}`),
+			},
+		},
+		{
+			name: "gitea pull request",
+			project: config.Project{
+				GitProvider: "gitea",
+				Repo:        "religiosa/staticus",
+				Actions: []config.Action{
+					{
+						On:     config.PatternList{"pull_request.synchronized"},
+						Branch: config.PatternList{"master"},
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/gitea-pull-request.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID: "0f3c9b8e-5a2d-4e71-9c6b-2d8e7f1a4b3c",
+				Event:      "pull_request",
+				Action:     "synchronized",
+				PullRequest: &whreceiver.PullRequestInfo{
+					Number:     7,
+					Title:      "Rework the build script",
+					Author:     "dev",
+					HeadBranch: "build-rework",
+					BaseBranch: "master",
+					HeadHash:   "8a7b6c5d4e3f2a1b0c9d8e7f6a5b4c3d2e1f0a9b",
+				},
+			},
+			authToken: "JgHhtuPOISmw3WDCRtz4H6IrT8zWwNkS",
+			secret:    "cc7ec03e-2e09-4bb9-b2fc-388b865200d0",
+		},
+		{
+			name: "gitlab merge request",
+			project: config.Project{
+				GitProvider: "gitlab",
+				Repo:        "root/test",
+				Actions: []config.Action{
+					{
+						On:     config.PatternList{"merge_request.close"},
+						Branch: config.PatternList{"main"},
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/gitlab-merge-request.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID: "a2d4c6e8-1b3d-4f5a-9c7e-0b2d4f6a8c1e",
+				Event:      "merge_request",
+				Action:     "close",
+				PullRequest: &whreceiver.PullRequestInfo{
+					Number:     4,
+					Title:      "Draft: login page",
+					Author:     "root",
+					HeadBranch: "feature/login",
+					BaseBranch: "main",
+					HeadHash:   "f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5",
+				},
+			},
+			authToken: "32167",
+			secret:    gitlabSigningToken,
+		},
+		{
+			name: "github release",
+			project: config.Project{
+				GitProvider: "github",
+				Repo:        "religiosa1/github-test",
+				Actions: []config.Action{
+					{
+						On:          config.PatternList{"release.published"},
+						ReleaseType: "prerelease",
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/github-release.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID: "6a2e1f40-6c21-11ef-9b1d-3e5f0a8c7d12",
+				Tag:        "v1.3.0-rc.1",
+				Event:      "release",
+				Action:     "published",
+				Release: &whreceiver.ReleaseInfo{
+					Tag:        "v1.3.0-rc.1",
+					Name:       "1.3.0 Release Candidate 1",
+					Prerelease: true,
+					Draft:      false,
+				},
+			},
+			authToken: "",
+			secret:    "3216732167",
+			pingRequest: &whreceiver.WebhookPostRequest{
+				Headers: http.Header{"X-Github-Event": []string{"ping"}},
+				Payload: []byte(`{}`),
+			},
+		},
+		{
+			name: "gitea release",
+			project: config.Project{
+				GitProvider: "gitea",
+				Repo:        "religiosa/staticus",
+				Actions: []config.Action{
+					{
+						On:          config.PatternList{"release.published"},
+						ReleaseType: "stable",
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/gitea-release.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID: "5b9e2d7c-3f1a-4c8e-b6d2-9a0e4f7c1b3d",
+				Tag:        "v2.0.0",
+				Event:      "release",
+				Action:     "published",
+				Release: &whreceiver.ReleaseInfo{
+					Tag:  "v2.0.0",
+					Name: "Staticus 2.0",
+				},
+			},
+			authToken: "JgHhtuPOISmw3WDCRtz4H6IrT8zWwNkS",
+			secret:    "cc7ec03e-2e09-4bb9-b2fc-388b865200d0",
+		},
+		{
+			name: "bitbucket",
+			project: config.Project{
+				GitProvider: "bitbucket",
+				Repo:        "religiosa1/bitbucket-test",
+				Actions: []config.Action{
+					{
+						On:     config.PatternList{"push"},
+						Branch: config.PatternList{"main"},
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/bitbucket.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID: "c2f1a0b9-8e7d-4c6b-a5f4-e3d2c1b0a9f8",
+				Branch:     "main",
+				Event:      "push",
+				Hash:       "4a0cbd1e7c4f3d2b1a0f9e8d7c6b5a4f3e2d1c0b",
+				Push: &whreceiver.PushInfo{
+					Before:        "a1e1f3c6b2d8e9f0a1b2c3d4e5f60718293a4b5c",
+					CommitMessage: "Update README.md\n",
+				},
+			},
+			authToken: "",
+			secret:    "bb-3216732167",
+		},
+		{
+			name: "bitbucket-server",
+			project: config.Project{
+				GitProvider: "bitbucket-server",
+				Repo:        "WEB/staticus",
+				Actions: []config.Action{
+					{
+						On:     config.PatternList{"push"},
+						Branch: config.PatternList{"master"},
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/bitbucket-server.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID: "b3a5e2c1-7d4f-4e6a-9b8c-0d1e2f3a4b5c",
+				Branch:     "master",
+				Event:      "push",
+				Hash:       "178864a7d521b6f5e720b386b2c2b0ef8563e0dc",
+				Push:       &whreceiver.PushInfo{Before: "ecddabb624f6f5ba43816f5926e580a5f680a932"},
+			},
+			authToken: "",
+			secret:    "bbs-3216732167",
+			pingRequest: &whreceiver.WebhookPostRequest{
+				Headers: http.Header{"X-Event-Key": []string{"diagnostics:ping"}},
+				Payload: []byte(`{"test": true}`),
+			},
+		},
+		{
+			name: "azure-devops",
+			project: config.Project{
+				GitProvider: "azure-devops",
+				Repo:        "Web/staticus",
+				Actions: []config.Action{
+					{
+						On:     config.PatternList{"push"},
+						Branch: config.PatternList{"master"},
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/azure-devops.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID: "03c164c2-8912-4d5e-8009-3707d5f83734",
+				Branch:     "master",
+				Event:      "push",
+				Hash:       "33b55f7cb7e7e245323987634f960cf4a6e6bc74",
+				Push:       &whreceiver.PushInfo{Before: "aad331d8d3b131fa9ae03cf5e53965b51942618a"},
+			},
+			authToken: "deploy:3216732167",
+			secret:    "",
+		},
+		{
+			name: "custom",
+			project: config.Project{
+				GitProvider: "custom",
+				CustomProvider: config.CustomProvider{
+					EventHeader:         "X-Gitea-Event",
+					DeliveryHeader:      "X-Gitea-Delivery",
+					AuthorizationHeader: "Authorization",
+					SignatureHeader:     "X-Hub-Signature-256",
+					SignaturePrefix:     "sha256=",
+					RefPath:             "/ref",
+					HashPath:            "/after",
+					RepoPath:            "/repository/full_name",
+				},
+				Repo: "religiosa/staticus",
+				Actions: []config.Action{
+					{
+						On:     config.PatternList{"push"},
+						Branch: config.PatternList{"master"},
+					},
+				},
+			},
+			request: requestmock.LoadRequestMock(t, "../requestmock/captured-requests/gitea.json"),
+			postInfo: whreceiver.WebhookPostInfo{
+				DeliveryID: "e3b2f0a4-2e9b-417a-b2e5-1a808025998e",
+				Branch:     "master",
+				Event:      "push",
+				Hash:       "323b2c0d7778db8aa4164db5aacea772c4c4feaf",
+			},
+			authToken: "JgHhtuPOISmw3WDCRtz4H6IrT8zWwNkS",
+			secret:    "cc7ec03e-2e09-4bb9-b2fc-388b865200d0",
+		},
+		{
+			name: "custom without authorization and signature",
+			project: config.Project{
+				GitProvider: "custom",
+				CustomProvider: config.CustomProvider{
+					EventHeader:    "X-Gitlab-Event",
+					DeliveryHeader: "X-Gitlab-Event-UUID",
+					RefPath:        "/ref",
+					HashPath:       "/checkout_sha",
+					RepoPath:       "/project/path_with_namespace",
+				},
+				Repo: "root/test",
+				Actions: []config.Action{
+					{
+						On:     config.PatternList{"Push Hook"},
+						Branch: config.PatternList{"main"},
 					},
 				},
 			},
@@ -84,11 +519,9 @@ func TestReceivers(t *testing.T) {
 			postInfo: whreceiver.WebhookPostInfo{
 				DeliveryID: "53c7c667-1a4b-49f2-94e1-8246ab64c776",
 				Branch:     "main",
-				Event:      "push",
+				Event:      "Push Hook",
 				Hash:       "ecb02fa2ce0400aaa3415de7b5b9f3cb59b3d5dc",
 			},
-			authToken: "32167",
-			secret:    "",
 		},
 	}
 
@@ -207,6 +640,407 @@ func TestReceivers(t *testing.T) {
 	}
 }
 
+func TestBitbucketServerPingHasNoRepo(t *testing.T) {
+	rcvr := whreceiver.New(config.Project{GitProvider: "bitbucket-server", Repo: "WEB/staticus"})
+	req := whreceiver.WebhookPostRequest{
+		Headers: http.Header{"X-Event-Key": []string{"diagnostics:ping"}},
+		Payload: []byte(`{"test": true}`),
+	}
+	got, err := rcvr.GetWebhookInfo(req)
+	if err != nil {
+		t.Fatalf("expected ping payload without repository to be accepted, got: %s", err)
+	}
+	if want := "ping"; got.Event != want {
+		t.Errorf("Unexpected Event value, want %q, got %q", want, got.Event)
+	}
+}
+
+func TestGogsCreateEvent(t *testing.T) {
+	rcvr := whreceiver.New(config.Project{GitProvider: "gogs", Repo: "religiosa/legacy"})
+	req := whreceiver.WebhookPostRequest{
+		Headers: http.Header{
+			"X-Gogs-Event":    []string{"create"},
+			"X-Gogs-Delivery": []string{"32167"},
+		},
+		Payload: []byte(`{
+			"ref": "feature/foo",
+			"ref_type": "branch",
+			"sha": "9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d",
+			"default_branch": "master",
+			"repository": {"full_name": "religiosa/legacy"}
+		}`),
+	}
+	got, err := rcvr.GetWebhookInfo(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	CompareWebhookPostInfo(t, whreceiver.WebhookPostInfo{
+		DeliveryID: "32167",
+		Branch:     "feature/foo",
+		Event:      "create",
+		Hash:       "9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d",
+	}, *got)
+}
+
+func TestAzureDevopsRawAuthorizationHeader(t *testing.T) {
+	rcvr := whreceiver.New(config.Project{GitProvider: "azure-devops", Repo: "Web/staticus"})
+	req := whreceiver.WebhookPostRequest{
+		Headers: http.Header{"Authorization": []string{"Bearer 32167"}},
+		Payload: []byte(`{}`),
+	}
+	got, err := rcvr.Authorize(req, "Bearer 32167")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != true {
+		t.Errorf("Authorization test failed, got %t, want true", got)
+	}
+}
+
+func TestCustomSignatureAlgorithms(t *testing.T) {
+	const secret = "32167"
+	payload := []byte(`{"ref":"main","repo":"foo/bar"}`)
+	tests := []struct {
+		name      string
+		algorithm string
+		encoding  string
+		prefix    string
+		signature string
+	}{
+		{"sha1 hex with prefix", "sha1", "hex", "sha1=", "sha1=7acef2de92f946211372ef59402b7d64b878ba79"},
+		{"sha256 base64", "sha256", "base64", "", "arlesSvw+7vmGj9cWb0wZJAVFWnmILHk+G7kV0cFJa8="},
+		{"sha512 hex", "sha512", "hex", "", "e8785df5900330d46cfeb6604cbf0f582b02005972939b03346ac7a552f62addc9b608baab3c7f51d9088f6a2fd1bbdf675f6c9b868c14e03abb401781dd2a3c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rcvr := whreceiver.New(config.Project{
+				GitProvider: "custom",
+				Repo:        "foo/bar",
+				CustomProvider: config.CustomProvider{
+					SignatureHeader:    "X-Signature",
+					SignatureAlgorithm: tt.algorithm,
+					SignatureEncoding:  tt.encoding,
+					SignaturePrefix:    tt.prefix,
+					RepoPath:           "/repo",
+				},
+			})
+			req := whreceiver.WebhookPostRequest{
+				Headers: http.Header{"X-Signature": []string{tt.signature}},
+				Payload: payload,
+			}
+			got, err := rcvr.VerifySignature(req, secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != true {
+				t.Errorf("Secret validation failed, got %t, want true", got)
+			}
+		})
+	}
+}
+
+func TestCustomBareBranchName(t *testing.T) {
+	rcvr := whreceiver.New(config.Project{
+		GitProvider: "custom",
+		Repo:        "foo/bar",
+		CustomProvider: config.CustomProvider{
+			RefPath:  "/ref",
+			RepoPath: "/repo",
+		},
+	})
+	got, err := rcvr.GetWebhookInfo(whreceiver.WebhookPostRequest{
+		Headers: http.Header{},
+		Payload: []byte(`{"ref":"feature/foo","repo":"foo/bar"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "feature/foo"; got.Branch != want {
+		t.Errorf("Unexpected Branch value, want %q, got %q", want, got.Branch)
+	}
+}
+
+func TestGithubSha1SignatureIsOptIn(t *testing.T) {
+	req := MakeWebhookPostRequest(requestmock.LoadRequestMock(t, "../requestmock/captured-requests/github-form-sha1.json"))
+	rcvr := whreceiver.New(config.Project{GitProvider: "github", Repo: "religiosa1/github-test"})
+	got, err := rcvr.VerifySignature(req, "3216732167")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got {
+		t.Error("expected sha1 signature to be rejected without allow_sha1_signature")
+	}
+}
+
+func TestGithubSha256SignatureTakesPrecedence(t *testing.T) {
+	req := MakeWebhookPostRequest(requestmock.LoadRequestMock(t, "../requestmock/captured-requests/github.json"))
+	req.Headers.Set("X-Hub-Signature-256", "sha256=00")
+	rcvr := whreceiver.New(config.Project{GitProvider: "github", Repo: "religiosa1/github-test", AllowSha1Signature: true})
+	got, err := rcvr.VerifySignature(req, "3216732167")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got {
+		t.Error("expected invalid sha256 signature to be rejected, even with a valid sha1 one")
+	}
+}
+
+func TestGithubFormPayloadWithoutPayloadField(t *testing.T) {
+	rcvr := whreceiver.New(config.Project{GitProvider: "github", Repo: "religiosa1/github-test"})
+	req := whreceiver.WebhookPostRequest{
+		Headers: http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}},
+		Payload: []byte("foo=bar"),
+	}
+	if _, err := rcvr.GetWebhookInfo(req); err == nil {
+		t.Error("expected an error, got nil")
+	}
+}
+
+func TestTagRefs(t *testing.T) {
+	const hash = "9e8d7c6b5a4f3e2d1c0b9a8f7e6d5c4b3a2f1e0d"
+	tests := []struct {
+		provider string
+		headers  http.Header
+		payload  string
+		event    string
+	}{
+		{
+			"github",
+			http.Header{"X-Github-Event": []string{"push"}},
+			`{"ref": "refs/tags/v1.2.0", "after": "` + hash + `", "repository": {"full_name": "user/repo"}}`,
+			"push",
+		},
+		{
+			"gitea",
+			http.Header{"X-Gitea-Event": []string{"push"}},
+			`{"ref": "refs/tags/v1.2.0", "after": "` + hash + `", "repository": {"full_name": "user/repo"}}`,
+			"push",
+		},
+		{
+			"gitlab",
+			http.Header{"X-Gitlab-Event": []string{"Tag Push Hook"}},
+			`{"object_kind": "tag_push", "ref": "refs/tags/v1.2.0", "after": "` + hash + `", "project": {"path_with_namespace": "user/repo"}}`,
+			"tag_push",
+		},
+		{
+			"gogs",
+			http.Header{"X-Gogs-Event": []string{"create"}},
+			`{"ref": "v1.2.0", "ref_type": "tag", "sha": "` + hash + `", "repository": {"full_name": "user/repo"}}`,
+			"create",
+		},
+		{
+			"bitbucket",
+			http.Header{"X-Event-Key": []string{"repo:push"}},
+			`{"push": {"changes": [{"new": {"type": "tag", "name": "v1.2.0", "target": {"hash": "` + hash + `"}}}]}, "repository": {"full_name": "user/repo"}}`,
+			"push",
+		},
+		{
+			"bitbucket-server",
+			http.Header{"X-Event-Key": []string{"repo:refs_changed"}},
+			`{"repository": {"slug": "repo", "project": {"key": "user"}}, "changes": [{"ref": {"displayId": "v1.2.0", "type": "TAG"}, "toHash": "` + hash + `"}]}`,
+			"push",
+		},
+		{
+			"azure-devops",
+			http.Header{},
+			`{"eventType": "git.push", "resource": {"refUpdates": [{"name": "refs/tags/v1.2.0", "newObjectId": "` + hash + `"}], "repository": {"name": "repo", "project": {"name": "user"}}}}`,
+			"push",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			rcvr := whreceiver.New(config.Project{GitProvider: tt.provider, Repo: "user/repo"})
+			got, err := rcvr.GetWebhookInfo(whreceiver.WebhookPostRequest{Headers: tt.headers, Payload: []byte(tt.payload)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// push context is covered by TestReceivers
+			got.Push = nil
+			CompareWebhookPostInfo(t, whreceiver.WebhookPostInfo{
+				Tag:   "v1.2.0",
+				Event: tt.event,
+				Hash:  hash,
+			}, *got)
+		})
+	}
+}
+
+func TestGitlabEventAliases(t *testing.T) {
+	rcvr := whreceiver.New(config.Project{GitProvider: "gitlab", Repo: "user/repo"})
+	tests := []struct {
+		header      string
+		wantEvent   string
+		wantAliases []string
+	}{
+		{"Push Hook", "push", nil},
+		{"Tag Push Hook", "tag_push", []string{"tag push"}},
+		{"Merge Request Hook", "merge_request", []string{"merge request"}},
+		{"Wiki Page Hook", "wiki_page", []string{"wiki page"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, err := rcvr.GetWebhookInfo(whreceiver.WebhookPostRequest{
+				Headers: http.Header{"X-Gitlab-Event": []string{tt.header}},
+				Payload: []byte(`{"project": {"path_with_namespace": "user/repo"}}`),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Event != tt.wantEvent {
+				t.Errorf("want event %q, got %q", tt.wantEvent, got.Event)
+			}
+			if !slices.Equal(tt.wantAliases, got.EventAliases) {
+				t.Errorf("want aliases %q, got %q", tt.wantAliases, got.EventAliases)
+			}
+		})
+	}
+}
+
+func TestGitlabNonPushEvent(t *testing.T) {
+	rcvr := whreceiver.New(config.Project{GitProvider: "gitlab", Repo: "user/repo"})
+	got, err := rcvr.GetWebhookInfo(whreceiver.WebhookPostRequest{
+		Headers: http.Header{"X-Gitlab-Event": []string{"Pipeline Hook"}},
+		Payload: []byte(`{"object_kind": "pipeline", "project": {"path_with_namespace": "user/repo"}}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Event != "pipeline" {
+		t.Errorf("want event %q, got %q", "pipeline", got.Event)
+	}
+	if got.Push != nil {
+		t.Errorf("want nil Push for a non-push event, got %+v", got.Push)
+	}
+	if got.ChangedFiles != nil || got.CommitMessages != nil {
+		t.Errorf("want nil ChangedFiles and CommitMessages for a non-push event, got %q and %q", got.ChangedFiles, got.CommitMessages)
+	}
+}
+
+func TestGitlabRelease(t *testing.T) {
+	rcvr := whreceiver.New(config.Project{GitProvider: "gitlab", Repo: "root/test"})
+	req := whreceiver.WebhookPostRequest{
+		Headers: http.Header{
+			"X-Gitlab-Event":      []string{"Release Hook"},
+			"X-Gitlab-Event-Uuid": []string{"32167"},
+		},
+		Payload: []byte(`{
+			"object_kind": "release",
+			"action": "create",
+			"name": "Release 1.0",
+			"tag": "v1.0",
+			"project": {"path_with_namespace": "root/test"},
+			"commit": {"id": "ecb02fa2ce0400aaa3415de7b5b9f3cb59b3d5dc"}
+		}`),
+	}
+	got, err := rcvr.GetWebhookInfo(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	CompareWebhookPostInfo(t, whreceiver.WebhookPostInfo{
+		DeliveryID: "32167",
+		Tag:        "v1.0",
+		Event:      "release",
+		Action:     "create",
+		Hash:       "ecb02fa2ce0400aaa3415de7b5b9f3cb59b3d5dc",
+		Release:    &whreceiver.ReleaseInfo{Tag: "v1.0", Name: "Release 1.0"},
+	}, *got)
+}
+
+func TestChangedFiles(t *testing.T) {
+	commits := `[
+		{"id": "1", "message": "Add docs", "added": ["docs/a.md"], "modified": ["go.mod"], "removed": []},
+		{"id": "2", "message": "Bump go.mod [skip ci]", "added": [], "modified": ["go.mod", "main.go"], "removed": ["docs/b.md"]}
+	]`
+	wantFiles := []string{"docs/a.md", "go.mod", "docs/b.md", "main.go"}
+	wantMessages := []string{"Add docs", "Bump go.mod [skip ci]"}
+
+	tests := []struct {
+		provider      string
+		headers       http.Header
+		payload       string
+		wantTruncated bool
+	}{
+		{
+			"gitea",
+			http.Header{"X-Gitea-Event": []string{"push"}},
+			`{"ref": "refs/heads/master", "after": "2", "total_commits": 2, "commits": ` + commits + `, "repository": {"full_name": "user/repo"}}`,
+			false,
+		},
+		{
+			"gitea truncated",
+			http.Header{"X-Gitea-Event": []string{"push"}},
+			`{"ref": "refs/heads/master", "after": "2", "total_commits": 7, "commits": ` + commits + `, "repository": {"full_name": "user/repo"}}`,
+			true,
+		},
+		{
+			"github",
+			http.Header{"X-Github-Event": []string{"push"}},
+			`{"ref": "refs/heads/master", "after": "2", "commits": ` + commits + `, "repository": {"full_name": "user/repo"}}`,
+			false,
+		},
+		{
+			"gitlab",
+			http.Header{"X-Gitlab-Event": []string{"Push Hook"}},
+			`{"object_kind": "push", "ref": "refs/heads/master", "after": "2", "total_commits_count": 2, "commits": ` + commits + `, "project": {"path_with_namespace": "user/repo"}}`,
+			false,
+		},
+		{
+			"gitlab truncated",
+			http.Header{"X-Gitlab-Event": []string{"Push Hook"}},
+			`{"object_kind": "push", "ref": "refs/heads/master", "after": "2", "total_commits_count": 21, "commits": ` + commits + `, "project": {"path_with_namespace": "user/repo"}}`,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			provider, _, _ := strings.Cut(tt.provider, " ")
+			rcvr := whreceiver.New(config.Project{GitProvider: provider, Repo: "user/repo"})
+			got, err := rcvr.GetWebhookInfo(whreceiver.WebhookPostRequest{Headers: tt.headers, Payload: []byte(tt.payload)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got.ChangedFiles, wantFiles) {
+				t.Errorf("Unexpected ChangedFiles value, want %q, got %q", wantFiles, got.ChangedFiles)
+			}
+			if !slices.Equal(got.CommitMessages, wantMessages) {
+				t.Errorf("Unexpected CommitMessages value, want %q, got %q", wantMessages, got.CommitMessages)
+			}
+			if got.ChangedFilesTruncated != tt.wantTruncated {
+				t.Errorf("Unexpected ChangedFilesTruncated value, want %t, got %t", tt.wantTruncated, got.ChangedFilesTruncated)
+			}
+		})
+	}
+}
+
+func TestVerifySignatureAny(t *testing.T) {
+	const secret = "cc7ec03e-2e09-4bb9-b2fc-388b865200d0"
+	rcvr := whreceiver.New(config.Project{GitProvider: "gitea", Repo: "religiosa/staticus"})
+	req := MakeWebhookPostRequest(requestmock.LoadRequestMock(t, "../requestmock/captured-requests/gitea.json"))
+
+	tests := []struct {
+		name    string
+		secrets []string
+		want    int
+	}{
+		{"first one matches", []string{secret, "old"}, 0},
+		{"second one matches", []string{"old", secret}, 1},
+		{"none matches", []string{"old", "older"}, -1},
+		{"no secrets", nil, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := whreceiver.VerifySignatureAny(rcvr, req, tt.secrets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("unexpected matched index, want %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
 func MakeWebhookPostRequest(requestMock requestmock.RequestMock) (req whreceiver.WebhookPostRequest) {
 	req.Payload = []byte(requestMock.Body)
 	req.Headers = http.Header{}
@@ -224,10 +1058,223 @@ func CompareWebhookPostInfo(t *testing.T, want whreceiver.WebhookPostInfo, got w
 	if want, got := want.Branch, got.Branch; want != got {
 		t.Errorf("Unexpected Branch value, want %q, got %q", want, got)
 	}
+	if want, got := want.Tag, got.Tag; want != got {
+		t.Errorf("Unexpected Tag value, want %q, got %q", want, got)
+	}
 	if want, got := want.Event, got.Event; want != got {
 		t.Errorf("Unexpected Event value, want %q, got %q", want, got)
 	}
 	if want, got := want.Hash, got.Hash; want != got {
 		t.Errorf("Unexpected Hash value, want %q, got %q", want, got)
 	}
+	if want, got := want.Action, got.Action; want != got {
+		t.Errorf("Unexpected Action value, want %q, got %q", want, got)
+	}
+	if want, got := want.PullRequest, got.PullRequest; (want == nil) != (got == nil) || (want != nil && *want != *got) {
+		t.Errorf("Unexpected PullRequest value, want %+v, got %+v", want, got)
+	}
+	if want, got := want.Release, got.Release; (want == nil) != (got == nil) || (want != nil && *want != *got) {
+		t.Errorf("Unexpected Release value, want %+v, got %+v", want, got)
+	}
+	if want, got := want.Push, got.Push; (want == nil) != (got == nil) || (want != nil && *want != *got) {
+		t.Errorf("Unexpected Push value, want %+v, got %+v", want, got)
+	}
+	if want, got := want.ChangedFiles, got.ChangedFiles; !slices.Equal(want, got) {
+		t.Errorf("Unexpected ChangedFiles value, want %q, got %q", want, got)
+	}
+	if want, got := want.ChangedFilesTruncated, got.ChangedFilesTruncated; want != got {
+		t.Errorf("Unexpected ChangedFilesTruncated value, want %t, got %t", want, got)
+	}
+}
+
+func TestStandardWebhooksSignature(t *testing.T) {
+	project := config.Project{GitProvider: "gitlab", Repo: "root/test"}
+	rcvr := whreceiver.New(project)
+	signedAt := time.Unix(gitlabSignedAt, 0)
+	mock := requestmock.LoadRequestMock(t, "../requestmock/captured-requests/gitlab-signed.json")
+
+	t.Run("accepts requests within the tolerance window", func(t *testing.T) {
+		for _, now := range []time.Time{
+			signedAt.Add(-whreceiver.StandardWebhooksTolerance),
+			signedAt.Add(whreceiver.StandardWebhooksTolerance),
+		} {
+			whreceiver.SetTimeNow(t, now)
+			req := MakeWebhookPostRequest(mock)
+			ok, err := rcvr.VerifySignature(req, gitlabSigningToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !ok {
+				t.Errorf("expected signature to be verified at %v", now)
+			}
+		}
+	})
+
+	t.Run("rejects replayed requests", func(t *testing.T) {
+		for _, now := range []time.Time{
+			signedAt.Add(-whreceiver.StandardWebhooksTolerance - time.Second),
+			signedAt.Add(whreceiver.StandardWebhooksTolerance + time.Second),
+		} {
+			whreceiver.SetTimeNow(t, now)
+			req := MakeWebhookPostRequest(mock)
+			ok, err := rcvr.VerifySignature(req, gitlabSigningToken)
+			if !errors.Is(err, whreceiver.ErrTimestampOutOfTolerance) {
+				t.Errorf("expected out of tolerance error, got %v", err)
+			}
+			if ok {
+				t.Errorf("expected signature to be rejected at %v", now)
+			}
+		}
+	})
+
+	t.Run("accepts any of the listed signatures", func(t *testing.T) {
+		whreceiver.SetTimeNow(t, signedAt)
+		req := MakeWebhookPostRequest(mock)
+		signature := req.Headers.Get("Webhook-Signature")
+		req.Headers.Set("Webhook-Signature", "v1,Zm9vYmFy v2,Zm9vYmFy "+signature)
+		ok, err := rcvr.VerifySignature(req, gitlabSigningToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Error("expected signature to be verified")
+		}
+	})
+
+	t.Run("rejects tampered timestamp", func(t *testing.T) {
+		whreceiver.SetTimeNow(t, signedAt)
+		req := MakeWebhookPostRequest(mock)
+		req.Headers.Set("Webhook-Timestamp", strconv.Itoa(gitlabSignedAt+1))
+		ok, err := rcvr.VerifySignature(req, gitlabSigningToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok {
+			t.Error("expected signature to be rejected")
+		}
+	})
+
+	t.Run("exposes the signed delivery id", func(t *testing.T) {
+		info, err := rcvr.GetWebhookInfo(MakeWebhookPostRequest(mock))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := mock.Headers["webhook-id"]; info.SignedDeliveryID != want {
+			t.Errorf("want signed delivery id %q, got %q", want, info.SignedDeliveryID)
+		}
+	})
+
+	t.Run("supports raw secrets", func(t *testing.T) {
+		whreceiver.SetTimeNow(t, signedAt)
+		secret := "gitlab-3216732167"
+		id := "msg_raw"
+		timestamp := strconv.Itoa(gitlabSignedAt)
+		req := MakeWebhookPostRequest(mock)
+		h := hmac.New(sha256.New, []byte(secret))
+		h.Write([]byte(id + "." + timestamp + "."))
+		h.Write(req.Payload)
+		req.Headers.Set("Webhook-Id", id)
+		req.Headers.Set("Webhook-Signature", "v1,"+base64.StdEncoding.EncodeToString(h.Sum(nil)))
+
+		ok, err := rcvr.VerifySignature(req, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Error("expected signature to be verified")
+		}
+	})
+}
+
+func TestPushRefChanges(t *testing.T) {
+	const zero = "0000000000000000000000000000000000000000"
+	const sha = "92bcfadb4199556415be69b9c31c0dc72343fea2"
+	type flags struct{ created, deleted, forced bool }
+
+	tests := []struct {
+		name     string
+		provider string
+		headers  http.Header
+		payload  string
+		want     flags
+	}{
+		{
+			"github created",
+			"github",
+			http.Header{"X-Github-Event": []string{"push"}},
+			`{"ref": "refs/heads/feat", "before": "` + zero + `", "after": "` + sha + `", "created": true, "repository": {"full_name": "user/repo"}}`,
+			flags{created: true},
+		},
+		{
+			"github deleted",
+			"github",
+			http.Header{"X-Github-Event": []string{"push"}},
+			`{"ref": "refs/heads/feat", "before": "` + sha + `", "after": "` + zero + `", "deleted": true, "repository": {"full_name": "user/repo"}}`,
+			flags{deleted: true},
+		},
+		{
+			"github forced",
+			"github",
+			http.Header{"X-Github-Event": []string{"push"}},
+			`{"ref": "refs/heads/feat", "before": "` + sha + `", "after": "` + sha + `", "forced": true, "repository": {"full_name": "user/repo"}}`,
+			flags{forced: true},
+		},
+		{
+			"gitea deleted by zero hash",
+			"gitea",
+			http.Header{"X-Gitea-Event": []string{"push"}},
+			`{"ref": "refs/heads/feat", "before": "` + sha + `", "after": "` + zero + `", "repository": {"full_name": "user/repo"}}`,
+			flags{deleted: true},
+		},
+		{
+			"gitlab created",
+			"gitlab",
+			http.Header{"X-Gitlab-Event": []string{"Push Hook"}},
+			`{"object_kind": "push", "ref": "refs/heads/feat", "before": "` + zero + `", "after": "` + sha + `", "project": {"path_with_namespace": "user/repo"}}`,
+			flags{created: true},
+		},
+		{
+			"gitlab deleted",
+			"gitlab",
+			http.Header{"X-Gitlab-Event": []string{"Push Hook"}},
+			`{"object_kind": "push", "ref": "refs/heads/feat", "before": "` + sha + `", "after": "` + zero + `", "project": {"path_with_namespace": "user/repo"}}`,
+			flags{deleted: true},
+		},
+		{
+			"bitbucket deleted and forced",
+			"bitbucket",
+			http.Header{"X-Event-Key": []string{"repo:push"}},
+			`{"push": {"changes": [{"old": {"type": "branch", "name": "feat", "target": {"hash": "` + sha + `"}}, "new": null, "closed": true, "forced": true}]}, "repository": {"full_name": "user/repo"}}`,
+			flags{deleted: true, forced: true},
+		},
+		{
+			"bitbucket-server created",
+			"bitbucket-server",
+			http.Header{"X-Event-Key": []string{"repo:refs_changed"}},
+			`{"repository": {"slug": "repo", "project": {"key": "user"}}, "changes": [{"ref": {"displayId": "feat", "type": "BRANCH"}, "fromHash": "` + zero + `", "toHash": "` + sha + `", "type": "ADD"}]}`,
+			flags{created: true},
+		},
+		{
+			"azure-devops deleted",
+			"azure-devops",
+			nil,
+			`{"eventType": "git.push", "resource": {"refUpdates": [{"name": "refs/heads/feat", "oldObjectId": "` + sha + `", "newObjectId": "` + zero + `"}], "repository": {"name": "repo", "project": {"name": "user"}}}}`,
+			flags{deleted: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rcvr := whreceiver.New(config.Project{GitProvider: tt.provider, Repo: "user/repo"})
+			got, err := rcvr.GetWebhookInfo(whreceiver.WebhookPostRequest{Headers: tt.headers, Payload: []byte(tt.payload)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Push == nil {
+				t.Fatal("expected push info, got nil")
+			}
+			if gotFlags := (flags{got.Push.Created, got.Push.Deleted, got.Push.Forced}); gotFlags != tt.want {
+				t.Errorf("Unexpected ref change flags, want %+v, got %+v", tt.want, gotFlags)
+			}
+		})
+	}
 }
@@ -18,6 +18,7 @@ var CLI struct {
 	Output        cmd.PipelineOutputArgs `cmd:"" aliases:"cat" help:"Display pipeline output"`
 	ListPipelines cmd.ListPipelinesArgs  `cmd:"" aliases:"ls" help:"Display a list of last N pipelines"`
 	Logs          cmd.LogsArgs           `cmd:"" help:"Display logs"`
+	Redeliver     cmd.RedeliverArgs      `cmd:"" help:"Replay a stored webhook delivery against the current config"`
 }
 
 func main() {
@@ -43,6 +44,8 @@ func main() {
 		cmd.ListPipelines(cfg, CLI.ListPipelines)
 	case "logs":
 		cmd.Logs(cfg, CLI.Logs)
+	case "redeliver <deliveryId>":
+		cmd.Redeliver(cfg, CLI.Redeliver)
 	default:
 		cmd.Serve(cfg)
 	}